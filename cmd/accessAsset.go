@@ -19,13 +19,22 @@ var accessCmd = &cobra.Command{
 			return
 		}
 
-		_, pubKey := loadKeyPair()
+		pubKey, err := loadPublicKey()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
 
 		db := storage.OpenDB("./data")
 		defer db.CloseDB()
 
 		blockchain := core.NewBlockchain(db)
 
+		if core.AssetStatus(assetID, blockchain) == core.AssetTakenDown {
+			fmt.Println("❌ This asset has been taken down and can no longer be accessed")
+			return
+		}
+
 		// Check if user has valid license
 		if !core.HasValidLicense(assetID, blockchain, pubKey) {
 			fmt.Println("❌ You don't have a valid license for this asset")
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+	"github.com/spf13/cobra"
+)
+
+const agentSockFile = "agent.sock"
+
+var (
+	agentSocket      string
+	agentConfirm     bool
+	agentIdleTimeout time.Duration
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Keep unlocked keys in a local signing agent",
+	Long: `Runs a signing agent, similar to ssh-agent. The agent holds unlocked keys in memory and signs
+transactions for other drmcli commands over a Unix socket that only your user can access.
+
+Commands use the agent instead of decrypting keys themselves when ` + AgentSockEnv + ` is set.`,
+}
+
+var agentStartCmd = &cobra.Command{
+	Use:   "start [identity...]",
+	Short: "Start the agent in the foreground with the given identities (defaults to the current one)",
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := agentSocketPath()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		names := args
+		if len(names) == 0 {
+			name, _, err := selectedIdentity()
+			if err != nil {
+				fmt.Println("❌", err)
+				return
+			}
+			names = []string{name}
+		}
+
+		agent := core.NewAgent()
+		agent.IdleTimeout = agentIdleTimeout
+		if agentConfirm {
+			agent.Confirm = confirmSignature()
+		}
+
+		for _, name := range names {
+			privKey, _, err := loadIdentity(name)
+			if err != nil {
+				fmt.Println("❌", err)
+				return
+			}
+			agent.AddKey(name, privKey)
+		}
+
+		listener, err := core.ListenAgent(path)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		defer os.Remove(path)
+
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-c
+			listener.Close()
+		}()
+
+		fmt.Printf("🔐 Agent holding %d key(s), listening on %s\n", len(names), path)
+		fmt.Printf("ℹ️ Use it with: export %s=%s\n", AgentSockEnv, path)
+		if err := agent.Serve(listener); err != nil {
+			fmt.Println("❌ Agent stopped:", err)
+			return
+		}
+		fmt.Println("👋 Agent stopped")
+	},
+}
+
+var agentAddCmd = &cobra.Command{
+	Use:   "add [identity...]",
+	Short: "Unlock identities and hand them to a running agent",
+	Run: func(cmd *cobra.Command, args []string) {
+		agent, err := runningAgent()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		names := args
+		if len(names) == 0 {
+			name, _, err := selectedIdentity()
+			if err != nil {
+				fmt.Println("❌", err)
+				return
+			}
+			names = []string{name}
+		}
+
+		for _, name := range names {
+			privKey, _, err := loadIdentity(name)
+			if err != nil {
+				fmt.Println("❌", err)
+				return
+			}
+			if err := agent.Add(name, privKey); err != nil {
+				fmt.Println("❌", err)
+				return
+			}
+			fmt.Printf("✅ Identity %q added to the agent\n", name)
+		}
+	},
+}
+
+var agentListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the keys held by the agent",
+	Run: func(cmd *cobra.Command, args []string) {
+		agent, err := runningAgent()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		keys, err := agent.Keys()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		if len(keys) == 0 {
+			fmt.Println("🔒 The agent holds no keys, add one with \"drmcli agent add\"")
+			return
+		}
+		for _, key := range keys {
+			fmt.Printf("  %-20s %s\n", key.Name, shortenKey(key.PublicKey))
+		}
+	},
+}
+
+var agentLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Drop all keys from the agent",
+	Run: func(cmd *cobra.Command, args []string) {
+		agent, err := runningAgent()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		if err := agent.Lock(); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		fmt.Println("🔒 Agent locked")
+	},
+}
+
+// Socket from --socket, the environment or the drmcli home directory, in that order
+func agentSocketPath() (string, error) {
+	if agentSocket != "" {
+		return agentSocket, nil
+	}
+	if path := os.Getenv(AgentSockEnv); path != "" {
+		return path, nil
+	}
+
+	home, err := homeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, agentSockFile), nil
+}
+
+func runningAgent() (*core.AgentClient, error) {
+	path, err := agentSocketPath()
+	if err != nil {
+		return nil, err
+	}
+	return &core.AgentClient{Path: path}, nil
+}
+
+// Ask on the agent's terminal before each signature, one request at a time
+func confirmSignature() func(string, core.LicenseTransaction) bool {
+	var mu sync.Mutex
+	reader := bufio.NewReader(os.Stdin)
+
+	return func(name string, tx core.LicenseTransaction) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		fmt.Printf("✍️ Sign this transaction with %q?\n", name)
+		printTransaction(tx)
+		fmt.Print("   [y/N] ")
+
+		answer, _ := reader.ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes"
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+	agentCmd.AddCommand(agentStartCmd, agentAddCmd, agentListCmd, agentLockCmd)
+	agentCmd.PersistentFlags().StringVar(&agentSocket, "socket", "", "Path of the agent socket (defaults to $"+AgentSockEnv+" or agent.sock in the drmcli home)")
+	agentStartCmd.Flags().BoolVar(&agentConfirm, "confirm", false, "Ask for confirmation before every signature")
+	agentStartCmd.Flags().DurationVar(&agentIdleTimeout, "idle-timeout", 0, "Drop keys after this long without requests (0 keeps them)")
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+	storage "github.com/Saumya40-codes/DeSecure/pkg"
+	"github.com/spf13/cobra"
+)
+
+var delistCmd = &cobra.Command{
+	Use:   "delist",
+	Short: "Withdraw one of your assets from sale",
+	Long:  `Stops new purchases of an asset you own. Licenses that were already purchased stay valid.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		node, err := newClientNode(ctx)
+		if err != nil {
+			fmt.Println("Error creating P2P node:", err)
+			return
+		}
+
+		signer, err := loadSigner()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		pubKey := signer.pubKey
+
+		db := storage.OpenDB("./data")
+		defer db.CloseDB()
+
+		bc := core.NewBlockchain(db)
+
+		delistTx, err := buildDelistTransaction(bc, assetID, pubKey)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		delistTx.Signature, err = signer.Sign(&delistTx)
+		if err != nil {
+			fmt.Println("❌ Error signing transaction:", err)
+			return
+		}
+
+		if !submitTransaction(node, bc, delistTx) {
+			return
+		}
+
+		fmt.Println("✅ Delist request submitted! TxID:", delistTx.TxID)
+		fmt.Println("ℹ️ Follow it with: drmcli tx status", delistTx.TxID)
+		fmt.Println("ℹ️ Existing licenses for this asset remain valid.")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(delistCmd)
+	delistCmd.Flags().StringVarP(&assetID, "asset", "a", "", "Asset ID/hash to delist")
+	delistCmd.MarkFlagRequired("asset")
+}
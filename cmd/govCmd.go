@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+	storage "github.com/Saumya40-codes/DeSecure/pkg"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// Blocks between a proposal and its activation when --at isn't given
+const defaultActivationDelay = 10
+
+var (
+	govValidatorConfig string
+
+	proposeAdd       bool
+	proposeRemove    bool
+	proposeID        int
+	proposePubKey    string
+	proposeQuorum    int
+	proposeBlockSize int
+	proposeTimeoutMs int64
+	proposeJail      int
+	proposeAt        int
+
+	voteReject bool
+)
+
+var govCmd = &cobra.Command{
+	Use:   "gov",
+	Short: "Propose and vote on changes to the validator set and consensus parameters",
+	Long: `Governance transactions let validators change the validator set and consensus parameters
+without restarting the network. A proposal passes once a quorum of validators approve it, and
+takes effect at its activation height.
+
+Governance transactions must be signed with a validator key, use --validator to sign with the
+key of a validator config or --key for an identity holding it.`,
+}
+
+var govProposeCmd = &cobra.Command{
+	Use:   "propose",
+	Short: "Propose adding or removing a validator, or changing consensus parameters",
+	Run: func(cmd *cobra.Command, args []string) {
+		proposal := core.Proposal{ActivationHeight: proposeAt}
+		switch {
+		case proposeAdd && proposeRemove:
+			fmt.Println("❌ Use only one of --add-validator and --remove-validator")
+			return
+		case proposeAdd:
+			proposal.Kind = core.ProposalAddValidator
+			proposal.Validator = &core.ValidatorInfo{ID: proposeID, PublicKey: proposePubKey}
+		case proposeRemove:
+			proposal.Kind = core.ProposalRemoveValidator
+			proposal.Validator = &core.ValidatorInfo{ID: proposeID}
+		case proposeQuorum != 0 || proposeBlockSize != 0 || proposeTimeoutMs != 0 || proposeJail != 0:
+			proposal.Kind = core.ProposalSetParams
+			proposal.Params = &core.ConsensusParams{Quorum: proposeQuorum, BlockSize: proposeBlockSize, TimeoutMs: proposeTimeoutMs, JailBlocks: proposeJail}
+		default:
+			fmt.Println("❌ Nothing to propose, see drmcli gov propose --help")
+			return
+		}
+
+		node, bc, signer, cleanup, ok := openGovernance()
+		if !ok {
+			return
+		}
+		defer cleanup()
+
+		if proposal.ActivationHeight == 0 {
+			proposal.ActivationHeight = len(bc.Blocks) + defaultActivationDelay
+		}
+
+		proposeTx, err := buildProposalTransaction(bc, signer.pubKey, proposal)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		proposeTx.Signature, err = signer.Sign(&proposeTx)
+		if err != nil {
+			fmt.Println("❌ Error signing transaction:", err)
+			return
+		}
+
+		if !submitTransaction(node, bc, proposeTx) {
+			return
+		}
+
+		fmt.Println("✅ Proposal submitted! ID:", proposeTx.TxID)
+		fmt.Printf("ℹ️ It takes effect at block %d if enough validators vote for it with: drmcli gov vote %s\n", proposal.ActivationHeight, proposeTx.TxID)
+	},
+}
+
+var govVoteCmd = &cobra.Command{
+	Use:   "vote <proposal-id>",
+	Short: "Vote for a proposal (or against it with --reject)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		node, bc, signer, cleanup, ok := openGovernance()
+		if !ok {
+			return
+		}
+		defer cleanup()
+
+		voteTx, err := buildProposalVoteTransaction(bc, signer.pubKey, args[0], !voteReject)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		voteTx.Signature, err = signer.Sign(&voteTx)
+		if err != nil {
+			fmt.Println("❌ Error signing transaction:", err)
+			return
+		}
+
+		if !submitTransaction(node, bc, voteTx) {
+			return
+		}
+		fmt.Println("✅ Vote submitted! TxID:", voteTx.TxID)
+	},
+}
+
+var govListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List governance proposals and their status",
+	Run: func(cmd *cobra.Command, args []string) {
+		db := storage.OpenDB("./data")
+		defer db.CloseDB()
+
+		bc := core.NewBlockchain(db)
+		if !initLocalGenesis(bc, genesisPath) {
+			return
+		}
+
+		proposals, err := bc.Proposals()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		if len(proposals) == 0 {
+			fmt.Println("🔍 No proposals yet")
+			return
+		}
+
+		titleColor := color.New(color.FgCyan, color.Bold)
+		hashColor := color.New(color.FgYellow)
+		titleColor.Printf("🏛️ %d proposal(s), current height %d\n\n", len(proposals), len(bc.Blocks))
+		for _, p := range proposals {
+			hashColor.Println(p.ID)
+			fmt.Printf("   %s, by validator %d at block %d\n", describeProposal(p.Proposal), p.Proposer, p.Height)
+			fmt.Printf("   Status: %s, %d/%d approvals, %d rejections, activates at block %d\n\n",
+				p.Status, len(p.Approvals), p.Quorum, len(p.Rejects), p.Proposal.ActivationHeight)
+		}
+	},
+}
+
+func describeProposal(p core.Proposal) string {
+	switch p.Kind {
+	case core.ProposalAddValidator:
+		return fmt.Sprintf("Add validator %d (%s)", p.Validator.ID, shortenKey(p.Validator.PublicKey))
+	case core.ProposalRemoveValidator:
+		return fmt.Sprintf("Remove validator %d", p.Validator.ID)
+	case core.ProposalSetParams:
+		var changes []string
+		if p.Params.Quorum != 0 {
+			changes = append(changes, fmt.Sprintf("quorum %d", p.Params.Quorum))
+		}
+		if p.Params.BlockSize != 0 {
+			changes = append(changes, fmt.Sprintf("block size %d", p.Params.BlockSize))
+		}
+		if p.Params.TimeoutMs != 0 {
+			changes = append(changes, fmt.Sprintf("timeout %dms", p.Params.TimeoutMs))
+		}
+		if p.Params.JailBlocks != 0 {
+			changes = append(changes, fmt.Sprintf("jail time %d blocks", p.Params.JailBlocks))
+		}
+		return "Set " + strings.Join(changes, ", ")
+	case core.ProposalTakedown:
+		return "Take down asset " + p.AssetHash
+	}
+	return p.Kind
+}
+
+// Store the genesis at path on the local chain, which is needed to know the validator set
+func initLocalGenesis(bc *core.Blockchain, path string) bool {
+	if path != "" {
+		genesis, err := core.LoadGenesis(path)
+		if err != nil {
+			fmt.Println("❌", err)
+			return false
+		}
+		if err := bc.InitGenesis(genesis); err != nil {
+			fmt.Println("❌", err)
+			return false
+		}
+	}
+	if bc.Genesis() == nil {
+		fmt.Println("❌ The local chain has no genesis, pass it with --genesis")
+		return false
+	}
+	return true
+}
+
+// Everything needed to submit a governance transaction, cleanup closes the database
+func openGovernance() (*core.Node, *core.Blockchain, *keySigner, func(), bool) {
+	var signer *keySigner
+	var err error
+	if govValidatorConfig != "" {
+		signer, err = validatorSigner(govValidatorConfig)
+	} else {
+		signer, err = loadSigner()
+	}
+	if err != nil {
+		fmt.Println("❌", err)
+		return nil, nil, nil, nil, false
+	}
+
+	node, err := newClientNode(context.Background())
+	if err != nil {
+		fmt.Println("Error creating P2P node:", err)
+		return nil, nil, nil, nil, false
+	}
+
+	db := storage.OpenDB("./data")
+	bc := core.NewBlockchain(db)
+	if !initLocalGenesis(bc, genesisPath) {
+		db.CloseDB()
+		return nil, nil, nil, nil, false
+	}
+	return node, bc, signer, db.CloseDB, true
+}
+
+func init() {
+	rootCmd.AddCommand(govCmd)
+	govCmd.AddCommand(govProposeCmd, govVoteCmd, govListCmd)
+
+	govProposeCmd.Flags().StringVar(&govValidatorConfig, "validator", "", "Sign with the key of this validator config")
+	govVoteCmd.Flags().StringVar(&govValidatorConfig, "validator", "", "Sign with the key of this validator config")
+
+	govProposeCmd.Flags().BoolVar(&proposeAdd, "add-validator", false, "Propose adding the validator given by --id and --pubkey")
+	govProposeCmd.Flags().BoolVar(&proposeRemove, "remove-validator", false, "Propose removing the validator given by --id")
+	govProposeCmd.Flags().IntVar(&proposeID, "id", 0, "Validator ID to add or remove")
+	govProposeCmd.Flags().StringVar(&proposePubKey, "pubkey", "", "Public key of the validator to add")
+	govProposeCmd.Flags().IntVar(&proposeQuorum, "quorum", 0, "New number of votes needed to decide")
+	govProposeCmd.Flags().IntVar(&proposeBlockSize, "block-size", 0, "New maximum number of transactions per block")
+	govProposeCmd.Flags().Int64Var(&proposeTimeoutMs, "timeout-ms", 0, "New consensus step timeout in milliseconds")
+	govProposeCmd.Flags().IntVar(&proposeJail, "jail-blocks", 0, "New number of blocks a validator is jailed for after conflicting votes")
+	govProposeCmd.Flags().IntVar(&proposeAt, "at", 0, fmt.Sprintf("Block height at which the change applies (defaults to %d blocks from now)", defaultActivationDelay))
+
+	govVoteCmd.Flags().BoolVar(&voteReject, "reject", false, "Vote against the proposal")
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// HomeEnv overrides the per-user directory holding identities and settings
+const HomeEnv = "DRMCLI_HOME"
+
+const (
+	defaultIdentity = "default"
+	configFile      = "config.json"
+)
+
+// Set by the global --key flag
+var keyName string
+
+var identityNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Settings persisted in the drmcli home directory
+type cliConfig struct {
+	DefaultKey string `json:"default_key,omitempty"`
+}
+
+// Directory holding the user's identities and settings, independent of the working directory
+func homeDir() (string, error) {
+	if dir := os.Getenv(HomeEnv); dir != "" {
+		return dir, nil
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("can't find a config directory, set %s: %w", HomeEnv, err)
+	}
+	return filepath.Join(configDir, "drmcli"), nil
+}
+
+func identitiesDir() (string, error) {
+	home, err := homeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "keys"), nil
+}
+
+func identityPath(name string) (string, error) {
+	if !identityNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid identity name %q, use letters, digits, - and _", name)
+	}
+
+	dir, err := identitiesDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+func identityExists(name string) bool {
+	path, err := identityPath(name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// Names of all identities in the keystore directory, sorted
+func listIdentities() ([]string, error) {
+	dir, err := identitiesDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// The identity commands act on: --key if given, else the configured default
+func selectedIdentity() (name string, explicit bool, err error) {
+	if keyName != "" {
+		return keyName, true, nil
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", false, err
+	}
+	if cfg.DefaultKey != "" {
+		return cfg.DefaultKey, true, nil
+	}
+	return defaultIdentity, false, nil
+}
+
+func loadConfig() (cliConfig, error) {
+	var cfg cliConfig
+
+	home, err := homeDir()
+	if err != nil {
+		return cfg, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(home, configFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse %s: %w", configFile, err)
+	}
+	return cfg, nil
+}
+
+func saveConfig(cfg cliConfig) error {
+	home, err := homeDir()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if err := os.MkdirAll(home, 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", home, err)
+	}
+	return os.WriteFile(filepath.Join(home, configFile), data, 0o600)
+}
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+	"github.com/spf13/cobra"
+)
+
+// ExportPassphraseEnv provides the passphrase of exported or imported key files
+const ExportPassphraseEnv = "DRMCLI_EXPORT_PASSPHRASE"
+
+// MnemonicEnv provides the recovery phrase to "keys restore" without a prompt
+const MnemonicEnv = "DRMCLI_MNEMONIC"
+
+var (
+	exportFormat       string
+	exportEncrypt      bool
+	exportOut          string
+	exportStdout       bool
+	importFile         string
+	mnemonicPassphrase bool
+)
+
+var keysExportCmd = &cobra.Command{
+	Use:   "export [name]",
+	Short: "Export an identity as PEM (PKCS#8) or JWK",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _, err := selectedIdentity()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		if len(args) == 1 {
+			name = args[0]
+		}
+		// Terminals get scrolled back, logged and screen shared, the bare key only goes there on request
+		if exportOut == "" && !exportEncrypt && !exportStdout {
+			fmt.Println("❌ Refusing to print the unencrypted private key, write it to a file with --out, encrypt it with --encrypt or pass --stdout")
+			return
+		}
+
+		privKey, _, err := loadIdentity(name)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		var data []byte
+		switch exportFormat {
+		case "pem":
+			var passphrase []byte
+			if exportEncrypt {
+				if passphrase, err = readPassphraseFrom(ExportPassphraseEnv, "Export passphrase: ", true); err != nil {
+					fmt.Println("❌", err)
+					return
+				}
+			}
+			data, err = core.MarshalPKCS8PEM(privKey, passphrase)
+		case "jwk":
+			if exportEncrypt {
+				fmt.Println("❌ JWK exports can't be encrypted, use --format pem")
+				return
+			}
+			data, err = core.MarshalJWK(privKey)
+			data = append(data, '\n')
+		default:
+			err = fmt.Errorf("unknown format %q, use pem or jwk", exportFormat)
+		}
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		if exportOut == "" {
+			os.Stdout.Write(data)
+			return
+		}
+		if err := os.WriteFile(exportOut, data, 0o600); err != nil {
+			fmt.Println("❌ Error writing export:", err)
+			return
+		}
+		fmt.Printf("✅ Identity %q exported to %s\n", name, exportOut)
+		if !exportEncrypt {
+			fmt.Println("⚠️ The file holds your private key unencrypted, keep it somewhere safe")
+		}
+	},
+}
+
+var keysImportCmd = &cobra.Command{
+	Use:   "import <name>",
+	Short: "Import an identity from a PEM or JWK file",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if identityExists(name) {
+			fmt.Printf("❌ Identity %q already exists\n", name)
+			return
+		}
+
+		data, err := os.ReadFile(importFile)
+		if err != nil {
+			fmt.Println("❌ Error reading key file:", err)
+			return
+		}
+
+		var privKey *ecdsa.PrivateKey
+		switch trimmed := bytes.TrimSpace(data); {
+		case bytes.HasPrefix(trimmed, []byte("{")):
+			privKey, err = core.ParseJWK(trimmed)
+		case bytes.Contains(trimmed, []byte("ENCRYPTED PRIVATE KEY")):
+			var passphrase []byte
+			if passphrase, err = readPassphraseFrom(ExportPassphraseEnv, "Passphrase of the key file: ", false); err == nil {
+				privKey, err = core.ParsePrivateKeyPEM(trimmed, passphrase)
+			}
+		default:
+			privKey, err = core.ParsePrivateKeyPEM(trimmed, nil)
+		}
+		if err != nil {
+			fmt.Println("❌ Error parsing key:", err)
+			return
+		}
+
+		saveImportedIdentity(name, privKey)
+	},
+}
+
+var keysRestoreCmd = &cobra.Command{
+	Use:   "restore <name>",
+	Short: "Restore an identity from its BIP-39 recovery phrase",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if identityExists(name) {
+			fmt.Printf("❌ Identity %q already exists\n", name)
+			return
+		}
+
+		phrase, err := readPassphraseFrom(MnemonicEnv, "Recovery phrase: ", false)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		var seedPassphrase []byte
+		if mnemonicPassphrase {
+			if seedPassphrase, err = promptSecret("Recovery phrase passphrase: "); err != nil {
+				fmt.Println("❌", err)
+				return
+			}
+		}
+
+		mnemonic := strings.Join(strings.Fields(strings.ToLower(string(phrase))), " ")
+		privKey, err := core.KeyFromMnemonic(mnemonic, string(seedPassphrase))
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		saveImportedIdentity(name, privKey)
+	},
+}
+
+// Create an identity from a freshly generated recovery phrase, used by "keys create --mnemonic"
+func createMnemonicIdentity(name string) {
+	mnemonic, err := core.NewMnemonic()
+	if err != nil {
+		fmt.Println("❌ Error generating recovery phrase:", err)
+		return
+	}
+
+	var seedPassphrase []byte
+	if mnemonicPassphrase {
+		if seedPassphrase, err = readPassphraseFrom("", "Recovery phrase passphrase: ", true); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+	}
+
+	privKey, err := core.KeyFromMnemonic(mnemonic, string(seedPassphrase))
+	if err != nil {
+		fmt.Println("❌", err)
+		return
+	}
+
+	if !saveImportedIdentity(name, privKey) {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("📝 Write down this recovery phrase, it is the only way to restore the identity:")
+	fmt.Println()
+	words := strings.Fields(mnemonic)
+	for i := 0; i < len(words); i += 6 {
+		fmt.Println("   " + strings.Join(words[i:min(i+6, len(words))], " "))
+	}
+	fmt.Println()
+}
+
+func saveImportedIdentity(name string, privKey *ecdsa.PrivateKey) bool {
+	path, err := identityPath(name)
+	if err != nil {
+		fmt.Println("❌", err)
+		return false
+	}
+
+	passphrase, err := readPassphrase("New passphrase: ", true)
+	if err != nil {
+		fmt.Println("❌", err)
+		return false
+	}
+
+	if err := saveKeystore(path, privKey, passphrase); err != nil {
+		fmt.Println("❌", err)
+		return false
+	}
+
+	fmt.Printf("✅ Identity %q saved\n", name)
+	fmt.Println("🔑 Public key:", core.PublicKeyHex(&privKey.PublicKey))
+	return true
+}
+
+func init() {
+	keysCmd.AddCommand(keysExportCmd)
+	keysCmd.AddCommand(keysImportCmd)
+	keysCmd.AddCommand(keysRestoreCmd)
+
+	keysExportCmd.Flags().StringVar(&exportFormat, "format", "pem", "Export format (pem, jwk)")
+	keysExportCmd.Flags().BoolVar(&exportEncrypt, "encrypt", false, "Encrypt the PEM export with a passphrase")
+	keysExportCmd.Flags().StringVarP(&exportOut, "out", "o", "", "File to write the export to (defaults to stdout)")
+	keysExportCmd.Flags().BoolVar(&exportStdout, "stdout", false, "Allow printing an unencrypted export to stdout")
+
+	keysImportCmd.Flags().StringVarP(&importFile, "file", "f", "", "PEM or JWK file to import")
+	keysImportCmd.MarkFlagRequired("file")
+
+	keysRestoreCmd.Flags().BoolVar(&mnemonicPassphrase, "with-passphrase", false, "The recovery phrase is protected by an extra BIP-39 passphrase")
+	keysCreateCmd.Flags().BoolVar(&mnemonicPassphrase, "with-passphrase", false, "Protect the recovery phrase with an extra BIP-39 passphrase")
+	keysCreateCmd.Flags().BoolVar(&withMnemonic, "mnemonic", false, "Derive the identity from a new BIP-39 recovery phrase")
+}
@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateDir    string
+	migrateName   string
+	confirmDelete bool
+	withMnemonic  bool
+)
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage your named identities",
+}
+
+var keysCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a new identity",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if identityExists(name) {
+			fmt.Printf("❌ Identity %q already exists\n", name)
+			return
+		}
+
+		if withMnemonic {
+			createMnemonicIdentity(name)
+			return
+		}
+
+		_, pubKey, err := generateAndSaveKeyPair(name)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		fmt.Printf("✅ Identity %q created\n", name)
+		fmt.Println("🔑 Public key:", pubKey)
+	},
+}
+
+var keysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List your identities",
+	Run: func(cmd *cobra.Command, args []string) {
+		names, err := listIdentities()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		if len(names) == 0 {
+			fmt.Println("🔍 No identities yet, create one with \"drmcli keys create <name>\"")
+			return
+		}
+
+		current, _, _ := selectedIdentity()
+		defaultColor := color.New(color.FgGreen, color.Bold)
+		for _, name := range names {
+			pubKey := "?"
+			if path, err := identityPath(name); err == nil {
+				if ks, err := readKeystore(path); err == nil {
+					pubKey = shortenKey(ks.PublicKey)
+				}
+			}
+
+			if name == current {
+				defaultColor.Printf("* %-20s %s\n", name, pubKey)
+			} else {
+				fmt.Printf("  %-20s %s\n", name, pubKey)
+			}
+		}
+	},
+}
+
+var keysShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show an identity (defaults to the current one)",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _, err := selectedIdentity()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		if len(args) == 1 {
+			name = args[0]
+		}
+
+		path, err := identityPath(name)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		ks, err := readKeystore(path)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		fmt.Println("Name:      ", name)
+		fmt.Println("Public key:", ks.PublicKey)
+		fmt.Println("Keystore:  ", path)
+	},
+}
+
+var keysUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Make an identity the default for all commands",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if !identityExists(name) {
+			fmt.Printf("❌ No identity named %q\n", name)
+			return
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		cfg.DefaultKey = name
+		if err := saveConfig(cfg); err != nil {
+			fmt.Println("❌ Error saving config:", err)
+			return
+		}
+
+		fmt.Printf("✅ %q is now the default identity\n", name)
+	},
+}
+
+var keysDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete an identity",
+	Long:  `Deletes an identity from the keystore. Without a backup, the assets and licenses it holds are lost for good.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		path, err := identityPath(name)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		if !identityExists(name) {
+			fmt.Printf("❌ No identity named %q\n", name)
+			return
+		}
+
+		if !confirmDelete {
+			fmt.Printf("⚠️ This permanently deletes %q, run again with --yes to confirm\n", name)
+			return
+		}
+
+		if err := os.Remove(path); err != nil {
+			fmt.Println("❌ Error deleting identity:", err)
+			return
+		}
+
+		cfg, err := loadConfig()
+		if err == nil && cfg.DefaultKey == name {
+			cfg.DefaultKey = ""
+			if err := saveConfig(cfg); err != nil {
+				fmt.Println("⚠️ Could not reset the default identity:", err)
+			}
+		}
+
+		fmt.Printf("✅ Identity %q deleted\n", name)
+	},
+}
+
+var keysMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Import a ./keys directory from older versions as a named identity",
+	Run: func(cmd *cobra.Command, args []string) {
+		if identityExists(migrateName) {
+			fmt.Printf("❌ Identity %q already exists, pick another with --name\n", migrateName)
+			return
+		}
+
+		target, err := identityPath(migrateName)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		// Keystores are already passphrase protected and only need to be moved
+		if ks := filepath.Join(migrateDir, legacyKeystoreFile); fileExists(ks) {
+			if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+				fmt.Println("❌", err)
+				return
+			}
+			if err := os.Rename(ks, target); err != nil {
+				fmt.Println("❌ Error moving keystore:", err)
+				return
+			}
+			fmt.Printf("✅ Keystore moved to identity %q\n", migrateName)
+			return
+		}
+
+		privKey, err := loadLegacyKeyPair(migrateDir)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		// What older versions signed with is the identity on chain, a migrated key that encodes
+		// differently would lose the uploads and licenses recorded under it
+		pubKey := core.PublicKeyHex(&privKey.PublicKey)
+		legacyKey := core.LegacyPublicKeyHex(&privKey.PublicKey)
+		if stored, err := os.ReadFile(filepath.Join(migrateDir, legacyPublicKeyFile)); err == nil {
+			legacyKey = string(stored)
+		}
+		if legacyKey != pubKey {
+			fmt.Println("❌ This key is recorded on chain as", legacyKey)
+			fmt.Println("   which differs from its current encoding", pubKey)
+			fmt.Println("   Migrating would lose ownership of everything it signed, the old files were left in", migrateDir)
+			return
+		}
+
+		passphrase, err := readPassphrase("New passphrase: ", true)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		if err := saveKeystore(target, privKey, passphrase); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		fmt.Printf("✅ Key migrated to identity %q\n", migrateName)
+
+		// The old files hold the private key in a recoverable form, don't leave them around
+		for _, name := range []string{legacySecretKeyFile, legacyPrivateKeyFile, legacyPublicKeyFile} {
+			if err := os.Remove(filepath.Join(migrateDir, name)); err != nil && !os.IsNotExist(err) {
+				fmt.Printf("⚠️ Could not remove %s: %v\n", name, err)
+			}
+		}
+
+		fmt.Println("🔑 Public key:", pubKey)
+	},
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func init() {
+	rootCmd.AddCommand(keysCmd)
+	keysCmd.AddCommand(keysCreateCmd)
+	keysCmd.AddCommand(keysListCmd)
+	keysCmd.AddCommand(keysShowCmd)
+	keysCmd.AddCommand(keysUseCmd)
+	keysCmd.AddCommand(keysDeleteCmd)
+	keysCmd.AddCommand(keysMigrateCmd)
+
+	keysDeleteCmd.Flags().BoolVar(&confirmDelete, "yes", false, "Confirm the deletion")
+	keysMigrateCmd.Flags().StringVar(&migrateDir, "dir", legacyKeyDir, "Key directory to migrate")
+	keysMigrateCmd.Flags().StringVar(&migrateName, "name", defaultIdentity, "Name of the identity to create")
+}
@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/term"
+)
+
+// Files written before named identities existed, only read by "keys migrate"
+const (
+	legacyKeyDir       = "./keys/"
+	legacyKeystoreFile = "keystore.json"
+
+	legacySecretKeyFile  = ".secret_key"
+	legacyPrivateKeyFile = ".private_key"
+	legacyPublicKeyFile  = ".public_key"
+)
+
+// PassphraseEnv lets scripts provide the keystore passphrase without a prompt
+const PassphraseEnv = "DRMCLI_PASSPHRASE"
+
+// Read the keystore passphrase from the environment or the terminal
+func readPassphrase(prompt string, confirm bool) ([]byte, error) {
+	return readPassphraseFrom(PassphraseEnv, prompt, confirm)
+}
+
+func readPassphraseFrom(envName, prompt string, confirm bool) ([]byte, error) {
+	if env, ok := os.LookupEnv(envName); ok {
+		if env == "" {
+			return nil, fmt.Errorf("$%s is set but empty", envName)
+		}
+		return []byte(env), nil
+	}
+
+	passphrase, err := promptSecret(prompt)
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase must not be empty")
+	}
+
+	if confirm {
+		again, err := promptSecret("Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(passphrase, again) {
+			return nil, errors.New("passphrases don't match")
+		}
+	}
+	return passphrase, nil
+}
+
+func promptSecret(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	defer fmt.Fprintln(os.Stderr)
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		return term.ReadPassword(fd)
+	}
+
+	// Not a terminal (e.g. piped input), read a single line
+	line, err := bufio.NewReader(os.Stdin).ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// Ensure key pair exists; otherwise, generate one
+func ensureKeyPair() (*ecdsa.PrivateKey, string, error) {
+	name, explicit, err := selectedIdentity()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if identityExists(name) {
+		return loadIdentity(name)
+	}
+	if explicit {
+		return nil, "", fmt.Errorf("no identity named %q, create it with \"drmcli keys create %s\"", name, name)
+	}
+	if hasLegacyKeys(legacyKeyDir) {
+		return nil, "", fmt.Errorf("found a key in the old format in %s, run \"drmcli keys migrate\" first", legacyKeyDir)
+	}
+
+	fmt.Printf("🔑 No key found, creating a new identity %q\n", name)
+	return generateAndSaveKeyPair(name)
+}
+
+// Generate and save new key pair
+func generateAndSaveKeyPair(name string) (*ecdsa.PrivateKey, string, error) {
+	path, err := identityPath(name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	privKey, pubKey := core.GenerateKeyPair()
+	if privKey == nil {
+		return nil, "", errors.New("failed to generate key pair")
+	}
+
+	passphrase, err := readPassphrase("New passphrase: ", true)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := saveKeystore(path, privKey, passphrase); err != nil {
+		return nil, "", err
+	}
+	return privKey, pubKey, nil
+}
+
+func loadIdentity(name string) (*ecdsa.PrivateKey, string, error) {
+	path, err := identityPath(name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ks, err := readKeystore(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	passphrase, err := readPassphrase(fmt.Sprintf("Passphrase for %s: ", name), false)
+	if err != nil {
+		return nil, "", err
+	}
+
+	privKey, err := core.DecryptKey(ks, passphrase)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to unlock identity %q: %w", name, err)
+	}
+	return privKey, ks.PublicKey, nil
+}
+
+// Load only the public key of the selected identity, without decrypting the private key
+func loadPublicKey() (string, error) {
+	name, _, err := selectedIdentity()
+	if err != nil {
+		return "", err
+	}
+
+	path, err := identityPath(name)
+	if err != nil {
+		return "", err
+	}
+
+	ks, err := readKeystore(path)
+	if err != nil {
+		return "", err
+	}
+	return ks.PublicKey, nil
+}
+
+func hasLegacyKeys(dir string) bool {
+	for _, name := range []string{legacyPrivateKeyFile, legacyKeystoreFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func readKeystore(path string) (*core.Keystore, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no identity found at %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+
+	var ks core.Keystore
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("failed to parse keystore %s: %w", path, err)
+	}
+	return &ks, nil
+}
+
+func saveKeystore(path string, privKey *ecdsa.PrivateKey, passphrase []byte) error {
+	ks, err := core.EncryptKey(privKey, passphrase)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode keystore: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write keystore: %w", err)
+	}
+	return nil
+}
+
+// Decrypt a key pair stored in the old .secret_key/.private_key layout
+func loadLegacyKeyPair(dir string) (*ecdsa.PrivateKey, error) {
+	secret, err := os.ReadFile(filepath.Join(dir, legacySecretKeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read legacy secret key: %w", err)
+	}
+	if len(secret) != 32 {
+		return nil, fmt.Errorf("legacy secret key has %d bytes, expected 32", len(secret))
+	}
+
+	encrypted, err := os.ReadFile(filepath.Join(dir, legacyPrivateKeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read legacy private key: %w", err)
+	}
+	if len(encrypted) < 24+secretbox.Overhead {
+		return nil, errors.New("legacy private key file is truncated")
+	}
+
+	var secretKey [32]byte
+	copy(secretKey[:], secret)
+
+	var nonce [24]byte
+	copy(nonce[:], encrypted[:24])
+	privBytes, ok := secretbox.Open(nil, encrypted[24:], &nonce, &secretKey)
+	if !ok {
+		return nil, errors.New("legacy private key doesn't match its secret key")
+	}
+
+	privKey, err := x509.ParseECPrivateKey(privBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse legacy private key: %w", err)
+	}
+	return privKey, nil
+}
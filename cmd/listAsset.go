@@ -12,6 +12,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var showAllAssets bool
+
 var listAssetsCmd = &cobra.Command{
 	Use:   "list-assets",
 	Short: "List available assets on the blockchain",
@@ -26,6 +28,7 @@ var listAssetsCmd = &cobra.Command{
 		infoColor := color.New(color.FgWhite)
 		hashColor := color.New(color.FgYellow)
 		categoryColor := color.New(color.FgMagenta)
+		statusColor := color.New(color.FgRed, color.Bold)
 
 		// Print pretty header
 		printCenteredTitle := func(title string) {
@@ -40,15 +43,22 @@ var listAssetsCmd = &cobra.Command{
 
 		// Track unique assets to avoid duplicates
 		uniqueAssets := make(map[string]core.LicenseTransaction)
+		assetStatus := make(map[string]string)
 
-		// Iterate through blocks in reverse order to get the latest transactions
-		for i := len(blockchain.Blocks) - 1; i >= 0; i-- {
-			block := blockchain.Blocks[i]
+		for _, block := range blockchain.Blocks {
 			for _, tx := range block.Transaction {
-				// Only add if we haven't seen this asset yet
-				if _, exists := uniqueAssets[tx.AssetHash]; !exists {
-					uniqueAssets[tx.AssetHash] = tx
+				if tx.TxType != core.TxTypeUpload {
+					continue
+				}
+
+				// Delisted and taken down assets are only shown with --all
+				status := core.AssetStatus(tx.AssetHash, blockchain)
+				if status != core.AssetActive && !showAllAssets {
+					continue
 				}
+
+				uniqueAssets[tx.AssetHash] = tx
+				assetStatus[tx.AssetHash] = status
 			}
 		}
 
@@ -85,11 +95,16 @@ var listAssetsCmd = &cobra.Command{
 			fmt.Printf("%s\n", tx.License)
 
 			infoColor.Printf(" Owner: ")
-			fmt.Printf("%s\n", shortenKey(tx.Owner))
+			fmt.Printf("%s\n", shortenKey(core.ResolveKey(blockchain, tx.Owner)))
 
 			infoColor.Printf("🆔 Asset ID: ")
 			hashColor.Printf("%s\n", assetHash)
 
+			if status := assetStatus[assetHash]; status != core.AssetActive {
+				infoColor.Printf(" Status: ")
+				statusColor.Printf("%s\n", status)
+			}
+
 			// Display timestamp in human-readable format
 			if tx.Timestamp > 0 {
 				timeStr := time.Unix(tx.Timestamp, 0).Format("2006-01-02 15:04:05")
@@ -121,4 +136,5 @@ func shortenKey(key string) string {
 
 func init() {
 	rootCmd.AddCommand(listAssetsCmd)
+	listAssetsCmd.Flags().BoolVar(&showAllAssets, "all", false, "Include delisted and taken down assets")
 }
@@ -3,7 +3,6 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/Saumya40-codes/DeSecure/core"
 	storage "github.com/Saumya40-codes/DeSecure/pkg"
@@ -22,8 +21,7 @@ var blockchainCmd = &cobra.Command{
 		fmt.Printf("Blockchain Status - %d blocks\n\n", len(blockchain.Blocks))
 
 		for i, block := range blockchain.Blocks {
-			// Parse time
-			t, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", block.Timestamp)
+			t, err := core.BlockTime(block)
 			timeStr := block.Timestamp
 			if err == nil {
 				timeStr = t.Format("2006-01-02 15:04:05")
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+)
+
+// Networking flags shared by every command that joins the network
+var (
+	bootstrapPeers []string
+	noMDNS         bool
+)
+
+// Join the network as a client, to submit transactions and follow their receipts
+func newClientNode(ctx context.Context) (*core.Node, error) {
+	return core.NewNodeWithConfig(ctx, core.NodeConfig{
+		Topic:  core.ConsensusTopic,
+		Peers:  bootstrapPeers,
+		NoMDNS: noMDNS,
+	})
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringSliceVar(&bootstrapPeers, "bootstrap", nil, "Peer multiaddr (with /p2p/<id>) to connect to and keep connected, can be repeated")
+	rootCmd.PersistentFlags().BoolVar(&noMDNS, "no-mdns", false, "Don't discover peers with multicast DNS, only connect to the bootstrap peers")
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+	"github.com/spf13/cobra"
+)
+
+var nodeConfigPath string
+
+var nodeCmd = &cobra.Command{
+	Use:   "node",
+	Short: "Inspect the libp2p identity of a node",
+}
+
+var nodeIDCmd = &cobra.Command{
+	Use:   "id",
+	Short: "Print the peer ID of a validator and the addresses other nodes can bootstrap from",
+	Long: `Prints the libp2p peer ID of a validator, which stays the same across restarts since its key
+is kept in the validator's data directory. The key is created if the validator never started.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := core.LoadValidatorConfig(nodeConfigPath)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		id, err := core.NodeID(cfg.NodeKeyPath())
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		fmt.Println("🔑 Peer ID:", id)
+
+		addrs := cfg.AnnounceAddrs
+		if len(addrs) == 0 {
+			addrs = cfg.ListenAddrs
+		}
+		if len(addrs) == 0 {
+			fmt.Println("ℹ️ No listen address configured, the validator picks random ports on every start")
+			return
+		}
+		fmt.Println("ℹ️ Bootstrap from it with:")
+		for _, addr := range addrs {
+			fmt.Printf("   --bootstrap %s/p2p/%s\n", addr, id)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(nodeCmd)
+	nodeCmd.AddCommand(nodeIDCmd)
+
+	nodeIDCmd.Flags().StringVarP(&nodeConfigPath, "config", "c", "", "Path to the validator config file")
+	nodeIDCmd.MarkFlagRequired("config")
+}
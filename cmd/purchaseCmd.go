@@ -2,10 +2,7 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
-	"time"
 
 	"github.com/Saumya40-codes/DeSecure/core"
 	storage "github.com/Saumya40-codes/DeSecure/pkg"
@@ -22,66 +19,44 @@ var purchaseCmd = &cobra.Command{
 	Short: "Purchase a license for an asset on the blockchain",
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := context.Background()
-		node, err := core.NewNode(ctx, "transactions", false)
+		node, err := newClientNode(ctx)
 		if err != nil {
 			fmt.Println("Error creating P2P node:", err)
 			return
 		}
 
-		privKey, pubKey := ensureKeyPair()
+		signer, err := ensureSigner()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		pubKey := signer.pubKey
 
 		// Open database to get asset information
 		db := storage.OpenDB("./data")
 		defer db.CloseDB()
 
-		var originalTx core.LicenseTransaction
-		var assetData []byte
-
 		bc := core.NewBlockchain(db)
 
-		for _, blocks := range bc.Blocks {
-			for _, tx := range blocks.Transaction {
-				if tx.AssetHash == assetID {
-					assetData, err = json.Marshal(tx)
-					if err == nil {
-						db.Save(assetID, assetData)
-					} else {
-						db.CloseDB()
-						log.Fatal("Error occurred")
-						fmt.Println(err)
-					}
-				}
-			}
+		purchaseTx, err := buildPurchaseTransaction(bc, assetID, pubKey)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
 		}
 
-		if err := json.Unmarshal(assetData, &originalTx); err != nil {
-			fmt.Println("❌ Error decoding asset data:", err)
+		purchaseTx.Signature, err = signer.Sign(&purchaseTx)
+		if err != nil {
+			fmt.Println("❌ Error signing transaction:", err)
 			return
 		}
 
-		// Create purchase transaction
-		purchaseTx := core.LicenseTransaction{
-			Owner:       originalTx.Owner,    // Original owner
-			Licensee:    pubKey,              // New licensee (buyer)
-			AssetHash:   assetID,             // Asset being purchased
-			License:     originalTx.License,  // Keep same license type
-			Metadata:    originalTx.Metadata, // Keep same metadata
-			Timestamp:   time.Now().Unix(),
-			IsValidated: false,
-			TxType:      "purchase", // Mark as purchase transaction
+		if !submitTransaction(node, bc, purchaseTx) {
+			return
 		}
 
-		purchaseTx.TxID = core.GenerateTransactionID(purchaseTx)
-		purchaseTx.Signature = core.SignTransaction(privKey, &purchaseTx)
-
-		fmt.Println("🌐 Broadcasting purchase transaction to network for validation...")
-		node.BroadcastTransaction(purchaseTx)
-
-		fmt.Println("✅ Purchase request broadcast complete! TxID:", purchaseTx.TxID)
+		fmt.Println("✅ Purchase request submitted! TxID:", purchaseTx.TxID)
 		fmt.Println("ℹ️ Your purchase will be validated by the network and added to the blockchain.")
-		fmt.Println("ℹ️ You can check its status later using the blockchain command.")
-
-		time.Sleep(2 * time.Second)
+		fmt.Println("ℹ️ Follow it with: drmcli tx status", purchaseTx.TxID)
 	},
 }
 
@@ -7,12 +7,20 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Genesis file of the network, the local chain checks blocks, receipts and validator answers against it
+var genesisPath string
+
 var rootCmd = &cobra.Command{
 	Use:   "drmcli",
 	Short: "DRMCLI is a command-line tool for decentralized DRM",
 	Long:  `A command-line tool for managing decentralized digital rights, including file uploads and access verification.`,
 }
 
+func init() {
+	rootCmd.PersistentFlags().StringVar(&keyName, "key", "", "Name of the identity to use (defaults to the one set with \"keys use\")")
+	rootCmd.PersistentFlags().StringVar(&genesisPath, "genesis", "", "Genesis file of the network, stored in the local chain on first use")
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+	storage "github.com/Saumya40-codes/DeSecure/pkg"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rotateTo      string
+	rotateRecover string
+)
+
+var rotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Move ownership and licenses of your identity to a new key",
+	Long: `Rotates the current identity to the key of another local identity. The rotation is signed by
+both keys, and everything owned or licensed by the old key follows the new one.
+
+With --recover, the current identity acts as the registered recovery key of the given public key
+and can rotate it without its signature. The rotation then only takes effect after the recovery
+delay, during which the old key can still cancel it by changing its recovery settings.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		node, err := newClientNode(ctx)
+		if err != nil {
+			fmt.Println("Error creating P2P node:", err)
+			return
+		}
+
+		signer, err := loadSigner()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		pubKey := signer.pubKey
+
+		newSigner, err := identitySigner(rotateTo)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		db := storage.OpenDB("./data")
+		defer db.CloseDB()
+
+		bc := core.NewBlockchain(db)
+
+		oldKey, recoverySigner := pubKey, ""
+		if rotateRecover != "" {
+			oldKey, recoverySigner = rotateRecover, pubKey
+		}
+
+		rotateTx, err := buildRotateKeyTransaction(bc, oldKey, newSigner.pubKey, recoverySigner)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		if rotateTx.Signature, err = signer.Sign(&rotateTx); err == nil {
+			rotateTx.CoSignature, err = newSigner.Sign(&rotateTx)
+		}
+		if err != nil {
+			fmt.Println("❌ Error signing transaction:", err)
+			return
+		}
+
+		if !submitTransaction(node, bc, rotateTx) {
+			return
+		}
+
+		fmt.Println("✅ Key rotation submitted! TxID:", rotateTx.TxID)
+		fmt.Println("ℹ️ Follow it with: drmcli tx status", rotateTx.TxID)
+		if recoverySigner != "" {
+			fmt.Println("ℹ️ The rotation takes effect once the recovery delay has passed.")
+		} else {
+			fmt.Printf("ℹ️ Once included, switch to the new key with: drmcli keys use %s\n", rotateTo)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rotateKeyCmd)
+	rotateKeyCmd.Flags().StringVar(&rotateTo, "to", "", "Local identity holding the new key")
+	rotateKeyCmd.Flags().StringVar(&rotateRecover, "recover", "", "Public key to rotate as its recovery key")
+	rotateKeyCmd.MarkFlagRequired("to")
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+	storage "github.com/Saumya40-codes/DeSecure/pkg"
+	"github.com/spf13/cobra"
+)
+
+var (
+	recoveryKey   string
+	recoveryDelay time.Duration
+)
+
+var setRecoveryCmd = &cobra.Command{
+	Use:   "set-recovery",
+	Short: "Register a recovery key that can rotate your identity if your key is lost",
+	Long: `Registers a recovery key for the current identity. The recovery key can later rotate the identity
+on its own, but the rotation only takes effect after the given delay. Pass an empty --recovery-key
+to remove the recovery key; doing so also cancels a recovery rotation that hasn't taken effect yet.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		node, err := newClientNode(ctx)
+		if err != nil {
+			fmt.Println("Error creating P2P node:", err)
+			return
+		}
+
+		signer, err := loadSigner()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		pubKey := signer.pubKey
+
+		db := storage.OpenDB("./data")
+		defer db.CloseDB()
+
+		bc := core.NewBlockchain(db)
+
+		recoveryTx, err := buildSetRecoveryTransaction(bc, pubKey, recoveryKey, recoveryDelay)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		recoveryTx.Signature, err = signer.Sign(&recoveryTx)
+		if err != nil {
+			fmt.Println("❌ Error signing transaction:", err)
+			return
+		}
+
+		if !submitTransaction(node, bc, recoveryTx) {
+			return
+		}
+
+		fmt.Println("✅ Recovery settings submitted! TxID:", recoveryTx.TxID)
+		fmt.Println("ℹ️ Follow it with: drmcli tx status", recoveryTx.TxID)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(setRecoveryCmd)
+	setRecoveryCmd.Flags().StringVar(&recoveryKey, "recovery-key", "", "Public key allowed to recover this identity")
+	setRecoveryCmd.Flags().DurationVar(&recoveryDelay, "delay", 72*time.Hour, "Time before a recovery rotation takes effect")
+}
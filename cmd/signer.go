@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"os"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+)
+
+// AgentSockEnv points the CLI at a running "drmcli agent"
+const AgentSockEnv = "DRMCLI_AGENT_SOCK"
+
+// Signs transactions for one identity, either with a decrypted key or through the agent
+type keySigner struct {
+	pubKey  string
+	privKey *ecdsa.PrivateKey
+	agent   *core.AgentClient
+}
+
+func (s *keySigner) Sign(tx *core.LicenseTransaction) (string, error) {
+	if s.agent != nil {
+		return s.agent.Sign(s.pubKey, *tx)
+	}
+	return core.SignTransaction(s.privKey, tx), nil
+}
+
+func agentClient() *core.AgentClient {
+	path := os.Getenv(AgentSockEnv)
+	if path == "" {
+		return nil
+	}
+	return &core.AgentClient{Path: path}
+}
+
+// Signer for the selected identity, preferring the agent when one is configured
+func loadSigner() (*keySigner, error) {
+	name, _, err := selectedIdentity()
+	if err != nil {
+		return nil, err
+	}
+	return identitySigner(name)
+}
+
+// Like loadSigner, but creates the identity if there is neither an agent nor a key yet
+func ensureSigner() (*keySigner, error) {
+	if agent := agentClient(); agent != nil {
+		return loadSigner()
+	}
+
+	privKey, pubKey, err := ensureKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	return &keySigner{pubKey: pubKey, privKey: privKey}, nil
+}
+
+func identitySigner(name string) (*keySigner, error) {
+	agent := agentClient()
+	if agent == nil {
+		privKey, pubKey, err := loadIdentity(name)
+		if err != nil {
+			return nil, err
+		}
+		return &keySigner{pubKey: pubKey, privKey: privKey}, nil
+	}
+
+	keys, err := agent.Keys()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if key.Name == name {
+			return &keySigner{pubKey: key.PublicKey, agent: agent}, nil
+		}
+	}
+	return nil, fmt.Errorf("agent doesn't hold identity %q, add it with \"drmcli agent add %s\"", name, name)
+}
+
+// Signer holding the key of a validator, for governance and takedowns
+func validatorSigner(configPath string) (*keySigner, error) {
+	cfg, err := core.LoadValidatorConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	privKey, pubKey, err := unlockValidatorKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &keySigner{pubKey: pubKey, privKey: privKey}, nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+)
+
+// submitTransaction hands tx to the validators and reports their decisions,
+// returning false when no validator admitted the transaction
+func submitTransaction(node *core.Node, bc *core.Blockchain, tx core.LicenseTransaction) bool {
+	fmt.Println("🌐 Submitting transaction to validators...")
+
+	if genesisPath != "" && !initLocalGenesis(bc, genesisPath) {
+		return false
+	}
+
+	opts := core.DefaultSubmitOptions()
+	opts.Validators = bc.Validators()
+	if opts.Validators == nil {
+		fmt.Println("⚠️ The local chain has no genesis to check the answers of validators against, the transaction is only gossiped, pass it with --genesis")
+	}
+
+	result, err := node.SubmitTransaction(context.Background(), tx, opts)
+	if err != nil {
+		fmt.Println("❌ Error submitting transaction:", err)
+		return false
+	}
+
+	// What a validator decided is only its own admission, the consensus decides the outcome
+	for id, reason := range result.Rejections {
+		fmt.Printf("⚠️ Validator %d didn't admit the transaction: %s\n", id, reason)
+		bc.RecordRejection(tx.TxID, id, reason)
+	}
+
+	switch {
+	case result.Gossiped:
+		fmt.Println("⚠️ No validator acknowledged the transaction, it was broadcast via gossip instead")
+		recordPendingTransaction(bc, tx.TxID)
+
+		// Wait a brief moment to ensure message is sent before the program exits
+		time.Sleep(2 * time.Second)
+	case result.Accepted:
+		fmt.Printf("✅ Accepted into the mempool of validator %d\n", result.ValidatorID)
+		bc.SaveReceipt(core.Receipt{
+			TxID:      tx.TxID,
+			Status:    core.TxStatusInMempool,
+			Timestamp: time.Now().Unix(),
+		})
+	default:
+		fmt.Println("❌ No validator admitted the transaction")
+		return false
+	}
+
+	return true
+}
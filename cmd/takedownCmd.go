@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var takedownReason string
+
+var takedownCmd = &cobra.Command{
+	Use:   "takedown",
+	Short: "Propose removing an asset for legal or compliance reasons",
+	Long: `Submits a takedown transaction for an asset, signed with a validator key. The other validators
+approve it with "drmcli gov vote", and the asset is removed once a quorum of them approved it.
+Taken down content can no longer be accessed.
+
+Use --validator to sign with the key of a validator config or --key for an identity holding it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if takedownReason == "" {
+			fmt.Println("❌ Please give a reason for the takedown with -r flag")
+			return
+		}
+
+		node, bc, signer, cleanup, ok := openGovernance()
+		if !ok {
+			return
+		}
+		defer cleanup()
+
+		takedownTx, err := buildTakedownTransaction(bc, assetID, signer.pubKey, takedownReason)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		takedownTx.Signature, err = signer.Sign(&takedownTx)
+		if err != nil {
+			fmt.Println("❌ Error signing transaction:", err)
+			return
+		}
+
+		if !submitTransaction(node, bc, takedownTx) {
+			return
+		}
+
+		fmt.Println("✅ Takedown request submitted! TxID:", takedownTx.TxID)
+		fmt.Println("ℹ️ Other validators approve it with: drmcli gov vote", takedownTx.TxID)
+		fmt.Println("ℹ️ The asset is removed once the validator quorum approves the request.")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(takedownCmd)
+	takedownCmd.Flags().StringVarP(&assetID, "asset", "a", "", "Asset ID/hash to take down")
+	takedownCmd.Flags().StringVarP(&takedownReason, "reason", "r", "", "Legal or compliance reason for the takedown")
+	takedownCmd.Flags().StringVar(&govValidatorConfig, "validator", "", "Sign with the key of this validator config")
+	takedownCmd.MarkFlagRequired("asset")
+}
@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+)
+
+// The builders below only read the local chain to fill in owners and nonces,
+// so the same transaction can be built, signed and broadcast on different machines.
+
+func buildUploadTransaction(bc *core.Blockchain, owner, assetHash string) core.LicenseTransaction {
+	// Create metadata object
+	metadata := map[string]string{
+		"Title":       title,
+		"Description": description,
+		"Category":    category,
+	}
+
+	metadataJSON, _ := json.Marshal(metadata)
+
+	// Create transaction with all required fields
+	transaction := core.LicenseTransaction{
+		Owner:       owner,
+		AssetHash:   assetHash,
+		License:     license,
+		Metadata:    string(metadataJSON),
+		Timestamp:   time.Now().Unix(),
+		IsValidated: false,
+		Nonce:       core.NextNonce(bc, owner),
+		TxType:      core.TxTypeUpload,
+	}
+
+	// Generate transaction ID
+	transaction.TxID = core.GenerateTransactionID(transaction)
+	return transaction
+}
+
+func buildPurchaseTransaction(bc *core.Blockchain, assetHash, buyer string) (core.LicenseTransaction, error) {
+	originalTx := core.FindUpload(assetHash, bc)
+	if originalTx == nil {
+		return core.LicenseTransaction{}, fmt.Errorf("asset not found on the blockchain: %s", assetHash)
+	}
+
+	if status := core.AssetStatus(assetHash, bc); status != core.AssetActive {
+		return core.LicenseTransaction{}, fmt.Errorf("asset is %s and no longer available for purchase", status)
+	}
+
+	// Create purchase transaction
+	purchaseTx := core.LicenseTransaction{
+		Owner:       core.ResolveKey(bc, originalTx.Owner), // Current key of the original owner
+		Licensee:    buyer,                                 // New licensee (buyer)
+		AssetHash:   assetHash,                             // Asset being purchased
+		License:     originalTx.License,                    // Keep same license type
+		Metadata:    originalTx.Metadata,                   // Keep same metadata
+		Timestamp:   time.Now().Unix(),
+		IsValidated: false,
+		Nonce:       core.NextNonce(bc, buyer),
+		TxType:      core.TxTypePurchase, // Mark as purchase transaction
+	}
+
+	purchaseTx.TxID = core.GenerateTransactionID(purchaseTx)
+	return purchaseTx, nil
+}
+
+func buildDelistTransaction(bc *core.Blockchain, assetHash, owner string) (core.LicenseTransaction, error) {
+	upload := core.FindUpload(assetHash, bc)
+	if upload == nil {
+		return core.LicenseTransaction{}, fmt.Errorf("asset not found on the blockchain: %s", assetHash)
+	}
+
+	if core.ResolveKey(bc, upload.Owner) != owner {
+		return core.LicenseTransaction{}, fmt.Errorf("only the owner of an asset can delist it")
+	}
+
+	if status := core.AssetStatus(assetHash, bc); status != core.AssetActive {
+		return core.LicenseTransaction{}, fmt.Errorf("asset is already %s", status)
+	}
+
+	delistTx := core.LicenseTransaction{
+		Owner:       owner,
+		AssetHash:   assetHash,
+		License:     upload.License,
+		Timestamp:   time.Now().Unix(),
+		IsValidated: false,
+		Nonce:       core.NextNonce(bc, owner),
+		TxType:      core.TxTypeDelist,
+	}
+
+	delistTx.TxID = core.GenerateTransactionID(delistTx)
+	return delistTx, nil
+}
+
+func buildTakedownTransaction(bc *core.Blockchain, assetHash, signer, reason string) (core.LicenseTransaction, error) {
+	if reason == "" {
+		return core.LicenseTransaction{}, fmt.Errorf("a takedown needs a reason")
+	}
+
+	status := core.AssetStatus(assetHash, bc)
+	if status == "" {
+		return core.LicenseTransaction{}, fmt.Errorf("asset not found on the blockchain: %s", assetHash)
+	}
+	if status == core.AssetTakenDown {
+		return core.LicenseTransaction{}, fmt.Errorf("asset has already been taken down")
+	}
+
+	takedownTx := core.LicenseTransaction{
+		Owner:       signer,
+		AssetHash:   assetHash,
+		Metadata:    core.NewTakedownMetadata(reason),
+		Timestamp:   time.Now().Unix(),
+		IsValidated: false,
+		Nonce:       core.NextNonce(bc, signer),
+		TxType:      core.TxTypeTakedown,
+	}
+
+	takedownTx.TxID = core.GenerateTransactionID(takedownTx)
+	return takedownTx, nil
+}
+
+// recoverySigner is empty for a regular rotation signed by oldKey itself
+func buildRotateKeyTransaction(bc *core.Blockchain, oldKey, newKey, recoverySigner string) (core.LicenseTransaction, error) {
+	if newKey == "" || newKey == oldKey {
+		return core.LicenseTransaction{}, fmt.Errorf("the new key must differ from the current one")
+	}
+
+	if current := core.ResolveKey(bc, oldKey); current != oldKey {
+		return core.LicenseTransaction{}, fmt.Errorf("key has already been rotated to %s", shortenKey(current))
+	}
+
+	signer := oldKey
+	if recoverySigner != "" {
+		signer = recoverySigner
+	}
+
+	rotateTx := core.LicenseTransaction{
+		Owner:       oldKey,
+		Licensee:    recoverySigner,
+		NewKey:      newKey,
+		Timestamp:   time.Now().Unix(),
+		IsValidated: false,
+		Nonce:       core.NextNonce(bc, signer),
+		TxType:      core.TxTypeRotateKey,
+	}
+
+	rotateTx.TxID = core.GenerateTransactionID(rotateTx)
+	return rotateTx, nil
+}
+
+func buildSetRecoveryTransaction(bc *core.Blockchain, owner, recoveryKey string, delay time.Duration) (core.LicenseTransaction, error) {
+	if recoveryKey == owner {
+		return core.LicenseTransaction{}, fmt.Errorf("the recovery key must differ from your key")
+	}
+	if recoveryKey != "" && delay <= 0 {
+		return core.LicenseTransaction{}, fmt.Errorf("the recovery delay must be positive")
+	}
+
+	recoveryTx := core.LicenseTransaction{
+		Owner:         owner,
+		RecoveryKey:   recoveryKey,
+		RecoveryDelay: int64(delay.Seconds()),
+		Timestamp:     time.Now().Unix(),
+		IsValidated:   false,
+		Nonce:         core.NextNonce(bc, owner),
+		TxType:        core.TxTypeSetRecovery,
+	}
+
+	recoveryTx.TxID = core.GenerateTransactionID(recoveryTx)
+	return recoveryTx, nil
+}
+
+func buildProposalTransaction(bc *core.Blockchain, signer string, proposal core.Proposal) (core.LicenseTransaction, error) {
+	validators := bc.Validators()
+	if validators == nil {
+		return core.LicenseTransaction{}, fmt.Errorf("the local chain has no genesis, pass it with --genesis")
+	}
+	if !validators.HasKey(signer) {
+		return core.LicenseTransaction{}, fmt.Errorf("only validators can make proposals, %s isn't one", shortenKey(signer))
+	}
+
+	proposeTx := core.LicenseTransaction{
+		Owner:       signer,
+		Metadata:    core.NewProposalMetadata(proposal),
+		Timestamp:   time.Now().Unix(),
+		IsValidated: false,
+		Nonce:       core.NextNonce(bc, signer),
+		TxType:      core.TxTypeGovPropose,
+	}
+
+	proposeTx.TxID = core.GenerateTransactionID(proposeTx)
+	return proposeTx, nil
+}
+
+func buildProposalVoteTransaction(bc *core.Blockchain, signer, proposalID string, approve bool) (core.LicenseTransaction, error) {
+	proposals, err := bc.Proposals()
+	if err != nil {
+		return core.LicenseTransaction{}, fmt.Errorf("the local chain has no genesis, pass it with --genesis")
+	}
+
+	var found *core.ProposalStatus
+	for i := range proposals {
+		if proposals[i].ID == proposalID {
+			found = &proposals[i]
+		}
+	}
+	if found == nil {
+		return core.LicenseTransaction{}, fmt.Errorf("proposal not found on the blockchain: %s", proposalID)
+	}
+	if found.Status != core.ProposalPending {
+		return core.LicenseTransaction{}, fmt.Errorf("proposal is already %s", found.Status)
+	}
+
+	voteTx := core.LicenseTransaction{
+		Owner:       signer,
+		Metadata:    core.NewProposalVoteMetadata(core.ProposalVote{ProposalID: proposalID, Approve: approve}),
+		Timestamp:   time.Now().Unix(),
+		IsValidated: false,
+		Nonce:       core.NextNonce(bc, signer),
+		TxType:      core.TxTypeGovVote,
+	}
+
+	voteTx.TxID = core.GenerateTransactionID(voteTx)
+	return voteTx, nil
+}
+
+func buildEvidenceTransaction(bc *core.Blockchain, reporter string, evidence core.Evidence) core.LicenseTransaction {
+	evidenceTx := core.LicenseTransaction{
+		Owner:       reporter,
+		Metadata:    core.NewEvidenceMetadata(evidence),
+		Timestamp:   time.Now().Unix(),
+		IsValidated: false,
+		Nonce:       core.NextNonce(bc, reporter),
+		TxType:      core.TxTypeEvidence,
+	}
+
+	evidenceTx.TxID = core.GenerateTransactionID(evidenceTx)
+	return evidenceTx
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+	storage "github.com/Saumya40-codes/DeSecure/pkg"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	waitTimeout time.Duration
+	statusData  string
+)
+
+var txCmd = &cobra.Command{
+	Use:   "tx",
+	Short: "Inspect and follow transactions",
+}
+
+var txStatusCmd = &cobra.Command{
+	Use:   "status <txid>",
+	Short: "Show the status of a transaction",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db := storage.OpenDB(statusData)
+		defer db.CloseDB()
+
+		blockchain := core.NewBlockchain(db)
+
+		receipt, confirmations := blockchain.TransactionStatus(args[0])
+		rejection := blockchain.Rejection(args[0])
+		if receipt == nil && rejection == nil {
+			fmt.Println("🔍 Transaction not known to this node:", args[0])
+			return
+		}
+
+		if receipt != nil {
+			printReceipt(receipt, confirmations)
+		}
+		if rejection != nil {
+			printRejection(rejection)
+		}
+	},
+}
+
+var txWaitCmd = &cobra.Command{
+	Use:   "wait <txid>",
+	Short: "Block until a transaction is included or rejected",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// Loading the chain on every poll is noisy, only the outcome matters here
+		log.SetOutput(io.Discard)
+
+		fmt.Println("⏳ Waiting for transaction", shortenHash(args[0]))
+
+		deadline := time.Now().Add(waitTimeout)
+		for {
+			receipt, confirmations := pollTransactionStatus(statusData, args[0])
+			if receipt != nil && core.IsFinal(receipt.Status) {
+				printReceipt(receipt, confirmations)
+				if receipt.Status == core.TxStatusRejected {
+					os.Exit(1)
+				}
+				return
+			}
+
+			if time.Now().After(deadline) {
+				status := "unknown"
+				if receipt != nil {
+					status = receipt.Status
+				}
+				fmt.Printf("❌ Timed out after %s, last known status: %s\n", waitTimeout, status)
+				os.Exit(1)
+			}
+
+			time.Sleep(2 * time.Second)
+		}
+	},
+}
+
+// The database is reopened on every poll so that writes made by the running
+// node since the last check become visible
+func pollTransactionStatus(dataDir, txID string) (*core.Receipt, int) {
+	db := storage.OpenDB(dataDir)
+	defer db.CloseDB()
+
+	return core.NewBlockchain(db).TransactionStatus(txID)
+}
+
+func printReceipt(receipt *core.Receipt, confirmations int) {
+	infoColor := color.New(color.FgWhite)
+	statusColor := color.New(color.FgGreen, color.Bold)
+	if receipt.Status == core.TxStatusRejected {
+		statusColor = color.New(color.FgRed, color.Bold)
+	}
+
+	infoColor.Printf("🆔 TxID: ")
+	fmt.Printf("%s\n", receipt.TxID)
+	infoColor.Printf(" Status: ")
+	statusColor.Printf("%s\n", receipt.Status)
+
+	if receipt.Status == core.TxStatusIncluded {
+		infoColor.Printf(" Block: ")
+		fmt.Printf("#%d %s\n", receipt.BlockIndex, shortenHash(receipt.BlockHash))
+		infoColor.Printf(" Confirmations: ")
+		fmt.Printf("%d\n", confirmations)
+	}
+
+	if receipt.Reason != "" {
+		infoColor.Printf(" Reason: ")
+		fmt.Printf("%s\n", receipt.Reason)
+	}
+
+	if receipt.Timestamp > 0 {
+		infoColor.Printf("⏰ Updated: ")
+		fmt.Printf("%s\n", time.Unix(receipt.Timestamp, 0).Format("2006-01-02 15:04:05"))
+	}
+}
+
+// Reasons validators gave for rejecting the transaction, as recorded by a validator
+func printRejection(rejection *core.Rejection) {
+	infoColor := color.New(color.FgWhite)
+	infoColor.Printf("🚫 Rejections: ")
+	if rejection.Final {
+		fmt.Printf("%s (final)\n", rejection.Summary())
+	} else {
+		fmt.Printf("%s\n", rejection.Summary())
+	}
+
+	ids := make([]int, 0, len(rejection.Reasons))
+	for id := range rejection.Reasons {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		fmt.Printf("   Validator %d: %s\n", id, rejection.Reasons[id])
+	}
+}
+
+// Remember a freshly broadcast transaction so its status can be followed locally
+func recordPendingTransaction(blockchain *core.Blockchain, txID string) {
+	blockchain.SaveReceipt(core.Receipt{
+		TxID:      txID,
+		Status:    core.TxStatusPending,
+		Timestamp: time.Now().Unix(),
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(txCmd)
+	txCmd.AddCommand(txStatusCmd)
+	txCmd.AddCommand(txWaitCmd)
+	txStatusCmd.Flags().StringVar(&statusData, "data", "./data", "Database to look in, e.g. the db directory of a validator for its rejection reasons")
+	txWaitCmd.Flags().StringVar(&statusData, "data", "./data", "Database to look in, the one of the node following the chain")
+	txWaitCmd.Flags().DurationVar(&waitTimeout, "timeout", 2*time.Minute, "Maximum time to wait for finality")
+}
@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+	storage "github.com/Saumya40-codes/DeSecure/pkg"
+	"github.com/spf13/cobra"
+)
+
+var (
+	txType     string
+	txSigner   string
+	txBuildOut string
+	txSignOut  string
+	txNewKey   string
+	txSignYes  bool
+)
+
+var txBuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Build an unsigned transaction file",
+	Long: `Builds a transaction from the local view of the chain and writes it, unsigned, to a JSON file.
+Sign it with "tx sign" (which works offline) and submit it with "tx broadcast".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		signer := txSigner
+		if signer == "" {
+			var err error
+			if signer, err = loadPublicKey(); err != nil {
+				fmt.Println("❌", err)
+				return
+			}
+		}
+
+		db := storage.OpenDB("./data")
+		defer db.CloseDB()
+
+		bc := core.NewBlockchain(db)
+
+		var transaction core.LicenseTransaction
+		var err error
+		switch txType {
+		case core.TxTypeUpload:
+			assetHash := assetID
+			if filePath != "" {
+				if assetHash, err = storage.UploadtoIPFS(filePath); err != nil {
+					fmt.Println("Error uploading file to IPFS:", err)
+					return
+				}
+				fmt.Println("✅ File uploaded to IPFS, CID:", assetHash)
+			}
+			if assetHash == "" {
+				fmt.Println("❌ Please specify the asset with -a or a file to upload with -f")
+				return
+			}
+			transaction = buildUploadTransaction(bc, signer, assetHash)
+		case core.TxTypePurchase:
+			transaction, err = buildPurchaseTransaction(bc, assetID, signer)
+		case core.TxTypeDelist:
+			transaction, err = buildDelistTransaction(bc, assetID, signer)
+		case core.TxTypeTakedown:
+			transaction, err = buildTakedownTransaction(bc, assetID, signer, takedownReason)
+		case core.TxTypeRotateKey:
+			if rotateRecover != "" {
+				transaction, err = buildRotateKeyTransaction(bc, rotateRecover, txNewKey, signer)
+			} else {
+				transaction, err = buildRotateKeyTransaction(bc, signer, txNewKey, "")
+			}
+		case core.TxTypeSetRecovery:
+			transaction, err = buildSetRecoveryTransaction(bc, signer, recoveryKey, recoveryDelay)
+		default:
+			err = fmt.Errorf("unknown transaction type %q", txType)
+		}
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		if err := writeTransactionFile(txBuildOut, transaction); err != nil {
+			fmt.Println("❌ Error writing transaction file:", err)
+			return
+		}
+
+		fmt.Println("✅ Unsigned transaction written to", txBuildOut)
+		fmt.Println("🆔 TxID:", transaction.TxID)
+	},
+}
+
+var txSignCmd = &cobra.Command{
+	Use:   "sign <file>",
+	Short: "Sign a transaction file with the local key, without network access",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		transaction, err := readTransactionFile(args[0])
+		if err != nil {
+			fmt.Println("❌ Error reading transaction file:", err)
+			return
+		}
+
+		// The ID is derived from every signed field, a mismatch means the file was edited
+		if transaction.TxID != core.GenerateTransactionID(transaction) {
+			fmt.Println("❌ Transaction ID doesn't match its contents, rebuild the transaction")
+			return
+		}
+
+		localSigner, err := loadSigner()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		pubKey := localSigner.pubKey
+
+		// The file may come from someone else, the signer has to see what it signs
+		fmt.Println("✍️ Signing this transaction:")
+		printTransaction(transaction)
+		if !txSignYes && !askConfirmation("Sign it?") {
+			fmt.Println("❌ Not signed")
+			return
+		}
+
+		// Rotations carry a second signature from the key taking over
+		switch {
+		case core.TransactionSigner(transaction) == pubKey:
+			transaction.Signature, err = localSigner.Sign(&transaction)
+		case transaction.TxType == core.TxTypeRotateKey && transaction.NewKey == pubKey:
+			transaction.CoSignature, err = localSigner.Sign(&transaction)
+		default:
+			signer := core.TransactionSigner(transaction)
+			fmt.Printf("❌ Transaction must be signed by %s, local key is %s\n", shortenKey(signer), shortenKey(pubKey))
+			return
+		}
+		if err != nil {
+			fmt.Println("❌ Error signing transaction:", err)
+			return
+		}
+
+		out := txSignOut
+		if out == "" {
+			out = args[0]
+		}
+		if err := writeTransactionFile(out, transaction); err != nil {
+			fmt.Println("❌ Error writing transaction file:", err)
+			return
+		}
+
+		fmt.Println("✅ Signed transaction written to", out)
+	},
+}
+
+var txBroadcastCmd = &cobra.Command{
+	Use:   "broadcast <file>",
+	Short: "Submit a signed transaction file to the network",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		transaction, err := readTransactionFile(args[0])
+		if err != nil {
+			fmt.Println("❌ Error reading transaction file:", err)
+			return
+		}
+
+		if !core.VerifyTransaction(transaction) {
+			fmt.Println("❌ Transaction is not signed or its signature is invalid")
+			return
+		}
+
+		node, err := newClientNode(context.Background())
+		if err != nil {
+			fmt.Println("Error creating P2P node:", err)
+			return
+		}
+
+		db := storage.OpenDB("./data")
+		defer db.CloseDB()
+
+		bc := core.NewBlockchain(db)
+
+		if !submitTransaction(node, bc, transaction) {
+			return
+		}
+
+		fmt.Println("✅ Transaction submitted! TxID:", transaction.TxID)
+		fmt.Println("ℹ️ Follow it with: drmcli tx status", transaction.TxID)
+	},
+}
+
+// Print every field covered by the signature of a transaction
+func printTransaction(tx core.LicenseTransaction) {
+	fields := []struct{ name, value string }{
+		{"TxID", tx.TxID},
+		{"Type", tx.TxType},
+		{"Signer", core.TransactionSigner(tx)},
+		{"Owner", tx.Owner},
+		{"Asset", tx.AssetHash},
+		{"License", tx.License},
+		{"Licensee", tx.Licensee},
+		{"Metadata", tx.Metadata},
+		{"Timestamp", formatUnix(tx.Timestamp)},
+		{"Expiry", formatUnix(tx.Expiry)},
+		{"Nonce", fmt.Sprint(tx.Nonce)},
+		{"New key", tx.NewKey},
+		{"Recovery key", tx.RecoveryKey},
+		{"Recovery delay", (time.Duration(tx.RecoveryDelay) * time.Second).String()},
+	}
+	for _, field := range fields {
+		value := field.value
+		if value == "" {
+			value = "-"
+		}
+		fmt.Printf("   %-15s %s\n", field.name+":", value)
+	}
+}
+
+func formatUnix(ts int64) string {
+	if ts == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s (%d)", time.Unix(ts, 0).UTC().Format(time.RFC3339), ts)
+}
+
+// Ask a yes/no question on the terminal, anything but yes is no
+func askConfirmation(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func readTransactionFile(path string) (core.LicenseTransaction, error) {
+	var transaction core.LicenseTransaction
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return transaction, err
+	}
+
+	err = json.Unmarshal(data, &transaction)
+	return transaction, err
+}
+
+func writeTransactionFile(path string, transaction core.LicenseTransaction) error {
+	data, err := json.MarshalIndent(transaction, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func init() {
+	txCmd.AddCommand(txBuildCmd)
+	txCmd.AddCommand(txSignCmd)
+	txCmd.AddCommand(txBroadcastCmd)
+
+	txBuildCmd.Flags().StringVar(&txType, "type", core.TxTypeUpload, "Transaction type (upload, purchase, delist, takedown, rotate-key, set-recovery)")
+	txBuildCmd.Flags().StringVar(&txSigner, "signer", "", "Public key that will sign the transaction (defaults to the local key)")
+	txBuildCmd.Flags().StringVarP(&txBuildOut, "out", "o", "tx.json", "Where to write the unsigned transaction")
+	txBuildCmd.Flags().StringVarP(&assetID, "asset", "a", "", "Asset ID/hash the transaction refers to")
+	txBuildCmd.Flags().StringVarP(&filePath, "file", "f", "", "File to upload to IPFS (upload only)")
+	txBuildCmd.Flags().StringVarP(&title, "title", "t", "Untitled", "Title of the asset (upload only)")
+	txBuildCmd.Flags().StringVarP(&description, "description", "d", "", "Description of the asset (upload only)")
+	txBuildCmd.Flags().StringVarP(&category, "category", "c", "Uncategorized", "Category of the asset (upload only)")
+	txBuildCmd.Flags().StringVarP(&license, "license", "l", "view", "License type (upload only)")
+	txBuildCmd.Flags().StringVarP(&takedownReason, "reason", "r", "", "Reason for the takedown (takedown only)")
+	txBuildCmd.Flags().StringVar(&txNewKey, "new-key", "", "Public key taking over (rotate-key only)")
+	txBuildCmd.Flags().StringVar(&rotateRecover, "recover", "", "Public key rotated by the signer as its recovery key (rotate-key only)")
+	txBuildCmd.Flags().StringVar(&recoveryKey, "recovery-key", "", "Recovery key to register (set-recovery only)")
+	txBuildCmd.Flags().DurationVar(&recoveryDelay, "delay", 72*time.Hour, "Recovery delay (set-recovery only)")
+
+	txSignCmd.Flags().StringVarP(&txSignOut, "out", "o", "", "Where to write the signed transaction (defaults to overwriting the input)")
+	txSignCmd.Flags().BoolVarP(&txSignYes, "yes", "y", false, "Sign without asking for confirmation")
+}
@@ -22,7 +22,11 @@ var myAssetsCmd = &cobra.Command{
 		blockchain := core.NewBlockchain(db)
 
 		// Get user's public key
-		_, pubKey := loadKeyPair()
+		pubKey, err := loadPublicKey()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
 
 		titleColor := color.New(color.FgCyan, color.Bold)
 		headerColor := color.New(color.FgGreen, color.Bold)
@@ -42,6 +46,9 @@ var myAssetsCmd = &cobra.Command{
 
 		printCenteredTitle("Your Digital Assets")
 
+		// Assets follow the identity through key rotations
+		keys := core.NewKeyResolver(blockchain)
+
 		// Track unique assets (owned or licensed)
 		myAssets := make(map[string]core.LicenseTransaction)
 
@@ -49,8 +56,12 @@ var myAssetsCmd = &cobra.Command{
 		for i := len(blockchain.Blocks) - 1; i >= 0; i-- {
 			block := blockchain.Blocks[i]
 			for _, tx := range block.Transaction {
+				if tx.AssetHash == "" {
+					continue
+				}
+
 				// If you're the owner or licensee and we haven't seen this asset yet
-				if tx.Owner == pubKey || tx.Licensee == pubKey {
+				if keys.Resolve(tx.Owner) == pubKey || keys.Resolve(tx.Licensee) == pubKey {
 					if _, exists := myAssets[tx.AssetHash]; !exists {
 						myAssets[tx.AssetHash] = tx
 					}
@@ -92,12 +103,12 @@ var myAssetsCmd = &cobra.Command{
 
 			// Show your role (owner or licensee)
 			infoColor.Printf(" Your Role: ")
-			if tx.Owner == pubKey {
+			if keys.Resolve(tx.Owner) == pubKey {
 				roleColor.Printf("Owner\n")
 			} else {
 				roleColor.Printf("Licensee\n")
 				infoColor.Printf(" Owner: ")
-				fmt.Printf("%s\n", shortenKey(tx.Owner))
+				fmt.Printf("%s\n", shortenKey(keys.Resolve(tx.Owner)))
 			}
 
 			infoColor.Printf("🆔 Asset ID: ")
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+	storage "github.com/Saumya40-codes/DeSecure/pkg"
+	"github.com/spf13/cobra"
+)
+
+// ValidatorPassphraseEnv lets validators start unattended, separate from the user's passphrase
+const ValidatorPassphraseEnv = "DRMCLI_VALIDATOR_PASSPHRASE"
+
+const validatorBaseDir = "./validator"
+
+var (
+	validatorID         int
+	validatorDir        string
+	validatorListen     []string
+	validatorAnnounce   []string
+	validatorPeers      []string
+	validatorConfigPath string
+	validatorGenesis    string
+	genesisOut          string
+	genesisQuorum       int
+	genesisConsensus    string
+)
+
+var validatorCmd = &cobra.Command{
+	Use:   "validator",
+	Short: "Set up and run a validator",
+}
+
+var validatorInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a validator key and config",
+	Long: `Creates a validator directory holding an encrypted validator key, a config file and, once
+started, the validator's database. The key stays the same across restarts.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if validatorID < 0 {
+			fmt.Println("❌ Validator ID must not be negative")
+			return
+		}
+
+		dir := validatorDir
+		if dir == "" {
+			dir = filepath.Join(validatorBaseDir, "validator_"+strconv.Itoa(validatorID))
+		}
+		configPath := filepath.Join(dir, core.ValidatorConfigFile)
+		if fileExists(configPath) {
+			fmt.Println("❌ A validator is already set up in", dir)
+			return
+		}
+
+		// Static peers of the config, together with --bootstrap ones given now
+		peers := append(validatorPeers, bootstrapPeers...)
+		if _, err := core.ParsePeers(peers); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		cfg := &core.ValidatorConfig{
+			ID:            validatorID,
+			KeyFile:       core.ValidatorKeyFile,
+			DataDir:       core.ValidatorDataDir,
+			ListenAddrs:   validatorListen,
+			AnnounceAddrs: validatorAnnounce,
+			Peers:         peers,
+			NoMDNS:        noMDNS,
+			Genesis:       validatorGenesis,
+		}
+
+		privKey, pubKey := core.GenerateKeyPair()
+		if privKey == nil {
+			fmt.Println("❌ Failed to generate validator key")
+			return
+		}
+
+		passphrase, err := readPassphraseFrom(ValidatorPassphraseEnv, "New validator passphrase: ", true)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		if err := saveKeystore(filepath.Join(dir, cfg.KeyFile), privKey, passphrase); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		if err := cfg.Save(configPath); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		fmt.Printf("✅ Validator %d initialized in %s\n", validatorID, dir)
+		fmt.Println("🔑 Public key:", pubKey)
+		fmt.Println("ℹ️ Start it with: drmcli validator start --config", configPath)
+	},
+}
+
+var validatorStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Run a validator from its config",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := core.LoadValidatorConfig(validatorConfigPath)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		privKey, pubKey, err := unlockValidatorKey(cfg)
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		// The genesis fixes the validator set, without one the validator runs alone
+		genesis := &core.Genesis{Validators: []core.ValidatorInfo{{ID: cfg.ID, PublicKey: pubKey}}}
+		if path := cfg.GenesisPath(); path != "" {
+			if genesis, err = core.LoadGenesis(path); err != nil {
+				fmt.Println("❌", err)
+				return
+			}
+			if !genesisHasValidator(genesis, cfg.ID, pubKey) {
+				fmt.Printf("❌ Validator %d with key %s isn't part of the genesis validator set\n", cfg.ID, shortenKey(pubKey))
+				return
+			}
+		} else {
+			log.Printf("Validator %d has no genesis configured, running as a single validator network", cfg.ID)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-c
+			log.Println("Shutting down...")
+			cancel()
+		}()
+
+		os.MkdirAll(cfg.DataPath(), 0o700)
+		db := storage.OpenDB(cfg.DataPath())
+		defer func() {
+			db.CloseDB()
+			log.Printf("Validator %d DB closed", cfg.ID)
+		}()
+
+		// Addresses given on the command line replace the configured ones
+		if len(validatorListen) > 0 {
+			cfg.ListenAddrs = validatorListen
+		}
+		if len(validatorAnnounce) > 0 {
+			cfg.AnnounceAddrs = validatorAnnounce
+		}
+
+		node, err := core.NewNodeWithConfig(ctx, core.NodeConfig{
+			Topic:         core.ConsensusTopic,
+			ListenAddrs:   cfg.ListenAddrs,
+			AnnounceAddrs: cfg.AnnounceAddrs,
+			KeyFile:       cfg.NodeKeyPath(),
+			Peers:         append(cfg.Peers, bootstrapPeers...),
+			NoMDNS:        cfg.NoMDNS || noMDNS,
+		})
+		if err != nil {
+			fmt.Printf("❌ Validator %d failed to create node: %v\n", cfg.ID, err)
+			return
+		}
+
+		log.Printf("Validator %d initialized with public key: %s", cfg.ID, shortenKey(pubKey))
+		for _, addr := range node.Host.Addrs() {
+			log.Printf("Validator %d listening on %s/p2p/%s", cfg.ID, addr, node.Host.ID())
+		}
+
+		blockchain := core.NewBlockchain(db)
+		if err := blockchain.InitGenesis(genesis); err != nil {
+			fmt.Printf("❌ Validator %d: %v\n", cfg.ID, err)
+			return
+		}
+		validators := blockchain.Validators()
+		log.Printf("Validator %d: %d validators, %d votes needed to decide", cfg.ID, validators.Size(), validators.Quorum())
+
+		mempool := core.NewMempool(blockchain, cfg.Mempool)
+
+		validator := core.NewValidator(cfg.ID, node, pubKey, privKey, mempool)
+		if err := validator.StartConsensus(ctx, blockchain); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		<-ctx.Done()
+		log.Printf("Validator %d received shutdown signal", cfg.ID)
+	},
+}
+
+var validatorGenesisCmd = &cobra.Command{
+	Use:   "genesis <validator config>...",
+	Short: "Write a genesis file with the given validators as the validator set",
+	Long: `Collects the IDs and public keys of the given validators into a genesis file. Copy it to every
+validator and reference it with "genesis" in their configs (paths are relative to the config).`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		genesis := &core.Genesis{Params: core.ConsensusParams{Quorum: genesisQuorum, Consensus: genesisConsensus}}
+		for _, path := range args {
+			cfg, err := core.LoadValidatorConfig(path)
+			if err != nil {
+				fmt.Println("❌", err)
+				return
+			}
+			ks, err := readKeystore(cfg.KeyPath())
+			if err != nil {
+				fmt.Println("❌", err)
+				return
+			}
+			genesis.Validators = append(genesis.Validators, core.ValidatorInfo{ID: cfg.ID, PublicKey: ks.PublicKey})
+		}
+
+		if err := genesis.Validate(); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		if err := genesis.Save(genesisOut); err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+		validators, _ := genesis.ValidatorSet()
+		fmt.Printf("✅ Genesis with %d validator(s) written to %s\n", validators.Size(), genesisOut)
+		if genesis.Params.Consensus == core.ConsensusRaft {
+			fmt.Printf("ℹ️ Raft consensus, %d of %d validators must be up to add blocks\n", validators.Size()/2+1, validators.Size())
+		} else {
+			fmt.Printf("ℹ️ %d of %d votes are needed to decide\n", validators.Quorum(), validators.Size())
+		}
+	},
+}
+
+func unlockValidatorKey(cfg *core.ValidatorConfig) (*ecdsa.PrivateKey, string, error) {
+	ks, err := readKeystore(cfg.KeyPath())
+	if err != nil {
+		return nil, "", err
+	}
+
+	passphrase, err := readPassphraseFrom(ValidatorPassphraseEnv, fmt.Sprintf("Passphrase for validator %d: ", cfg.ID), false)
+	if err != nil {
+		return nil, "", err
+	}
+
+	privKey, err := core.DecryptKey(ks, passphrase)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to unlock validator %d: %w", cfg.ID, err)
+	}
+	return privKey, ks.PublicKey, nil
+}
+
+func genesisHasValidator(genesis *core.Genesis, id int, pubKey string) bool {
+	for _, val := range genesis.Validators {
+		if val.ID == id {
+			return val.PublicKey == pubKey
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(validatorCmd)
+	validatorCmd.AddCommand(validatorInitCmd, validatorStartCmd, validatorGenesisCmd)
+
+	validatorInitCmd.Flags().IntVar(&validatorID, "id", 0, "Validator ID")
+	validatorInitCmd.Flags().StringVar(&validatorDir, "dir", "", "Validator directory (defaults to ./validator/validator_<id>)")
+	validatorInitCmd.Flags().StringSliceVar(&validatorListen, "listen", nil, "libp2p listen multiaddr (TCP or QUIC), can be repeated")
+	validatorInitCmd.Flags().StringSliceVar(&validatorAnnounce, "announce", nil, "Multiaddr to advertise to peers instead of the listen ones, can be repeated")
+	validatorInitCmd.Flags().StringSliceVar(&validatorPeers, "peer", nil, "Static peer multiaddr to keep connected, can be repeated")
+	validatorInitCmd.Flags().StringVar(&validatorGenesis, "genesis", "", "Genesis file, relative to the validator directory")
+	validatorInitCmd.MarkFlagRequired("id")
+
+	validatorStartCmd.Flags().StringVarP(&validatorConfigPath, "config", "c", "", "Path to the validator config file")
+	validatorStartCmd.MarkFlagRequired("config")
+	validatorStartCmd.Flags().StringSliceVar(&validatorListen, "listen", nil, "libp2p listen multiaddr, replaces the configured ones, can be repeated")
+	validatorStartCmd.Flags().StringSliceVar(&validatorAnnounce, "announce", nil, "Multiaddr to advertise to peers, replaces the configured ones, can be repeated")
+
+	validatorGenesisCmd.Flags().StringVarP(&genesisOut, "out", "o", "genesis.json", "Genesis file to write")
+	validatorGenesisCmd.Flags().IntVar(&genesisQuorum, "quorum", 0, "Votes needed to decide (defaults to 2f+1 of 3f+1)")
+	validatorGenesisCmd.Flags().StringVar(&genesisConsensus, "consensus", core.ConsensusVote,
+		fmt.Sprintf("Consensus of the network: %s, %s or %s (crash fault tolerant only, for trusted validators)", core.ConsensusVote, core.ConsensusBFT, core.ConsensusRaft))
+}
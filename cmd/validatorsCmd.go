@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Saumya40-codes/DeSecure/core"
+	storage "github.com/Saumya40-codes/DeSecure/pkg"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var validatorsCmd = &cobra.Command{
+	Use:   "validators",
+	Short: "Show the validator set with each validator's status and penalties",
+	Long: `Lists the validators of the network as seen by the local chain. Validators that signed
+conflicting votes are jailed, out of the active set for a number of blocks, and validators that
+signed two different blocks in one BFT step are tombstoned for good.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		db := storage.OpenDB("./data")
+		defer db.CloseDB()
+
+		bc := core.NewBlockchain(db)
+		if !initLocalGenesis(bc, genesisPath) {
+			return
+		}
+
+		statuses, err := bc.ValidatorStatuses()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		titleColor := color.New(color.FgCyan, color.Bold)
+		statusColors := map[string]*color.Color{
+			core.ValidatorActive:     color.New(color.FgGreen),
+			core.ValidatorJailed:     color.New(color.FgYellow),
+			core.ValidatorTombstoned: color.New(color.FgRed),
+		}
+
+		validators := bc.Validators()
+		titleColor.Printf("🛡️ %d validator(s), %d active, %d votes needed, current height %d\n\n",
+			len(statuses), validators.Size(), validators.Quorum(), len(bc.Blocks))
+		for _, v := range statuses {
+			fmt.Printf("Validator %d (%s): ", v.ID, shortenKey(v.PublicKey))
+			statusColors[v.Status].Print(v.Status)
+			if v.Status == core.ValidatorJailed {
+				fmt.Printf(" until block %d", v.Until)
+			}
+			fmt.Println()
+
+			for _, record := range v.History {
+				fmt.Printf("   %s at block %d for %s, evidence %s\n", record.Penalty, record.Height, describeOffense(record.Kind), record.TxID)
+			}
+		}
+	},
+}
+
+var validatorsReportCmd = &cobra.Command{
+	Use:   "report <evidence file>",
+	Short: "Submit evidence that a validator signed conflicting messages",
+	Long: `Submits a JSON evidence file holding two conflicting messages signed by one validator, either
+{"Vote": {"ValidatorID", "First", "Second"}} with an approval and a rejection of one transaction, or
+{"BFT": {"ValidatorID", "First", "Second"}} with two BFT messages of one step for different blocks.
+Anyone can report evidence, validators also report what they see themselves.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			fmt.Println("❌ Error reading evidence:", err)
+			return
+		}
+		var evidence core.Evidence
+		if err := json.Unmarshal(data, &evidence); err != nil {
+			fmt.Println("❌ Invalid evidence file:", err)
+			return
+		}
+		if (evidence.Vote == nil) == (evidence.BFT == nil) {
+			fmt.Println("❌ Evidence must hold either conflicting votes or conflicting BFT messages")
+			return
+		}
+
+		signer, err := loadSigner()
+		if err != nil {
+			fmt.Println("❌", err)
+			return
+		}
+
+		db := storage.OpenDB("./data")
+		defer db.CloseDB()
+
+		bc := core.NewBlockchain(db)
+		if !initLocalGenesis(bc, genesisPath) {
+			return
+		}
+
+		node, err := newClientNode(context.Background())
+		if err != nil {
+			fmt.Println("Error creating P2P node:", err)
+			return
+		}
+
+		evidenceTx := buildEvidenceTransaction(bc, signer.pubKey, evidence)
+		evidenceTx.Signature, err = signer.Sign(&evidenceTx)
+		if err != nil {
+			fmt.Println("❌ Error signing transaction:", err)
+			return
+		}
+
+		if !submitTransaction(node, bc, evidenceTx) {
+			return
+		}
+		fmt.Printf("✅ Evidence against validator %d submitted! TxID: %s\n", evidence.Offender(), evidenceTx.TxID)
+	},
+}
+
+func describeOffense(kind string) string {
+	switch kind {
+	case core.EvidenceVote:
+		return "conflicting votes"
+	case core.EvidenceBFT:
+		return "double signing blocks"
+	}
+	return kind
+}
+
+func init() {
+	rootCmd.AddCommand(validatorsCmd)
+	validatorsCmd.AddCommand(validatorsReportCmd)
+}
@@ -0,0 +1,309 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Operations understood by the signing agent
+const (
+	AgentOpList = "list"
+	AgentOpSign = "sign"
+	AgentOpAdd  = "add"
+	AgentOpLock = "lock"
+)
+
+// Request sent to the agent, one JSON object per line
+type AgentRequest struct {
+	Op          string              `json:"op"`
+	Name        string              `json:"name,omitempty"`        // Identity name, for add
+	PublicKey   string              `json:"public_key,omitempty"`  // Key to sign with
+	PrivateKey  string              `json:"private_key,omitempty"` // PKCS#8 PEM, for add
+	Transaction *LicenseTransaction `json:"transaction,omitempty"`
+}
+
+// Response to an AgentRequest, Error is set if it failed
+type AgentResponse struct {
+	Error     string     `json:"error,omitempty"`
+	Keys      []AgentKey `json:"keys,omitempty"`
+	Signature string     `json:"signature,omitempty"`
+}
+
+// Key held by the agent
+type AgentKey struct {
+	Name      string `json:"name"`
+	PublicKey string `json:"public_key"`
+}
+
+// Shortest interval between checks for an idle agent
+const minIdleCheck = 10 * time.Millisecond
+
+type agentKey struct {
+	name    string
+	privKey *ecdsa.PrivateKey
+}
+
+// Agent keeps unlocked keys in memory and signs transactions for local clients
+type Agent struct {
+	// Asked before every signature when set, the request is refused if it returns false
+	Confirm func(name string, tx LicenseTransaction) bool
+	// Keys are dropped after this long without a request, 0 keeps them until the agent exits
+	IdleTimeout time.Duration
+
+	mu       sync.Mutex
+	keys     map[string]agentKey // public key -> key
+	lastUsed time.Time
+}
+
+func NewAgent() *Agent {
+	return &Agent{keys: make(map[string]agentKey), lastUsed: time.Now()}
+}
+
+// Add an unlocked key under an identity name
+func (a *Agent) AddKey(name string, privKey *ecdsa.PrivateKey) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.keys[PublicKeyHex(&privKey.PublicKey)] = agentKey{name: name, privKey: privKey}
+	a.lastUsed = time.Now()
+}
+
+// Drop all keys from memory
+func (a *Agent) Lock() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.keys = make(map[string]agentKey)
+}
+
+// Keys held by the agent, sorted by name
+func (a *Agent) Keys() []AgentKey {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	keys := make([]AgentKey, 0, len(a.keys))
+	for pubKey, key := range a.keys {
+		keys = append(keys, AgentKey{Name: key.name, PublicKey: pubKey})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys
+}
+
+// Listen on a Unix socket only the current user can connect to
+func ListenAgent(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	// A socket left behind by an agent that didn't shut down cleanly
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("an agent is already listening on %s", path)
+		}
+		os.Remove(path)
+	}
+
+	// Bound in a directory only the current user can enter and moved into place once restricted,
+	// so nobody else can connect before the chmod
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".agent-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	bound := filepath.Join(dir, filepath.Base(path))
+	listener, err := net.Listen("unix", bound)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	// The socket is removed by whoever started the agent, it's no longer at the bound path
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+
+	if err := os.Chmod(bound, 0o600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	if err := os.Rename(bound, path); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to move socket to %s: %w", path, err)
+	}
+	return listener, nil
+}
+
+// Serve clients until the listener is closed
+func (a *Agent) Serve(listener net.Listener) error {
+	if a.IdleTimeout > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go a.lockWhenIdle(done)
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go a.handleConn(conn)
+	}
+}
+
+func (a *Agent) lockWhenIdle(done chan struct{}) {
+	// Tiny timeouts would make a zero interval, which the ticker doesn't accept
+	ticker := time.NewTicker(max(a.IdleTimeout/4, minIdleCheck))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			a.mu.Lock()
+			idle := len(a.keys) > 0 && time.Since(a.lastUsed) >= a.IdleTimeout
+			a.mu.Unlock()
+			if idle {
+				log.Println("Agent idle, dropping keys")
+				a.Lock()
+			}
+		}
+	}
+}
+
+func (a *Agent) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		var req AgentRequest
+		if err := decoder.Decode(&req); err != nil {
+			return
+		}
+		if err := encoder.Encode(a.handle(req)); err != nil {
+			return
+		}
+	}
+}
+
+func (a *Agent) handle(req AgentRequest) AgentResponse {
+	a.mu.Lock()
+	a.lastUsed = time.Now()
+	a.mu.Unlock()
+
+	switch req.Op {
+	case AgentOpList:
+		return AgentResponse{Keys: a.Keys()}
+	case AgentOpAdd:
+		privKey, err := ParsePrivateKeyPEM([]byte(req.PrivateKey), nil)
+		if err != nil {
+			return AgentResponse{Error: err.Error()}
+		}
+		a.AddKey(req.Name, privKey)
+		return AgentResponse{}
+	case AgentOpLock:
+		a.Lock()
+		return AgentResponse{}
+	case AgentOpSign:
+		signature, err := a.sign(req.PublicKey, req.Transaction)
+		if err != nil {
+			return AgentResponse{Error: err.Error()}
+		}
+		return AgentResponse{Signature: signature}
+	default:
+		return AgentResponse{Error: fmt.Sprintf("unknown operation %q", req.Op)}
+	}
+}
+
+func (a *Agent) sign(pubKey string, tx *LicenseTransaction) (string, error) {
+	if tx == nil {
+		return "", errors.New("no transaction to sign")
+	}
+	// The ID is derived from every signed field, a mismatch means the request was tampered with
+	if tx.TxID != GenerateTransactionID(*tx) {
+		return "", errors.New("transaction ID doesn't match its contents")
+	}
+
+	a.mu.Lock()
+	key, ok := a.keys[pubKey]
+	a.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("agent holds no key %s", pubKey)
+	}
+
+	if a.Confirm != nil && !a.Confirm(key.name, *tx) {
+		return "", errors.New("signature refused by the agent")
+	}
+	return SignTransaction(key.privKey, tx), nil
+}
+
+// Client for a running agent
+type AgentClient struct {
+	Path string
+}
+
+func (c AgentClient) call(req AgentRequest) (*AgentResponse, error) {
+	conn, err := net.Dial("unix", c.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach agent at %s: %w", c.Path, err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send agent request: %w", err)
+	}
+
+	var resp AgentResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read agent response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("agent: %s", resp.Error)
+	}
+	return &resp, nil
+}
+
+// Keys held by the agent
+func (c AgentClient) Keys() ([]AgentKey, error) {
+	resp, err := c.call(AgentRequest{Op: AgentOpList})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Keys, nil
+}
+
+// Hand an unlocked key to the agent
+func (c AgentClient) Add(name string, privKey *ecdsa.PrivateKey) error {
+	pemData, err := MarshalPKCS8PEM(privKey, nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.call(AgentRequest{Op: AgentOpAdd, Name: name, PrivateKey: string(pemData)})
+	return err
+}
+
+// Ask the agent to drop all its keys
+func (c AgentClient) Lock() error {
+	_, err := c.call(AgentRequest{Op: AgentOpLock})
+	return err
+}
+
+// Sign a transaction with one of the agent's keys
+func (c AgentClient) Sign(pubKey string, tx LicenseTransaction) (string, error) {
+	resp, err := c.call(AgentRequest{Op: AgentOpSign, PublicKey: pubKey, Transaction: &tx})
+	if err != nil {
+		return "", err
+	}
+	return resp.Signature, nil
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startTestAgent(t *testing.T, agent *Agent) AgentClient {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := ListenAgent(path)
+	if err != nil {
+		t.Fatalf("Failed to start agent: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go agent.Serve(listener)
+
+	return AgentClient{Path: path}
+}
+
+func TestAgentSignsTransactions(t *testing.T) {
+	privKey, pubKey := GenerateKeyPair()
+	agent := NewAgent()
+	client := startTestAgent(t, agent)
+
+	if info, err := os.Stat(client.Path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("Agent socket isn't private: %v (%v)", info, err)
+	}
+	if err := client.Add("alice", privKey); err != nil {
+		t.Fatalf("Failed to add key: %v", err)
+	}
+	keys, err := client.Keys()
+	if err != nil || len(keys) != 1 || keys[0].Name != "alice" || keys[0].PublicKey != pubKey {
+		t.Fatalf("Unexpected agent keys %v (%v)", keys, err)
+	}
+
+	tx := LicenseTransaction{Owner: pubKey, AssetHash: "asset-agent", License: "view", TxType: TxTypeUpload, Timestamp: time.Now().Unix()}
+	tx.TxID = GenerateTransactionID(tx)
+	if tx.Signature, err = client.Sign(pubKey, tx); err != nil {
+		t.Fatalf("Agent failed to sign: %v", err)
+	}
+	if !VerifyTransaction(tx) {
+		t.Error("Agent signature doesn't verify")
+	}
+
+	// The agent refuses to sign fields that don't match the ID
+	tampered := tx
+	tampered.AssetHash = "asset-other"
+	if _, err := client.Sign(pubKey, tampered); err == nil {
+		t.Error("Agent signed a transaction whose ID doesn't match")
+	}
+
+	if err := client.Lock(); err != nil {
+		t.Fatalf("Failed to lock agent: %v", err)
+	}
+	if _, err := client.Sign(pubKey, tx); err == nil {
+		t.Error("Locked agent still signs")
+	}
+}
+
+func TestAgentConfirmationAndIdleLock(t *testing.T) {
+	privKey, pubKey := GenerateKeyPair()
+	agent := NewAgent()
+	agent.Confirm = func(name string, tx LicenseTransaction) bool { return tx.AssetHash != "asset-refused" }
+	agent.IdleTimeout = 100 * time.Millisecond
+	agent.AddKey("bob", privKey)
+	// Too short to divide into check intervals, mustn't bring the agent down
+	tiny := NewAgent()
+	tiny.IdleTimeout = time.Nanosecond
+	startTestAgent(t, tiny)
+	client := startTestAgent(t, agent)
+
+	tx := LicenseTransaction{Owner: pubKey, AssetHash: "asset-refused", TxType: TxTypeDelist}
+	tx.TxID = GenerateTransactionID(tx)
+	if _, err := client.Sign(pubKey, tx); err == nil || !strings.Contains(err.Error(), "refused") {
+		t.Errorf("Expected refused signature, got %v", err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if keys, _ := client.Keys(); len(keys) != 0 {
+		t.Error("Idle agent kept its keys")
+	}
+}
@@ -0,0 +1,105 @@
+package core
+
+import "encoding/json"
+
+// Transaction types understood by the validators
+const (
+	TxTypeUpload   = "upload"
+	TxTypePurchase = "purchase"
+	TxTypeDelist   = "delist"
+	TxTypeTakedown = "takedown"
+
+	TxTypeRotateKey   = "rotate-key"
+	TxTypeSetRecovery = "set-recovery"
+
+	TxTypeGovPropose = "gov-propose"
+	TxTypeGovVote    = "gov-vote"
+
+	TxTypeEvidence = "evidence"
+)
+
+// IsAssetTransaction reports whether a transaction type refers to an asset
+func IsAssetTransaction(txType string) bool {
+	switch txType {
+	case TxTypeUpload, TxTypePurchase, TxTypeDelist, TxTypeTakedown:
+		return true
+	}
+	return false
+}
+
+// Asset states derived from the transactions recorded on the chain
+const (
+	AssetActive    = "active"
+	AssetDelisted  = "delisted"
+	AssetTakenDown = "taken_down"
+)
+
+// TakedownMetadata is stored in the Metadata field of a takedown transaction
+type TakedownMetadata struct {
+	Reason string
+}
+
+// NewTakedownMetadata encodes the reason for a takedown
+func NewTakedownMetadata(reason string) string {
+	data, _ := json.Marshal(TakedownMetadata{Reason: reason})
+	return string(data)
+}
+
+// FindUpload returns the upload transaction that registered assetHash
+func FindUpload(assetHash string, bc *Blockchain) *LicenseTransaction {
+	for _, block := range bc.chain() {
+		for i, tx := range block.Transaction {
+			if tx.AssetHash == assetHash && tx.TxType == TxTypeUpload {
+				return &block.Transaction[i]
+			}
+		}
+	}
+	return nil
+}
+
+// AssetStatus returns the current state of an asset, or "" if it was never uploaded
+func AssetStatus(assetHash string, bc *Blockchain) string {
+	if FindUpload(assetHash, bc) == nil {
+		return ""
+	}
+
+	// A takedown is final once the validators approve it, nothing can bring the asset back
+	if bc.TakenDown(assetHash) {
+		return AssetTakenDown
+	}
+
+	status := AssetActive
+	for _, block := range bc.chain() {
+		for _, tx := range block.Transaction {
+			if tx.AssetHash == assetHash && tx.TxType == TxTypeDelist {
+				status = AssetDelisted
+			}
+		}
+	}
+	return status
+}
+
+// NextNonce returns the nonce the signer should use for its next transaction
+func NextNonce(bc *Blockchain, signer string) uint64 {
+	var next uint64
+	for _, block := range bc.chain() {
+		for _, tx := range block.Transaction {
+			if TransactionSigner(tx) == signer && tx.Nonce >= next {
+				next = tx.Nonce + 1
+			}
+		}
+	}
+	return next
+}
+
+// TransactionSigner returns the public key expected to have signed tx
+func TransactionSigner(tx LicenseTransaction) string {
+	switch {
+	case tx.TxType == TxTypePurchase:
+		return tx.Licensee
+	case tx.TxType == TxTypeRotateKey && tx.Licensee != "":
+		// Recovery rotations are signed by the recovery key
+		return tx.Licensee
+	}
+	return tx.Owner
+}
@@ -0,0 +1,719 @@
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// Messages exchanged by the BFT engine
+const (
+	BFTProposal  = "proposal"
+	BFTPrevote   = "prevote"
+	BFTPrecommit = "precommit"
+	BFTCommit    = "commit" // A certified block, sent to validators still deciding its height
+)
+
+// Steps of a round
+const (
+	stepPropose = iota
+	stepPrevote
+	stepPrecommit
+)
+
+// Key of the lock and the own messages a BFT validator must not forget across restarts
+const BFTStateKey = "bft-state"
+
+const (
+	defaultBFTTimeout = time.Second
+	maxFutureMessages = 10000
+)
+
+// BFTMessage is a signed proposal or vote for a block at a height and round
+type BFTMessage struct {
+	Type        string
+	Height      int
+	Round       int
+	ValidatorID int
+	BlockHash   string `json:",omitempty"` // Empty for a vote for no block
+	Block       *Block `json:",omitempty"` // Proposals and commits only
+	ValidRound  int    // Proposals only, round in which the block got a prevote quorum, -1 if none
+	Signature   string
+}
+
+// CommitCertificate proves that a quorum of validators precommitted a block
+type CommitCertificate struct {
+	Height     int
+	Round      int
+	BlockHash  string
+	Precommits []BFTMessage
+}
+
+func bftDigest(msg *BFTMessage) []byte {
+	data := fmt.Sprintf("bft|%s|%d|%d|%d|%s|%d", msg.Type, msg.Height, msg.Round, msg.ValidatorID, msg.BlockHash, msg.ValidRound)
+	hash := sha256.Sum256([]byte(data))
+	return hash[:]
+}
+
+// SignBFTMessage signs a BFT message with the validator's key
+func SignBFTMessage(privKey *ecdsa.PrivateKey, msg *BFTMessage) string {
+	return signDigest(privKey, bftDigest(msg))
+}
+
+// VerifyBFTMessage checks a message against the key the validator set has for its sender
+func VerifyBFTMessage(msg BFTMessage, validators *ValidatorSet) bool {
+	pubKey, ok := validators.Key(msg.ValidatorID)
+	if !ok {
+		return false
+	}
+	return verifySignature(pubKey, msg.Signature, bftDigest(&msg))
+}
+
+// VerifyCommitCertificate checks that a block was precommitted by a quorum of validators
+func VerifyCommitCertificate(block *Block, validators *ValidatorSet) error {
+	cert := block.Certificate
+	if cert == nil {
+		return errors.New("block has no commit certificate")
+	}
+	if cert.BlockHash != block.Hash || cert.Height != block.Index {
+		return errors.New("certificate is for another block")
+	}
+
+	signers := make(map[int]bool)
+	for _, precommit := range cert.Precommits {
+		if precommit.Type != BFTPrecommit || precommit.Height != cert.Height || precommit.Round != cert.Round || precommit.BlockHash != cert.BlockHash {
+			continue
+		}
+		if VerifyBFTMessage(precommit, validators) {
+			signers[precommit.ValidatorID] = true
+		}
+	}
+	if len(signers) < validators.Quorum() {
+		return fmt.Errorf("certificate has %d valid precommits, %d needed", len(signers), validators.Quorum())
+	}
+	return nil
+}
+
+// Proposer of a round, rotating through the set so an offline proposer only stalls one round
+func (s *ValidatorSet) RoundProposer(height, round int) int {
+	return s.members[(height+round)%len(s.members)].ID
+}
+
+// What a validator signed at the height it is deciding, so a restart doesn't make it sign
+// something else in a step it already voted in
+type bftState struct {
+	Height      int
+	LockedRound int
+	LockedBlock *Block       `json:",omitempty"`
+	Sent        []BFTMessage `json:",omitempty"` // One per round and type
+}
+
+type bftTimeout struct {
+	height, round, step int
+}
+
+// BFTEngine agrees on blocks with the other validators in rounds of propose, prevote and
+// precommit, in the style of Tendermint. A validator locks on a block once it precommits it
+// and only prevotes for another block after seeing a newer prevote quorum for it, so two
+// different blocks can't both be committed at one height.
+type BFTEngine struct {
+	ID         int
+	OnCommit   func(*Block)                  // Called after a block was added to the chain
+	OnEvidence func(Evidence)                // Called when a validator signed two different blocks in one step
+	Ready      func(LicenseTransaction) bool // Optional, only transactions it holds ready are proposed and accepted
+
+	bc        *Blockchain
+	mempool   *Mempool
+	privKey   *ecdsa.PrivateKey
+	broadcast func(BFTMessage)
+
+	inbox    chan BFTMessage
+	timeouts chan bftTimeout
+	wake     chan struct{}
+	done     <-chan struct{}
+	answered map[int]time.Time // Height -> when its block was last sent to a validator behind
+
+	// Round state, only used by the Run goroutine
+	validators  *ValidatorSet
+	height      int
+	round       int
+	step        int
+	active      bool // A round was started at this height
+	lockedRound int
+	lockedBlock *Block
+	validRound  int
+	validBlock  *Block
+	proposals   map[int]*BFTMessage
+	prevotes    map[int]map[int]BFTMessage // Round -> ValidatorID -> vote
+	precommits  map[int]map[int]BFTMessage
+	seen        map[int]map[int]bool  // Round -> validators that sent anything
+	triggered   map[string]bool       // One-shot rules already fired this height
+	validity    map[string]bool       // Block hash -> valid
+	sent        map[string]BFTMessage // Type/round -> own signed message
+	future      []BFTMessage
+}
+
+// NewBFTEngine creates an engine, broadcast must deliver messages to the other validators
+func NewBFTEngine(id int, privKey *ecdsa.PrivateKey, bc *Blockchain, mempool *Mempool, broadcast func(BFTMessage)) *BFTEngine {
+	return &BFTEngine{
+		ID:        id,
+		bc:        bc,
+		mempool:   mempool,
+		privKey:   privKey,
+		broadcast: broadcast,
+		inbox:     make(chan BFTMessage, 1024),
+		timeouts:  make(chan bftTimeout, 16),
+		wake:      make(chan struct{}, 1),
+		answered:  make(map[int]time.Time),
+	}
+}
+
+// Receive queues a message from another validator, dropping it if the engine is too far behind.
+// A dropped message only delays the round, the timeouts move the engine on.
+func (e *BFTEngine) Receive(msg BFTMessage) {
+	select {
+	case e.inbox <- msg:
+	default:
+		log.Printf("BFT %d: inbox full, dropped %s from validator %d", e.ID, msg.Type, msg.ValidatorID)
+	}
+}
+
+// Notify tells the engine there are new transactions to put in a block
+func (e *BFTEngine) Notify() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run the engine until ctx is canceled
+func (e *BFTEngine) Run(ctx context.Context) {
+	if e.bc.Validators() == nil {
+		log.Printf("BFT %d: chain has no genesis, not starting", e.ID)
+		return
+	}
+	e.done = ctx.Done()
+	e.newHeight()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-e.inbox:
+			e.handle(msg)
+		case t := <-e.timeouts:
+			e.onTimeout(t)
+		case <-e.wake:
+			if !e.active && len(e.candidates()) > 0 {
+				e.startRound(0)
+			}
+		}
+	}
+}
+
+func (e *BFTEngine) newHeight() {
+	e.height = len(e.bc.chain())
+	e.validators = e.bc.Validators()
+	e.round, e.step, e.active = 0, stepPropose, false
+	e.lockedRound, e.lockedBlock = -1, nil
+	e.validRound, e.validBlock = -1, nil
+	e.proposals = make(map[int]*BFTMessage)
+	e.prevotes = make(map[int]map[int]BFTMessage)
+	e.precommits = make(map[int]map[int]BFTMessage)
+	e.seen = make(map[int]map[int]bool)
+	e.triggered = make(map[string]bool)
+	e.validity = make(map[string]bool)
+	e.sent = make(map[string]BFTMessage)
+	e.loadState()
+
+	// Messages that arrived early for this height
+	future := e.future
+	e.future = nil
+	for _, msg := range future {
+		e.handle(msg)
+	}
+
+	if !e.active && len(e.candidates()) > 0 {
+		e.startRound(0)
+	}
+}
+
+// Mempool transactions that may go in the next block
+func (e *BFTEngine) candidates() []LicenseTransaction {
+	txs := e.mempool.GetTransactions()
+	if e.Ready == nil {
+		return txs
+	}
+	return slices.DeleteFunc(txs, func(tx LicenseTransaction) bool { return !e.Ready(tx) })
+}
+
+func (e *BFTEngine) timeout(round int) time.Duration {
+	base := defaultBFTTimeout
+	if ms := e.validators.Params().TimeoutMs; ms > 0 {
+		base = time.Duration(ms) * time.Millisecond
+	}
+	// Later rounds wait longer, so slow validators eventually catch up
+	return base + time.Duration(round)*base/2
+}
+
+func (e *BFTEngine) scheduleTimeout(step int) {
+	t := bftTimeout{height: e.height, round: e.round, step: step}
+	done := e.done
+	time.AfterFunc(e.timeout(e.round), func() {
+		select {
+		case e.timeouts <- t:
+		case <-done:
+		}
+	})
+}
+
+func (e *BFTEngine) startRound(round int) {
+	e.round, e.step, e.active = round, stepPropose, true
+
+	if e.validators.RoundProposer(e.height, round) == e.ID {
+		block, validRound := e.validBlock, e.validRound
+		if block == nil {
+			block = buildBlock(e.bc, e.candidates(), e.validators.Params())
+		}
+		if block != nil {
+			e.send(BFTMessage{Type: BFTProposal, BlockHash: block.Hash, Block: block, ValidRound: validRound})
+		}
+	}
+	e.scheduleTimeout(stepPropose)
+	e.check(round)
+}
+
+func (e *BFTEngine) validBlockProposal(block *Block) bool {
+	valid, ok := e.validity[block.Hash]
+	if !ok {
+		valid = block.Index == e.height && validateBlock(e.bc, block, e.validators.Params()) == nil
+		e.validity[block.Hash] = valid
+	}
+	if !valid || e.Ready == nil {
+		return valid
+	}
+
+	// Not cached, a transaction may become ready while the height is decided
+	for _, tx := range block.Transaction {
+		if !e.Ready(tx) {
+			return false
+		}
+	}
+	return true
+}
+
+// Sign and broadcast a message, handling it locally as well. What was signed in a step before
+// is sent again instead of anything else, and stored before it leaves.
+func (e *BFTEngine) send(msg BFTMessage) {
+	msg.Height, msg.Round, msg.ValidatorID = e.height, e.round, e.ID
+	if msg.Type != BFTProposal {
+		msg.ValidRound = -1
+	}
+
+	key := fmt.Sprintf("%s/%d", msg.Type, msg.Round)
+	if signed, ok := e.sent[key]; ok {
+		if signed.BlockHash != msg.BlockHash {
+			log.Printf("BFT %d: refusing to sign another %s at height %d round %d, sending the one signed before", e.ID, msg.Type, msg.Height, msg.Round)
+		}
+		msg = signed
+	} else {
+		msg.Signature = SignBFTMessage(e.privKey, &msg)
+		e.sent[key] = msg
+		if err := e.saveState(); err != nil {
+			// Sent after a restart could conflict with what it signs then
+			log.Printf("BFT %d: not sending %s, error saving state: %v", e.ID, msg.Type, err)
+			return
+		}
+	}
+
+	e.broadcast(msg)
+	e.handle(msg)
+}
+
+// Restore the lock and the own messages of the current height from before a restart
+func (e *BFTEngine) loadState() {
+	data, err := e.bc.db.Load(BFTStateKey)
+	if err != nil {
+		return
+	}
+	var state bftState
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Printf("BFT %d: ignoring unreadable state: %v", e.ID, err)
+		return
+	}
+	if state.Height != e.height {
+		return
+	}
+
+	if state.LockedBlock != nil {
+		// Locked on a block with a prevote quorum, which makes it the valid block as well
+		e.lockedRound, e.lockedBlock = state.LockedRound, state.LockedBlock
+		e.validRound, e.validBlock = state.LockedRound, state.LockedBlock
+	}
+	for _, msg := range state.Sent {
+		e.sent[fmt.Sprintf("%s/%d", msg.Type, msg.Round)] = msg
+	}
+	log.Printf("BFT %d: restored %d signed messages and the lock at height %d", e.ID, len(state.Sent), e.height)
+}
+
+func (e *BFTEngine) saveState() error {
+	state := bftState{Height: e.height, LockedRound: e.lockedRound, LockedBlock: e.lockedBlock}
+	for _, msg := range e.sent {
+		state.Sent = append(state.Sent, msg)
+	}
+	data, _ := json.Marshal(state)
+	return e.bc.db.Save(BFTStateKey, data)
+}
+
+func (e *BFTEngine) handle(msg BFTMessage) {
+	if msg.Height < e.height {
+		e.answerBehind(msg)
+		return
+	}
+	if msg.Height > e.height {
+		if len(e.future) < maxFutureMessages {
+			e.future = append(e.future, msg)
+		}
+		// The others moved on without this validator, its votes get the block of this height sent
+		if !e.active {
+			e.startRound(0)
+		}
+		return
+	}
+	if e.validators == nil || !VerifyBFTMessage(msg, e.validators) {
+		log.Printf("BFT %d: dropped %s from validator %d with an invalid signature", e.ID, msg.Type, msg.ValidatorID)
+		return
+	}
+
+	if msg.Type == BFTCommit {
+		e.catchUp(msg.Block)
+		return
+	}
+
+	if !e.active {
+		e.startRound(0)
+	}
+
+	if !e.record(msg) {
+		return
+	}
+
+	// f+1 validators in a later round means at least one honest validator moved on
+	if msg.Round > e.round && len(e.seen[msg.Round]) >= e.validators.Size()-e.validators.Quorum()+1 {
+		e.startRound(msg.Round)
+		return
+	}
+	e.check(msg.Round)
+}
+
+// Store a message, returning false if it is a duplicate or not acceptable
+func (e *BFTEngine) record(msg BFTMessage) bool {
+	switch msg.Type {
+	case BFTProposal:
+		if msg.ValidatorID != e.validators.RoundProposer(msg.Height, msg.Round) {
+			return false
+		}
+		if first := e.proposals[msg.Round]; first != nil {
+			if first.BlockHash != msg.BlockHash {
+				e.evidence(*first, msg)
+			}
+			return false
+		}
+		if msg.Block == nil || msg.Block.Hash != msg.BlockHash {
+			return false
+		}
+		e.proposals[msg.Round] = &msg
+	case BFTPrevote, BFTPrecommit:
+		votes := e.prevotes
+		if msg.Type == BFTPrecommit {
+			votes = e.precommits
+		}
+		if votes[msg.Round] == nil {
+			votes[msg.Round] = make(map[int]BFTMessage)
+		}
+		if first, ok := votes[msg.Round][msg.ValidatorID]; ok {
+			if first.BlockHash != msg.BlockHash {
+				e.evidence(first, msg)
+			}
+			return false
+		}
+		votes[msg.Round][msg.ValidatorID] = msg
+	default:
+		return false
+	}
+
+	if e.seen[msg.Round] == nil {
+		e.seen[msg.Round] = make(map[int]bool)
+	}
+	e.seen[msg.Round][msg.ValidatorID] = true
+	return true
+}
+
+// Keep two signed messages of one step for different blocks as evidence against their sender
+func (e *BFTEngine) evidence(first, second BFTMessage) {
+	log.Printf("EVIDENCE: validator %d sent conflicting %ss at height %d round %d", second.ValidatorID, second.Type, second.Height, second.Round)
+
+	// Blocks aren't covered by the signatures, the hashes are enough
+	first.Block, second.Block = nil, nil
+	evidence := Evidence{BFT: &BFTEvidence{ValidatorID: second.ValidatorID, First: first, Second: second}}
+	e.bc.saveEvidence(evidence)
+	if e.OnEvidence != nil {
+		e.OnEvidence(evidence)
+	}
+}
+
+// Count votes in a round for a block hash, or all votes if any is true
+func countVotes(votes map[int]BFTMessage, hash string, any bool) int {
+	count := 0
+	for _, vote := range votes {
+		if any || vote.BlockHash == hash {
+			count++
+		}
+	}
+	return count
+}
+
+// once reports whether a one-shot rule fires for the first time
+func (e *BFTEngine) once(rule string, round int) bool {
+	key := fmt.Sprintf("%s/%d", rule, round)
+	if e.triggered[key] {
+		return false
+	}
+	e.triggered[key] = true
+	return true
+}
+
+// Apply the consensus rules after state for a round changed
+func (e *BFTEngine) check(round int) {
+	quorum := e.validators.Quorum()
+
+	// A block precommitted by a quorum in any round is decided
+	if proposal := e.proposals[round]; proposal != nil && countVotes(e.precommits[round], proposal.BlockHash, false) >= quorum {
+		e.commit(proposal.Block, round)
+		return
+	}
+
+	if round != e.round {
+		return
+	}
+	proposal := e.proposals[round]
+
+	if e.step == stepPropose && proposal != nil {
+		vr := proposal.ValidRound
+		switch {
+		case vr == -1:
+			if e.validBlockProposal(proposal.Block) && (e.lockedRound == -1 || e.lockedBlock.Hash == proposal.BlockHash) {
+				e.prevote(proposal.BlockHash)
+			} else {
+				e.prevote("")
+			}
+		case vr < round && countVotes(e.prevotes[vr], proposal.BlockHash, false) >= quorum:
+			// The proposer re-proposes a block that had a prevote quorum, which may unlock us
+			if e.validBlockProposal(proposal.Block) && (e.lockedRound <= vr || e.lockedBlock.Hash == proposal.BlockHash) {
+				e.prevote(proposal.BlockHash)
+			} else {
+				e.prevote("")
+			}
+		}
+	}
+
+	if e.step >= stepPrevote && proposal != nil && countVotes(e.prevotes[round], proposal.BlockHash, false) >= quorum &&
+		e.validBlockProposal(proposal.Block) && e.once("polka", round) {
+		if e.step == stepPrevote {
+			e.lockedRound, e.lockedBlock = round, proposal.Block
+			e.precommit(proposal.BlockHash)
+		}
+		e.validRound, e.validBlock = round, proposal.Block
+	}
+
+	if e.step == stepPrevote && countVotes(e.prevotes[round], "", false) >= quorum {
+		e.precommit("")
+	}
+
+	if countVotes(e.precommits[round], "", true) >= quorum && e.once("precommit-timeout", round) {
+		e.scheduleTimeout(stepPrecommit)
+	}
+}
+
+// Both voting steps time out from the own vote rather than from hearing a quorum, so dropped
+// votes can't stall a round
+func (e *BFTEngine) prevote(hash string) {
+	e.step = stepPrevote
+	e.scheduleTimeout(stepPrevote)
+	e.send(BFTMessage{Type: BFTPrevote, BlockHash: hash})
+}
+
+func (e *BFTEngine) precommit(hash string) {
+	e.step = stepPrecommit
+	e.scheduleTimeout(stepPrecommit)
+	e.send(BFTMessage{Type: BFTPrecommit, BlockHash: hash})
+}
+
+func (e *BFTEngine) onTimeout(t bftTimeout) {
+	if t.height != e.height || t.round != e.round {
+		return
+	}
+
+	switch {
+	case t.step == stepPropose && e.step == stepPropose:
+		log.Printf("BFT %d: no valid proposal at height %d round %d", e.ID, e.height, e.round)
+		e.prevote("")
+	case t.step == stepPrevote && e.step == stepPrevote:
+		e.precommit("")
+	case t.step == stepPrecommit:
+		e.startRound(e.round + 1)
+	}
+}
+
+func (e *BFTEngine) commit(block *Block, round int) {
+	cert := &CommitCertificate{Height: e.height, Round: round, BlockHash: block.Hash}
+	for _, precommit := range e.precommits[round] {
+		if precommit.BlockHash == block.Hash {
+			cert.Precommits = append(cert.Precommits, precommit)
+		}
+	}
+
+	committed := *block
+	committed.Certificate = cert
+	e.addBlock(&committed)
+}
+
+// Add a certified block to the chain and move on to the next height
+func (e *BFTEngine) addBlock(block *Block) {
+	if err := e.bc.AddBlock(block); err != nil {
+		log.Printf("BFT %d: failed to add block %d: %v", e.ID, block.Index, err)
+		return
+	}
+	for _, tx := range block.Transaction {
+		e.mempool.RemoveTransaction(tx.TxID)
+	}
+	log.Printf("BFT %d: committed block %d in round %d with %d transaction(s)", e.ID, block.Index, block.Certificate.Round, len(block.Transaction))
+
+	if e.OnCommit != nil {
+		e.OnCommit(block)
+	}
+	e.newHeight()
+}
+
+// Send the certified block of a height to a validator still voting on it, at most once per
+// timeout so all the votes of a round don't each get an answer
+func (e *BFTEngine) answerBehind(msg BFTMessage) {
+	if msg.Type == BFTCommit || time.Since(e.answered[msg.Height]) < e.timeout(0) {
+		return
+	}
+	blocks := e.bc.chain()
+	if msg.Height <= 0 || msg.Height >= len(blocks) || blocks[msg.Height].Certificate == nil {
+		return
+	}
+	if !VerifyBFTMessage(msg, e.validators) {
+		return
+	}
+	e.answered[msg.Height] = time.Now()
+
+	block := blocks[msg.Height]
+	reply := BFTMessage{
+		Type:        BFTCommit,
+		Height:      block.Index,
+		Round:       block.Certificate.Round,
+		ValidatorID: e.ID,
+		BlockHash:   block.Hash,
+		Block:       block,
+		ValidRound:  -1,
+	}
+	reply.Signature = SignBFTMessage(e.privKey, &reply)
+	e.broadcast(reply)
+}
+
+// Take the block of the current height from a validator that is ahead, the certificate shows a
+// quorum committed it so it doesn't need to be decided again
+func (e *BFTEngine) catchUp(block *Block) {
+	if block == nil || block.Index != e.height {
+		return
+	}
+	if err := VerifyCommitCertificate(block, e.validators); err != nil {
+		log.Printf("BFT %d: dropped block %d without a valid certificate: %v", e.ID, block.Index, err)
+		return
+	}
+	if err := validateBlock(e.bc, block, e.validators.Params()); err != nil {
+		log.Printf("BFT %d: dropped certified block %d: %v", e.ID, block.Index, err)
+		return
+	}
+	log.Printf("BFT %d: caught up with block %d from another validator", e.ID, block.Index)
+	e.addBlock(block)
+}
+
+// bftConsensus runs a BFTEngine behind the Consensus interface
+type bftConsensus struct {
+	cfg    ConsensusConfig
+	engine *BFTEngine
+}
+
+func newBFTConsensus(cfg ConsensusConfig) *bftConsensus {
+	engine := NewBFTEngine(cfg.ID, cfg.PrivateKey, cfg.Blockchain, cfg.Mempool, func(msg BFTMessage) {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			log.Printf("BFT %d: error marshaling %s: %v", cfg.ID, msg.Type, err)
+			return
+		}
+		cfg.Broadcast(data)
+	})
+	engine.OnCommit = func(block *Block) {
+		includedReceipts(cfg, block)
+		cfg.OnCommit(block)
+	}
+	engine.OnEvidence = cfg.OnEvidence
+	return &bftConsensus{cfg: cfg, engine: engine}
+}
+
+func (c *bftConsensus) Run(ctx context.Context) {
+	c.engine.Run(ctx)
+}
+
+// Transactions are taken from the mempool by the proposer of each round
+func (c *bftConsensus) Propose(tx LicenseTransaction) {
+	c.engine.Notify()
+}
+
+// Proposers leave invalid transactions out of their blocks, so there is nothing to vote on and
+// no rejection is ever final
+func (c *bftConsensus) Reject(tx LicenseTransaction, reason string) {
+	rejectLocally(c.cfg, tx, reason)
+}
+
+func (c *bftConsensus) Receive(data []byte) {
+	var msg BFTMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Printf("BFT %d: received invalid message: %v", c.engine.ID, err)
+		return
+	}
+	c.engine.Receive(msg)
+}
+
+func (c *bftConsensus) Validate(data []byte) error {
+	var msg BFTMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("invalid BFT message: %w", err)
+	}
+
+	return checkBFTMessage(msg, c.cfg.Blockchain.Validators())
+}
+
+// Check a BFT message was signed by a validator and carries the block it is for
+func checkBFTMessage(msg BFTMessage, validators *ValidatorSet) error {
+	if err := knownValidator(validators, msg.ValidatorID); err != nil {
+		return err
+	}
+	if !VerifyBFTMessage(msg, validators) {
+		return fmt.Errorf("%s not signed by validator %d", msg.Type, msg.ValidatorID)
+	}
+	if msg.Block != nil && msg.Block.Hash != msg.BlockHash {
+		return fmt.Errorf("%s carries a block other than the one it is for", msg.Type)
+	}
+	return checkBlockHash(msg.Block)
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Block 1 of every online validator, which must be the same certified block holding the upload
+func checkCertifiedBlock(t *testing.T, net *consensusNetwork) *Block {
+	t.Helper()
+
+	var first *Block
+	for _, id := range net.online() {
+		block := net.chains[id].chain()[1]
+		if first == nil {
+			first = block
+		}
+		if block.Hash != first.Hash {
+			t.Fatalf("Validators committed different blocks: %s and %s", block.Hash, first.Hash)
+		}
+		if err := VerifyCommitCertificate(block, net.chains[id].Validators()); err != nil {
+			t.Errorf("Validator %d has an invalid certificate: %v", id, err)
+		}
+	}
+	if len(first.Transaction) != 1 {
+		t.Errorf("Block should hold the submitted transaction, has %d", len(first.Transaction))
+	}
+	return first
+}
+
+func TestBFTCommitsBlockWithCertificate(t *testing.T) {
+	net := newConsensusNetwork(t, ConsensusBFT, 4)
+	net.upload(t, "asset-bft")
+
+	block := checkCertifiedBlock(t, net)
+	if block.Certificate.Round != 0 {
+		t.Errorf("Block should be committed in the first round, was round %d", block.Certificate.Round)
+	}
+
+	// A certificate signed by too few validators isn't accepted
+	forged := *block
+	forged.Certificate = &CommitCertificate{Height: 1, BlockHash: forged.Hash, Precommits: forged.Certificate.Precommits[:1]}
+	if VerifyCommitCertificate(&forged, net.chains[0].Validators()) == nil {
+		t.Error("Certificate with a single precommit was accepted")
+	}
+}
+
+func TestBFTChangesProposerWhenOffline(t *testing.T) {
+	net := newConsensusNetwork(t, ConsensusBFT, 4)
+	proposer := net.chains[0].Validators().RoundProposer(1, 0)
+	net.crash(proposer)
+	net.upload(t, "asset-bft")
+
+	block := checkCertifiedBlock(t, net)
+	if block.Certificate.Round == 0 {
+		t.Error("Block can't be committed in the round of the offline proposer")
+	}
+	for _, precommit := range block.Certificate.Precommits {
+		if precommit.ValidatorID == proposer {
+			t.Error("Certificate has a precommit of the offline validator")
+		}
+	}
+}
+
+func TestBFTEquivocatingProposerCommitsOneBlock(t *testing.T) {
+	net := newConsensusNetwork(t, ConsensusBFT, 4)
+	byzantine := net.chains[0].Validators().RoundProposer(1, 0)
+	net.crash(byzantine)
+
+	var honest []int
+	for _, id := range net.online() {
+		honest = append(honest, id)
+	}
+
+	// Two valid blocks for height 1
+	var blocks []*Block
+	for i := range 2 {
+		privKey, pubKey := GenerateKeyPair()
+		tx := sign(net.chains[0], LicenseTransaction{Owner: pubKey, AssetHash: fmt.Sprintf("asset-equivocated-%d", i), License: "view", TxType: TxTypeUpload}, privKey)
+		blocks = append(blocks, CreateBlock(*net.chains[0].tip(), []LicenseTransaction{tx}))
+	}
+
+	// The proposer sends one block to the first honest validator and the other to the rest,
+	// voting for whichever block each of them got
+	for i, id := range honest {
+		block := blocks[min(i, 1)]
+		for _, msg := range []BFTMessage{
+			{Type: BFTProposal, BlockHash: block.Hash, Block: block, ValidRound: -1},
+			{Type: BFTPrevote, BlockHash: block.Hash, ValidRound: -1},
+			{Type: BFTPrecommit, BlockHash: block.Hash, ValidRound: -1},
+		} {
+			msg.Height, msg.ValidatorID = 1, byzantine
+			msg.Signature = SignBFTMessage(net.keys[byzantine], &msg)
+			data, _ := json.Marshal(msg)
+			net.queues[id] <- data
+		}
+	}
+
+	// The validator left out of the quorum catches up with the block the others committed
+	deadline := time.Now().Add(10 * time.Second)
+	for _, id := range honest {
+		for len(net.chains[id].chain()) < 2 {
+			if time.Now().After(deadline) {
+				t.Fatalf("Validator %d never committed a block at height 1", id)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	checkCertifiedBlock(t, net)
+	net.checkReplicated(t, 2)
+}
+
+func TestBFTRestartMidHeightKeepsSignedMessages(t *testing.T) {
+	keys, members := newTestValidators(t, 4)
+	bc := newTestBlockchain(t)
+	if err := bc.InitGenesis(&Genesis{Validators: members}); err != nil {
+		t.Fatal(err)
+	}
+	mempool := NewMempool(bc, MempoolConfig{})
+
+	var blocks []*Block
+	for i := range 2 {
+		privKey, pubKey := GenerateKeyPair()
+		tx := sign(bc, LicenseTransaction{Owner: pubKey, AssetHash: fmt.Sprintf("asset-restarted-%d", i), License: "view", TxType: TxTypeUpload}, privKey)
+		blocks = append(blocks, CreateBlock(*bc.tip(), []LicenseTransaction{tx}))
+	}
+
+	// Validator 2 doesn't propose in round 0, so it only sends votes
+	start := func() (*BFTEngine, *[]BFTMessage) {
+		var sent []BFTMessage
+		engine := NewBFTEngine(2, keys[2], bc, mempool, func(msg BFTMessage) { sent = append(sent, msg) })
+		engine.newHeight()
+		return engine, &sent
+	}
+
+	// Locked on the first block after prevoting and precommitting it
+	engine, sent := start()
+	engine.prevote(blocks[0].Hash)
+	engine.lockedRound, engine.lockedBlock = 0, blocks[0]
+	engine.precommit(blocks[0].Hash)
+	before := *sent
+
+	// Restarted in the same round, it is asked to vote for the other block
+	engine, sent = start()
+	if engine.lockedRound != 0 || engine.lockedBlock == nil || engine.lockedBlock.Hash != blocks[0].Hash {
+		t.Fatalf("Expected the lock on %s to be restored, got round %d", blocks[0].Hash, engine.lockedRound)
+	}
+	engine.prevote(blocks[1].Hash)
+	engine.precommit(blocks[1].Hash)
+
+	if len(*sent) != len(before) {
+		t.Fatalf("Expected %d messages, sent %d", len(before), len(*sent))
+	}
+	for i, msg := range *sent {
+		if msg.BlockHash != before[i].BlockHash || msg.Signature != before[i].Signature {
+			t.Errorf("Restarted validator signed a conflicting %s for %q, had %q", msg.Type, msg.BlockHash, before[i].BlockHash)
+		}
+	}
+
+	// A new height starts from a clean state
+	engine.commit(blocks[0], 0)
+	if engine.height != 2 || engine.lockedBlock != nil || len(engine.sent) != 0 {
+		t.Errorf("Expected no lock at height %d, locked on %v with %d messages", engine.height, engine.lockedBlock, len(engine.sent))
+	}
+}
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// BlockSyncProtocol lets a node that is behind fetch the blocks it missed from a validator, instead
+// of waiting for gossip that only carries the newest block
+const BlockSyncProtocol = "/drm/blocks/1.0.0"
+
+const (
+	maxBlockSyncBatch        = 64       // Most blocks sent in one response
+	maxBlockSyncResponseSize = 32 << 20 // Upper bound on an encoded response
+)
+
+// BlockSyncRequest asks for the blocks from height From on
+type BlockSyncRequest struct {
+	From int
+}
+
+// BlockSyncResponse holds the requested blocks, each signed by the answering validator like a
+// gossiped block update
+type BlockSyncResponse struct {
+	Updates []BlockUpdate `json:",omitempty"`
+}
+
+// Answer a block sync request from the local chain
+func (v *Validator) answerBlockSync(blockchain *Blockchain, req BlockSyncRequest) BlockSyncResponse {
+	var resp BlockSyncResponse
+	blocks := blockchain.chain()
+	if req.From < 1 || req.From >= len(blocks) {
+		return resp
+	}
+
+	for _, block := range blocks[req.From:min(req.From+maxBlockSyncBatch, len(blocks))] {
+		update := BlockUpdate{Block: block, ValidatorID: v.ID}
+		update.Signature = SignBlockUpdate(v.PrivateKey, &update)
+		resp.Updates = append(resp.Updates, update)
+	}
+	return resp
+}
+
+func (v *Validator) handleBlockSyncStream(blockchain *Blockchain, s network.Stream) {
+	defer s.Close()
+
+	s.SetDeadline(time.Now().Add(syncTimeout))
+
+	var req BlockSyncRequest
+	if err := json.NewDecoder(io.LimitReader(s, maxSyncRequestSize)).Decode(&req); err != nil {
+		log.Printf("Validator %d received malformed block sync request from %s: %v", v.ID, s.Conn().RemotePeer(), err)
+		s.Reset()
+		return
+	}
+
+	if err := json.NewEncoder(s).Encode(v.answerBlockSync(blockchain, req)); err != nil {
+		log.Printf("Validator %d error answering block sync from %s: %v", v.ID, s.Conn().RemotePeer(), err)
+	}
+}
+
+// Send a block sync request to a peer and wait for the answer
+func (n *Node) requestBlocks(ctx context.Context, p peer.ID, req BlockSyncRequest) (*BlockSyncResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+
+	s, err := n.Host.NewStream(ctx, p, BlockSyncProtocol)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	s.SetDeadline(time.Now().Add(syncTimeout))
+
+	if err := json.NewEncoder(s).Encode(req); err != nil {
+		s.Reset()
+		return nil, fmt.Errorf("failed to send block sync request: %w", err)
+	}
+	s.CloseWrite()
+
+	var resp BlockSyncResponse
+	if err := json.NewDecoder(bufio.NewReader(io.LimitReader(s, maxBlockSyncResponseSize))).Decode(&resp); err != nil {
+		s.Reset()
+		return nil, fmt.Errorf("failed to read block sync response: %w", err)
+	}
+	return &resp, nil
+}
+
+// Add the blocks after the local tip, checking each like a gossiped block update. Returns how
+// many were added before the updates ran out or one didn't check out.
+func syncBlocks(blockchain *Blockchain, fetch func(from int) ([]BlockUpdate, error)) (int, error) {
+	added := 0
+	for {
+		updates, err := fetch(blockchain.tip().Index + 1)
+		if err != nil {
+			return added, err
+		}
+
+		before := added
+		for _, update := range updates {
+			if err := verifyBlockUpdate(blockchain, update); err != nil {
+				if errors.Is(err, ErrStaleMessage) && update.Block.Index <= blockchain.tip().Index {
+					// Gossip got it in first
+					continue
+				}
+				return added, err
+			}
+			if err := addNetworkBlock(blockchain, update.Block); err != nil {
+				return added, err
+			}
+			added++
+		}
+		if added == before {
+			// Nothing new, the peer is no further ahead
+			return added, nil
+		}
+	}
+}
+
+// Fetch the blocks the local chain is missing from a peer
+func (n *Node) syncBlocksFrom(ctx context.Context, blockchain *Blockchain, p peer.ID) {
+	if blockchain.Genesis() == nil {
+		// Nothing to check the blocks against
+		return
+	}
+
+	added, err := syncBlocks(blockchain, func(from int) ([]BlockUpdate, error) {
+		resp, err := n.requestBlocks(ctx, p, BlockSyncRequest{From: from})
+		if err != nil {
+			// Not a validator, or gone already
+			return nil, nil
+		}
+		return resp.Updates, nil
+	})
+	if added > 0 {
+		log.Printf("Fetched %d blocks from %s, the chain is at height %d", added, p, blockchain.tip().Index)
+	}
+	if err != nil {
+		log.Printf("Stopped syncing blocks with %s: %v", p, err)
+	}
+}
+
+// Sync the chain with every peer now, when a peer connects and whenever the returned function is
+// called, as when a gossiped block is ahead of the chain
+func (n *Node) startBlockSync(blockchain *Blockchain) func() {
+	behind := make(chan struct{}, 1)
+	sync := func() {
+		select {
+		case behind <- struct{}{}:
+		default:
+			// A sync is queued already
+		}
+	}
+
+	n.Host.Network().Notify(&network.NotifyBundle{
+		ConnectedF: func(_ network.Network, conn network.Conn) {
+			go n.syncBlocksFrom(n.ctx, blockchain, conn.RemotePeer())
+		},
+	})
+
+	go func() {
+		sync()
+		for {
+			select {
+			case <-n.ctx.Done():
+				return
+			case <-behind:
+				// Peers that aren't validators don't answer, a failing validator leaves the rest to the others
+				for _, p := range n.Host.Network().Peers() {
+					n.syncBlocksFrom(n.ctx, blockchain, p)
+				}
+			}
+		}
+	}()
+	return sync
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBlockSync(t *testing.T) {
+	net := newConsensusNetwork(t, ConsensusBFT, 4)
+	for i := range 3 {
+		net.upload(t, fmt.Sprintf("asset-synced-block-%d", i))
+	}
+	height := len(net.chains[0].chain())
+
+	validator := NewValidator(1, nil, PublicKeyHex(&net.keys[1].PublicKey), net.keys[1], net.mempools[1])
+	behind := newTestBlockchain(t)
+	if err := behind.InitGenesis(net.chains[0].Genesis()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A few blocks per answer, so the node has to ask again from its new tip
+	requests := 0
+	added, err := syncBlocks(behind, func(from int) ([]BlockUpdate, error) {
+		requests++
+		updates := validator.answerBlockSync(net.chains[1], BlockSyncRequest{From: from}).Updates
+		return updates[:min(len(updates), 2)], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != height-1 || len(behind.chain()) != height || behind.tip().Hash != net.chains[0].tip().Hash {
+		t.Fatalf("Expected %d blocks synced up to %s, added %d", height-1, net.chains[0].tip().Hash, added)
+	}
+	if requests < 2 {
+		t.Errorf("Expected the blocks to be fetched in batches, got %d requests", requests)
+	}
+	for _, block := range net.chains[0].chain()[1:] {
+		for _, tx := range block.Transaction {
+			if receipt := behind.Receipt(tx.TxID); receipt == nil || receipt.Status != TxStatusIncluded {
+				t.Errorf("Expected an included receipt for %s, got %+v", tx.TxID, receipt)
+			}
+		}
+	}
+
+	// Blocks the validators didn't certify are refused, however they are signed
+	forged := newTestBlockchain(t)
+	if err := forged.InitGenesis(net.chains[0].Genesis()); err != nil {
+		t.Fatal(err)
+	}
+	added, err = syncBlocks(forged, func(from int) ([]BlockUpdate, error) {
+		updates := validator.answerBlockSync(net.chains[1], BlockSyncRequest{From: from}).Updates
+		if len(updates) == 0 {
+			return nil, nil
+		}
+		block := *updates[0].Block
+		block.Certificate = nil
+		update := BlockUpdate{Block: &block, ValidatorID: 1}
+		update.Signature = SignBlockUpdate(net.keys[1], &update)
+		return []BlockUpdate{update}, nil
+	})
+	if err == nil || added != 0 || len(forged.chain()) != 1 {
+		t.Errorf("Expected the uncertified block to be refused, added %d: %v", added, err)
+	}
+}
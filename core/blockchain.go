@@ -1,12 +1,14 @@
 package core
 
 import (
-	"context"
+	"crypto/ecdsa"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,31 +27,44 @@ type Block struct {
 	Transaction []LicenseTransaction
 	PrevHash    string
 	Hash        string
+	Certificate *CommitCertificate `json:",omitempty"` // Precommits that finalized the block, not part of the hash
 }
 
 type Blockchain struct {
-	Blocks    []*Block
-	VoteCount map[string]map[int]bool
-	mu        sync.Mutex
-	db        *storage.DB
+	Blocks []*Block
+	Votes  map[string]map[int]VoteMessage // TxID -> ValidatorID -> first vote seen
+	mu     sync.Mutex                     // Guards Blocks, Votes, txIndex and gov
+	db     *storage.DB
+
+	txIndex map[string]int // TxID -> height of the including block
+
+	genesis     *Genesis
+	gov         *governance // Governance as of the next block, updated by appendBlock
+	rejectionMu sync.Mutex  // Serializes updates of stored rejection reasons
+
+	// Validator set for the next block, replaying governance for every vote is too slow
+	validators       *ValidatorSet
+	validatorsHeight int
 }
 
 func NewBlockchain(db *storage.DB) *Blockchain {
 	bc := &Blockchain{
-		Blocks:    []*Block{},
-		VoteCount: make(map[string]map[int]bool),
-		db:        db,
+		Blocks:  []*Block{},
+		Votes:   make(map[string]map[int]VoteMessage),
+		db:      db,
+		txIndex: make(map[string]int),
 	}
 
 	_, err := db.Load(LatestBlockKey)
 	if err != nil {
+		// Only persisted, loadFromDB reads it back like any other chain
 		genesis := CreateGenesisBlock()
-		bc.Blocks = append(bc.Blocks, genesis)
 		log.Println("Genesis block created with", genesis.Hash)
 		bc.persistBlock(genesis)
 	}
 
 	bc.loadFromDB()
+	bc.loadGenesis()
 
 	return bc
 }
@@ -87,9 +102,44 @@ func (bc *Blockchain) loadFromDB() {
 		}
 	}
 
+	for height, block := range bc.Blocks {
+		bc.indexBlock(height, block)
+	}
 	log.Printf("Loaded %d blocks from database", len(bc.Blocks))
 }
 
+// Blocks of the chain as of now. Blocks are only ever appended, so the snapshot can be read without
+// the lock while others are added.
+func (bc *Blockchain) chain() []*Block {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	return bc.Blocks[:len(bc.Blocks):len(bc.Blocks)]
+}
+
+// Last block of the chain
+func (bc *Blockchain) tip() *Block {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	return bc.Blocks[len(bc.Blocks)-1]
+}
+
+// Append a block to the chain, bc.mu must be held
+func (bc *Blockchain) appendBlock(block *Block) {
+	bc.Blocks = append(bc.Blocks, block)
+	bc.indexBlock(len(bc.Blocks)-1, block)
+	if bc.gov != nil {
+		bc.gov.addBlock(len(bc.Blocks)-1, block)
+	}
+}
+
+func (bc *Blockchain) indexBlock(height int, block *Block) {
+	for _, tx := range block.Transaction {
+		bc.txIndex[tx.TxID] = height
+	}
+}
+
 // Persist block to database
 func (bc *Blockchain) persistBlock(block *Block) {
 	blockData, err := json.Marshal(block)
@@ -125,15 +175,53 @@ func (bc *Blockchain) AddTransaction(tx LicenseTransaction) {
 	prevBlock := bc.Blocks[len(bc.Blocks)-1]
 	newBlock := CreateBlock(*prevBlock, []LicenseTransaction{tx})
 
-	bc.Blocks = append(bc.Blocks, newBlock)
+	bc.appendBlock(newBlock)
 	bc.persistBlock(newBlock) // Persist new block
 
-	// Clear vote count for this transaction
-	delete(bc.VoteCount, tx.TxID)
+	// Clear votes for this transaction
+	delete(bc.Votes, tx.TxID)
 
 	log.Println("Block added with consensus:", newBlock.Hash)
 }
 
+// AddBlock appends a block agreed on by the validators, applying its transactions
+func (bc *Blockchain) AddBlock(block *Block) error {
+	if err := bc.appendAgreed(block); err != nil {
+		return err
+	}
+
+	// Outside the chain lock, checks take the license registry lock before reading the chain
+	for _, tx := range block.Transaction {
+		if tx.TxType == TxTypeUpload {
+			recordUpload(tx, bc)
+		}
+	}
+
+	log.Println("Block added with consensus:", block.Hash)
+	return nil
+}
+
+func (bc *Blockchain) appendAgreed(block *Block) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	prevBlock := bc.Blocks[len(bc.Blocks)-1]
+	if block.Index != prevBlock.Index+1 || block.PrevHash != prevBlock.Hash {
+		return fmt.Errorf("block %d doesn't extend the chain at height %d", block.Index, prevBlock.Index)
+	}
+	if calculateHash(*block) != block.Hash {
+		return fmt.Errorf("block %d has an invalid hash", block.Index)
+	}
+
+	bc.appendBlock(block)
+	bc.persistBlock(block)
+
+	for _, tx := range block.Transaction {
+		delete(bc.Votes, tx.TxID)
+	}
+	return nil
+}
+
 func calculateHash(block Block) string {
 	txData, _ := json.Marshal(block.Transaction)
 	record := fmt.Sprintf("%d%s%s%s", block.Index, block.Timestamp, txData, block.PrevHash)
@@ -141,10 +229,21 @@ func calculateHash(block Block) string {
 	return hex.EncodeToString(hash[:])
 }
 
+// Layout of block timestamps, what time.Time.String writes
+const blockTimeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// BlockTime parses the timestamp the proposer put on a block
+func BlockTime(block *Block) (time.Time, error) {
+	// Drop the monotonic clock reading String appends
+	timestamp, _, _ := strings.Cut(block.Timestamp, " m=")
+	return time.Parse(blockTimeLayout, timestamp)
+}
+
 func CreateGenesisBlock() *Block {
+	// Every node must start from the same block, so it can't depend on the local clock
 	genesisBlock := &Block{
 		Index:       0,
-		Timestamp:   time.Now().String(),
+		Timestamp:   time.Unix(0, 0).UTC().String(),
 		Transaction: []LicenseTransaction{},
 		PrevHash:    "",
 	}
@@ -155,9 +254,14 @@ func CreateGenesisBlock() *Block {
 }
 
 func CreateBlock(prevBlock Block, transactions []LicenseTransaction) *Block {
+	return createBlockAt(prevBlock, transactions, time.Now())
+}
+
+// Create a block timestamped at, the time its transactions were checked at
+func createBlockAt(prevBlock Block, transactions []LicenseTransaction, at time.Time) *Block {
 	newBlock := &Block{
 		Index:       prevBlock.Index + 1,
-		Timestamp:   time.Now().String(),
+		Timestamp:   at.String(),
 		Transaction: transactions,
 		PrevHash:    prevBlock.Hash,
 	}
@@ -167,50 +271,200 @@ func CreateBlock(prevBlock Block, transactions []LicenseTransaction) *Block {
 	return newBlock
 }
 
+// BlockUpdate is a block a validator added to its chain, signed by the validator
+type BlockUpdate struct {
+	Block       *Block
+	ValidatorID int
+	Signature   string
+}
+
+func blockUpdateDigest(update *BlockUpdate) []byte {
+	data := fmt.Sprintf("block-update|%d|%d|%s", update.ValidatorID, update.Block.Index, update.Block.Hash)
+	hash := sha256.Sum256([]byte(data))
+	return hash[:]
+}
+
+// SignBlockUpdate signs a block update with the validator's key
+func SignBlockUpdate(privKey *ecdsa.PrivateKey, update *BlockUpdate) string {
+	return signDigest(privKey, blockUpdateDigest(update))
+}
+
+// VerifyBlockUpdate checks that a block update is signed by the key the validator set has for its sender
+func VerifyBlockUpdate(update BlockUpdate, validators *ValidatorSet) bool {
+	pubKey, ok := validators.Key(update.ValidatorID)
+	if !ok {
+		return false
+	}
+	return verifySignature(pubKey, update.Signature, blockUpdateDigest(&update))
+}
+
+// Blocks further ahead than the next one can't be checked against the local chain, they are
+// fetched from the validators instead
+var errBlockAhead = fmt.Errorf("%w: block is ahead of the chain", ErrStaleMessage)
+
+// Reject block updates that weren't agreed on by the validators or don't extend the local chain,
+// and skip known ones
+func checkBlockUpdate(blockchain *Blockchain, env *Envelope) error {
+	var update BlockUpdate
+	if err := env.Decode(&update); err != nil {
+		return fmt.Errorf("invalid block update: %w", err)
+	}
+	return verifyBlockUpdate(blockchain, update)
+}
+
+// Check a block update extends the local chain with a block the validators agreed on
+func verifyBlockUpdate(blockchain *Blockchain, update BlockUpdate) error {
+	block := update.Block
+	if block == nil {
+		return errors.New("block update has no block")
+	}
+	if calculateHash(*block) != block.Hash {
+		return fmt.Errorf("block %d has an invalid hash", block.Index)
+	}
+	for _, tx := range block.Transaction {
+		if !ValidateTransaction(tx) {
+			return fmt.Errorf("block %d has invalid transaction %s", block.Index, tx.TxID)
+		}
+	}
+
+	// Only the next block can be checked against the local chain
+	tip := blockchain.tip()
+	if block.Index <= tip.Index {
+		return fmt.Errorf("%w: already have block %d", ErrStaleMessage, block.Index)
+	}
+	if block.Index > tip.Index+1 {
+		return fmt.Errorf("%w: block %d, the chain is at height %d", errBlockAhead, block.Index, tip.Index)
+	}
+	if block.PrevHash != tip.Hash {
+		return fmt.Errorf("block %d doesn't extend the chain at height %d", block.Index, tip.Index)
+	}
+
+	validators := blockchain.Validators()
+	if validators == nil {
+		return fmt.Errorf("%w: chain has no genesis to check block %d against", ErrStaleMessage, block.Index)
+	}
+	if !VerifyBlockUpdate(update, validators) {
+		return fmt.Errorf("block %d isn't signed by a validator", block.Index)
+	}
+	// Raft trusts its validators not to lie, the other consensus implementations certify every block
+	if validators.Params().Consensus == ConsensusRaft {
+		return nil
+	}
+	if err := VerifyCommitCertificate(block, validators); err != nil {
+		return fmt.Errorf("block %d: %w", block.Index, err)
+	}
+	return nil
+}
+
+// Append a block the validators agreed on and record receipts for its transactions
+func addNetworkBlock(blockchain *Blockchain, block *Block) error {
+	if err := blockchain.AddBlock(block); err != nil {
+		return err
+	}
+	for _, tx := range block.Transaction {
+		blockchain.SaveReceipt(Receipt{
+			TxID:       tx.TxID,
+			Status:     TxStatusIncluded,
+			BlockIndex: block.Index,
+			BlockHash:  block.Hash,
+			Timestamp:  time.Now().Unix(),
+		})
+	}
+	return nil
+}
+
+// ListenForTransactions keeps the local chain and receipts up to date with what validators gossip
 func ListenForTransactions(node *Node, blockchain *Blockchain, db *storage.DB) {
-	ctx := context.Background()
+	// Blocks missed while offline or behind are fetched from the validators
+	resync := node.startBlockSync(blockchain)
 
-	for {
-		msg, err := node.Sub.Next(ctx)
-		log.Println("Message Received")
-		if err != nil {
-			log.Println("Error reading from topic:", err)
-			continue
+	err := node.Check(MessageBlock, func(env *Envelope) error {
+		err := checkBlockUpdate(blockchain, env)
+		if errors.Is(err, errBlockAhead) {
+			resync()
 		}
+		return err
+	})
+	if err != nil {
+		log.Println("Error checking block updates:", err)
+	}
 
-		// Try to determine if this is a block update
-		var msgType struct {
-			Type string `json:"type"`
+	err = node.Handle(MessageBlock, func(env *Envelope) {
+		var update BlockUpdate
+		if err := env.Decode(&update); err != nil {
+			log.Println("Invalid block update:", err)
+			return
 		}
-		if err := json.Unmarshal(msg.Data, &msgType); err == nil && msgType.Type == "block_update" {
-			// Handle block update
-			var updateMsg struct {
-				Type  string `json:"type"`
-				Block Block  `json:"block"`
-			}
-			if err := json.Unmarshal(msg.Data, &updateMsg); err == nil {
-				log.Println("Received block update:", updateMsg.Block.Hash)
-
-				// Check if we already have this block
-				hasBlock := false
-				blockchain.mu.Lock()
-				for _, b := range blockchain.Blocks {
-					if b.Hash == updateMsg.Block.Hash {
-						hasBlock = true
-						break
-					}
-				}
-
-				if !hasBlock {
-					blockCopy := updateMsg.Block
-					blockCopy.PrevHash = blockchain.Blocks[len(blockchain.Blocks)-1].Hash // we dont want validators blocks last hash :)
-					blockchain.Blocks = append(blockchain.Blocks, &blockCopy)
-					blockchain.persistBlock(&blockCopy)
-
-					log.Println("Added new block from network:", blockCopy.Hash)
-				}
-				blockchain.mu.Unlock()
-			}
+		block := update.Block
+		log.Printf("Received block update from validator %d: %s", update.ValidatorID, block.Hash)
+
+		// Checked to extend the chain, unless another update of the same block got in first
+		if err := addNetworkBlock(blockchain, block); err != nil {
+			log.Println("Skipped block from network:", err)
+			return
 		}
+		log.Println("Added new block from network:", block.Hash)
+	})
+	if err != nil {
+		log.Println("Error listening for block updates:", err)
+	}
+
+	err = node.Check(MessageReceipt, func(env *Envelope) error { return checkReceipt(blockchain, env) })
+	if err != nil {
+		log.Println("Error checking receipts:", err)
+	}
+
+	err = node.Handle(MessageReceipt, func(env *Envelope) {
+		var receipt SignedReceipt
+		if err := env.Decode(&receipt); err != nil {
+			log.Println("Invalid receipt:", err)
+			return
+		}
+		// The local chain already decided, and a block may have come in since the check
+		if height, _ := blockchain.FindTransaction(receipt.TxID); height >= 0 {
+			return
+		}
+		log.Printf("Received receipt for %s from validator %d: %s", receipt.TxID, receipt.ValidatorID, receipt.Status)
+		blockchain.SaveReceipt(receipt.Receipt)
+	})
+	if err != nil {
+		log.Println("Error listening for receipts:", err)
+	}
+}
+
+// Reject receipts that aren't signed by a validator or contradict the local chain, and skip
+// receipts of transactions the local chain already includes
+func checkReceipt(blockchain *Blockchain, env *Envelope) error {
+	var receipt SignedReceipt
+	if err := env.Decode(&receipt); err != nil {
+		return fmt.Errorf("invalid receipt: %w", err)
+	}
+	if _, ok := txStatusRank[receipt.Status]; !ok {
+		return fmt.Errorf("receipt has unknown status %q", receipt.Status)
+	}
+
+	validators := blockchain.Validators()
+	if validators == nil {
+		return fmt.Errorf("%w: chain has no genesis to check receipts against", ErrStaleMessage)
+	}
+	if _, ok := validators.Key(receipt.ValidatorID); !ok {
+		return fmt.Errorf("%w: validator %d isn't in the validator set", ErrStaleMessage, receipt.ValidatorID)
+	}
+	if !VerifyReceipt(receipt, validators) {
+		return fmt.Errorf("receipt for %s has an invalid signature", receipt.TxID)
+	}
+
+	height, _ := blockchain.FindTransaction(receipt.TxID)
+	blocks := blockchain.chain()
+	if height >= 0 {
+		if receipt.Status == TxStatusIncluded && (receipt.BlockIndex != height || receipt.BlockHash != blocks[height].Hash) {
+			return fmt.Errorf("receipt puts %s in block %d, the chain has it in block %d", receipt.TxID, receipt.BlockIndex, height)
+		}
+		return fmt.Errorf("%w: transaction %s is already in block %d", ErrStaleMessage, receipt.TxID, height)
+	}
+	// Blocks this node has can be checked, it learns of later ones from the receipt
+	if receipt.Status == TxStatusIncluded && receipt.BlockIndex < len(blocks) {
+		return fmt.Errorf("receipt puts %s in block %d, which doesn't include it", receipt.TxID, receipt.BlockIndex)
 	}
+	return nil
 }
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Consensus implementations, chosen by the genesis of the network
+const (
+	ConsensusVote = "vote" // Validators vote on each transaction, tolerates Byzantine validators
+	ConsensusBFT  = "bft"  // Rounds of propose, prevote and precommit, tolerates Byzantine validators
+	ConsensusRaft = "raft" // Leader replicates blocks to followers, only tolerates crashes
+)
+
+const defaultBlockSize = 100
+
+// How far ahead of the local clock a block may be timestamped
+const maxBlockClockSkew = time.Minute
+
+// Consensus decides which transactions make it into the chain, in the same order on every validator
+type Consensus interface {
+	// Run until ctx is canceled
+	Run(ctx context.Context)
+	// Propose a transaction that was admitted into the mempool
+	Propose(tx LicenseTransaction)
+	// Reject a transaction that failed the validator's checks, reason is one of the Reject* codes
+	Reject(tx LicenseTransaction, reason string)
+	// Receive a consensus message broadcast by another validator
+	Receive(data []byte)
+	// Validate a consensus message before the network relays it, errors wrapping ErrStaleMessage
+	// only drop it, others count against the peer that sent it
+	Validate(data []byte) error
+}
+
+// ConsensusConfig is what every consensus implementation is built from
+type ConsensusConfig struct {
+	ID         int
+	PrivateKey *ecdsa.PrivateKey
+	Blockchain *Blockchain
+	Mempool    *Mempool
+	Broadcast  func(data []byte)       // Deliver a message to the other validators
+	OnCommit   func(block *Block)      // A block was added to the chain
+	OnReceipt  func(receipt Receipt)   // The status of a transaction changed
+	OnEvidence func(evidence Evidence) // Another validator signed conflicting messages
+	OnMissing  func(txID string)       // Votes arrived for a transaction that isn't in the mempool
+}
+
+// NewConsensus creates the consensus implementation of the given kind, the default one if empty
+func NewConsensus(kind string, cfg ConsensusConfig) (Consensus, error) {
+	if cfg.Blockchain.Validators() == nil {
+		return nil, errors.New("chain has no genesis")
+	}
+	if cfg.OnCommit == nil {
+		cfg.OnCommit = func(*Block) {}
+	}
+	if cfg.OnReceipt == nil {
+		cfg.OnReceipt = func(Receipt) {}
+	}
+	if cfg.OnEvidence == nil {
+		cfg.OnEvidence = func(Evidence) {}
+	}
+	if cfg.OnMissing == nil {
+		cfg.OnMissing = func(string) {}
+	}
+
+	switch kind {
+	case "", ConsensusVote:
+		return newVoteConsensus(cfg), nil
+	case ConsensusBFT:
+		return newBFTConsensus(cfg), nil
+	case ConsensusRaft:
+		return newRaftConsensus(cfg), nil
+	}
+	return nil, fmt.Errorf("unknown consensus %q", kind)
+}
+
+// Signed by a validator this one doesn't know, possibly added by a change it hasn't committed yet
+func knownValidator(validators *ValidatorSet, id int) error {
+	if _, ok := validators.Key(id); !ok {
+		return fmt.Errorf("%w: validator %d isn't in the validator set", ErrStaleMessage, id)
+	}
+	return nil
+}
+
+// Check a block carried by a consensus message wasn't altered after it was hashed
+func checkBlockHash(block *Block) error {
+	if block != nil && calculateHash(*block) != block.Hash {
+		return fmt.Errorf("block %d has an invalid hash", block.Index)
+	}
+	return nil
+}
+
+// Receipts for the transactions of a block that was added to the chain
+func includedReceipts(cfg ConsensusConfig, block *Block) {
+	for _, tx := range block.Transaction {
+		cfg.Mempool.RemoveTransaction(tx.TxID)
+		cfg.OnReceipt(Receipt{TxID: tx.TxID, Status: TxStatusIncluded, BlockIndex: block.Index, BlockHash: block.Hash})
+	}
+}
+
+// Rejections only known to this validator, for implementations that don't vote on transactions.
+// The reason is kept without deciding the outcome, others may still include the transaction.
+func rejectLocally(cfg ConsensusConfig, tx LicenseTransaction, reason string) {
+	cfg.Blockchain.recordRejection(tx.TxID, cfg.ID, reason, false)
+}
+
+// Fill a block with transactions from the mempool that are valid on top of the chain, nil if there are none
+func buildBlock(bc *Blockchain, candidates []LicenseTransaction, params ConsensusParams) *Block {
+	size := params.BlockSize
+	if size <= 0 {
+		size = defaultBlockSize
+	}
+
+	at := time.Now()
+	var txs []LicenseTransaction
+	signers, assets := make(map[string]bool), make(map[string]bool)
+	for _, tx := range candidates {
+		if len(txs) == size {
+			break
+		}
+		if !blockCanInclude(tx, bc, at, signers, assets) {
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	if len(txs) == 0 {
+		return nil
+	}
+
+	return createBlockAt(*bc.tip(), txs, at)
+}
+
+// Check a block proposed by another validator before adding it to the chain
+func validateBlock(bc *Blockchain, block *Block, params ConsensusParams) error {
+	prev := bc.tip()
+	if block.Index != prev.Index+1 || block.PrevHash != prev.Hash {
+		return fmt.Errorf("block %d doesn't extend the chain at height %d", block.Index, prev.Index)
+	}
+	if calculateHash(*block) != block.Hash {
+		return fmt.Errorf("block %d has an invalid hash", block.Index)
+	}
+
+	// Recovery delays run from block times, so a proposer can't move them back or far ahead
+	at, err := BlockTime(block)
+	if err != nil {
+		return fmt.Errorf("block %d has an invalid timestamp: %w", block.Index, err)
+	}
+	if prevAt, err := BlockTime(prev); err == nil && at.Before(prevAt) {
+		return fmt.Errorf("block %d is timestamped before block %d", block.Index, prev.Index)
+	}
+	if at.After(time.Now().Add(maxBlockClockSkew)) {
+		return fmt.Errorf("block %d is timestamped in the future", block.Index)
+	}
+
+	size := params.BlockSize
+	if size <= 0 {
+		size = defaultBlockSize
+	}
+	if len(block.Transaction) == 0 || len(block.Transaction) > size {
+		return fmt.Errorf("block %d has %d transactions, 1 to %d allowed", block.Index, len(block.Transaction), size)
+	}
+
+	signers, assets := make(map[string]bool), make(map[string]bool)
+	for _, tx := range block.Transaction {
+		if !blockCanInclude(tx, bc, at, signers, assets) {
+			return fmt.Errorf("block %d has invalid transaction %s", block.Index, tx.TxID)
+		}
+	}
+	return nil
+}
+
+// Transactions in one block are checked against the chain only, so a block holds at most one
+// transaction per signer and per asset to keep them from depending on each other. at is the
+// timestamp of the block.
+func blockCanInclude(tx LicenseTransaction, bc *Blockchain, at time.Time, signers, assets map[string]bool) bool {
+	signer := TransactionSigner(tx)
+	if signers[signer] || (tx.AssetHash != "" && assets[tx.AssetHash]) {
+		return false
+	}
+	if height, _ := bc.FindTransaction(tx.TxID); height >= 0 {
+		return false
+	}
+	if !ValidateTransaction(tx) || checkLicenseAt(tx, bc, at) != "" {
+		return false
+	}
+
+	signers[signer] = true
+	if tx.AssetHash != "" {
+		assets[tx.AssetHash] = true
+	}
+	return true
+}
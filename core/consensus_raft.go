@@ -0,0 +1,459 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// Raft messages
+const (
+	RaftAppend      = "append"
+	RaftAppendReply = "append-reply"
+	RaftRequestVote = "request-vote"
+	RaftVoteReply   = "vote-reply"
+)
+
+// Roles of a Raft validator
+const (
+	raftFollower = iota
+	raftCandidate
+	raftLeader
+)
+
+// Key of the term, vote and pending block a Raft validator must not forget across restarts
+const RaftStateKey = "raft-state"
+
+const defaultRaftTimeout = time.Second
+
+// RaftMessage is exchanged between Raft validators, To is -1 for messages to every validator
+type RaftMessage struct {
+	Type string
+	Term int
+	From int
+	To   int
+
+	// append: the entry at the follower's next index, already committed if its index is below Committed
+	Block     *Block `json:",omitempty"`
+	EntryTerm int
+	Committed int // Length of the leader's chain
+
+	// request-vote: the candidate's last entry
+	LastIndex int
+	LastTerm  int
+
+	Granted bool   // vote-reply
+	Next    int    // append-reply, length of the follower's chain
+	Pending string // append-reply, hash of the follower's uncommitted entry
+
+	Signature string
+}
+
+func raftDigest(msg RaftMessage) []byte {
+	msg.Signature = ""
+	data, _ := json.Marshal(msg)
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+type raftState struct {
+	Term     int
+	VotedFor int // -1 if the validator hasn't voted in Term
+	LastTerm int // Term of the entry of the last committed block
+
+	// The uncommitted block, the leader counts it as held once acked
+	Pending     *Block `json:",omitempty"`
+	PendingTerm int
+}
+
+// raftConsensus replicates blocks from an elected leader to its followers, in the style of Raft.
+// The chain is the committed log, with at most one uncommitted block after it: the leader only
+// creates a block after the previous one was replicated to a majority. It needs a majority of
+// validators up and trusts them to follow the protocol, so it suits permissioned networks only.
+type raftConsensus struct {
+	cfg   ConsensusConfig
+	inbox chan RaftMessage
+	wake  chan struct{}
+
+	state       raftState
+	role        int
+	leader      atomic.Int64 // ID of the current leader, -1 if unknown
+	deadline    time.Time    // Start an election if the leader isn't heard from by then
+	votes       map[int]bool
+	pending     *Block // Uncommitted block after the chain
+	pendingTerm int
+	next        map[int]int  // Leader only, index of the next entry each follower needs
+	acks        map[int]bool // Leader only, validators holding the pending block
+}
+
+func newRaftConsensus(cfg ConsensusConfig) *raftConsensus {
+	r := &raftConsensus{
+		cfg:   cfg,
+		inbox: make(chan RaftMessage, 1024),
+		wake:  make(chan struct{}, 1),
+		state: raftState{VotedFor: -1},
+	}
+	r.leader.Store(-1)
+
+	if data, err := cfg.Blockchain.db.Load(RaftStateKey); err == nil {
+		if err := json.Unmarshal(data, &r.state); err != nil {
+			log.Printf("Raft %d: ignoring unreadable state: %v", cfg.ID, err)
+		}
+	}
+	// Only kept while it is still the block after the chain
+	if pending := r.state.Pending; pending != nil && pending.Index == len(cfg.Blockchain.chain()) {
+		r.pending, r.pendingTerm = pending, r.state.PendingTerm
+	}
+	r.state.Pending, r.state.PendingTerm = nil, 0
+	return r
+}
+
+// Leader returns the ID of the current leader as far as this validator knows, -1 if unknown
+func (r *raftConsensus) Leader() int {
+	return int(r.leader.Load())
+}
+
+func (r *raftConsensus) Run(ctx context.Context) {
+	r.resetElection()
+	ticker := time.NewTicker(r.timeout() / 5)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-r.inbox:
+			r.handle(msg)
+		case <-r.wake:
+			r.propose()
+		case <-ticker.C:
+			if r.role == raftLeader {
+				r.sendAppends()
+			} else if time.Now().After(r.deadline) && len(r.inbox) == 0 {
+				// Queued messages may be from the leader, a busy validator mustn't depose it
+				r.startElection()
+			}
+		}
+	}
+}
+
+// Only the leader puts transactions in blocks, the others wait for its blocks
+func (r *raftConsensus) Propose(tx LicenseTransaction) {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Validators are trusted, a leader leaves invalid transactions out of its blocks, so its
+// rejection is the outcome
+func (r *raftConsensus) Reject(tx LicenseTransaction, reason string) {
+	if r.Leader() != r.cfg.ID {
+		rejectLocally(r.cfg, tx, reason)
+		return
+	}
+	r.cfg.Blockchain.recordRejection(tx.TxID, r.cfg.ID, reason, true)
+	r.cfg.OnReceipt(Receipt{TxID: tx.TxID, Status: TxStatusRejected, Reason: reason})
+}
+
+func (r *raftConsensus) Receive(data []byte) {
+	var msg RaftMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Printf("Raft %d: received invalid message: %v", r.cfg.ID, err)
+		return
+	}
+	// Messages to one validator reach all of them, they mustn't crowd out this one's
+	if msg.To != -1 && msg.To != r.cfg.ID {
+		return
+	}
+
+	select {
+	case r.inbox <- msg:
+	default:
+		log.Printf("Raft %d: inbox full, dropped %s from validator %d", r.cfg.ID, msg.Type, msg.From)
+	}
+}
+
+func (r *raftConsensus) Validate(data []byte) error {
+	var msg RaftMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("invalid raft message: %w", err)
+	}
+
+	validators := r.cfg.Blockchain.Validators()
+	if err := knownValidator(validators, msg.From); err != nil {
+		return err
+	}
+	pubKey, _ := validators.Key(msg.From)
+	if !verifySignature(pubKey, msg.Signature, raftDigest(msg)) {
+		return fmt.Errorf("%s not signed by validator %d", msg.Type, msg.From)
+	}
+	return checkBlockHash(msg.Block)
+}
+
+func (r *raftConsensus) timeout() time.Duration {
+	if ms := r.cfg.Blockchain.Validators().Params().TimeoutMs; ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return defaultRaftTimeout
+}
+
+// Randomized so that validators rarely start elections at the same time
+func (r *raftConsensus) resetElection() {
+	timeout := r.timeout()
+	r.deadline = time.Now().Add(timeout + time.Duration(rand.Int63n(int64(timeout))))
+}
+
+func (r *raftConsensus) majority() int {
+	return r.cfg.Blockchain.Validators().Size()/2 + 1
+}
+
+func (r *raftConsensus) saveState() {
+	state := r.state
+	state.Pending, state.PendingTerm = r.pending, r.pendingTerm
+	data, _ := json.Marshal(state)
+	if err := r.cfg.Blockchain.db.Save(RaftStateKey, data); err != nil {
+		log.Printf("Raft %d: error saving state: %v", r.cfg.ID, err)
+	}
+}
+
+// Index and term of the last entry, committed or not
+func (r *raftConsensus) lastEntry() (int, int) {
+	index := len(r.cfg.Blockchain.chain()) - 1
+	if r.pending != nil {
+		return index + 1, r.pendingTerm
+	}
+	return index, r.state.LastTerm
+}
+
+func (r *raftConsensus) send(msg RaftMessage) {
+	msg.Term, msg.From = r.state.Term, r.cfg.ID
+	msg.Signature = signDigest(r.cfg.PrivateKey, raftDigest(msg))
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Raft %d: error marshaling %s: %v", r.cfg.ID, msg.Type, err)
+		return
+	}
+	r.cfg.Broadcast(data)
+}
+
+func (r *raftConsensus) handle(msg RaftMessage) {
+	if msg.To != -1 && msg.To != r.cfg.ID {
+		return
+	}
+	pubKey, ok := r.cfg.Blockchain.Validators().Key(msg.From)
+	if !ok || !verifySignature(pubKey, msg.Signature, raftDigest(msg)) {
+		log.Printf("Raft %d: dropped %s from validator %d with an invalid signature", r.cfg.ID, msg.Type, msg.From)
+		return
+	}
+
+	// A newer term means this validator's view is stale
+	if msg.Term > r.state.Term {
+		r.state.Term, r.state.VotedFor = msg.Term, -1
+		r.saveState()
+		r.role = raftFollower
+		r.leader.Store(-1)
+	}
+
+	switch msg.Type {
+	case RaftRequestVote:
+		r.handleRequestVote(msg)
+	case RaftVoteReply:
+		if r.role == raftCandidate && msg.Term == r.state.Term && msg.Granted {
+			r.votes[msg.From] = true
+			if len(r.votes) >= r.majority() {
+				r.becomeLeader()
+			}
+		}
+	case RaftAppend:
+		r.handleAppend(msg)
+	case RaftAppendReply:
+		r.handleAppendReply(msg)
+	}
+}
+
+func (r *raftConsensus) startElection() {
+	r.state.Term++
+	r.state.VotedFor = r.cfg.ID
+	r.saveState()
+	r.role = raftCandidate
+	r.leader.Store(-1)
+	r.votes = map[int]bool{r.cfg.ID: true}
+	r.resetElection()
+
+	log.Printf("Raft %d: starting election for term %d", r.cfg.ID, r.state.Term)
+	lastIndex, lastTerm := r.lastEntry()
+	r.send(RaftMessage{Type: RaftRequestVote, To: -1, LastIndex: lastIndex, LastTerm: lastTerm})
+
+	if len(r.votes) >= r.majority() {
+		r.becomeLeader()
+	}
+}
+
+func (r *raftConsensus) handleRequestVote(msg RaftMessage) {
+	// Only candidates with every committed block can win, so a new leader never loses one
+	lastIndex, lastTerm := r.lastEntry()
+	upToDate := msg.LastTerm > lastTerm || (msg.LastTerm == lastTerm && msg.LastIndex >= lastIndex)
+
+	granted := msg.Term == r.state.Term && (r.state.VotedFor == -1 || r.state.VotedFor == msg.From) && upToDate
+	if granted {
+		r.state.VotedFor = msg.From
+		r.saveState()
+		r.resetElection()
+	}
+	r.send(RaftMessage{Type: RaftVoteReply, To: msg.From, Granted: granted})
+}
+
+func (r *raftConsensus) becomeLeader() {
+	log.Printf("Raft %d: elected leader for term %d", r.cfg.ID, r.state.Term)
+	r.role = raftLeader
+	r.leader.Store(int64(r.cfg.ID))
+
+	r.next = make(map[int]int)
+	for _, member := range r.cfg.Blockchain.Validators().Members() {
+		r.next[member.ID] = len(r.cfg.Blockchain.chain())
+	}
+
+	// A block left over from an earlier term is replicated again as an entry of this term
+	r.acks = map[int]bool{r.cfg.ID: true}
+	if r.pending != nil {
+		r.pendingTerm = r.state.Term
+		r.saveState()
+		r.checkCommit()
+	}
+	r.propose()
+	r.sendAppends()
+}
+
+// Start replicating a new block if the leader has none in flight
+func (r *raftConsensus) propose() {
+	if r.role != raftLeader || r.pending != nil {
+		return
+	}
+
+	block := buildBlock(r.cfg.Blockchain, r.cfg.Mempool.GetTransactions(), r.cfg.Blockchain.Validators().Params())
+	if block == nil {
+		return
+	}
+	r.pending, r.pendingTerm = block, r.state.Term
+	r.saveState()
+	r.acks = map[int]bool{r.cfg.ID: true}
+
+	r.checkCommit()
+	r.sendAppends()
+}
+
+// Send every follower the entry it needs next, or a heartbeat if it is up to date
+func (r *raftConsensus) sendAppends() {
+	for _, member := range r.cfg.Blockchain.Validators().Members() {
+		if member.ID != r.cfg.ID {
+			r.sendAppend(member.ID)
+		}
+	}
+}
+
+func (r *raftConsensus) sendAppend(to int) {
+	blocks := r.cfg.Blockchain.chain()
+	msg := RaftMessage{Type: RaftAppend, To: to, Committed: len(blocks)}
+
+	next := r.next[to]
+	switch {
+	case next > 0 && next < len(blocks):
+		msg.Block = blocks[next]
+		if next == len(blocks)-1 {
+			msg.EntryTerm = r.state.LastTerm
+		}
+	case next == len(blocks) && r.pending != nil:
+		msg.Block, msg.EntryTerm = r.pending, r.pendingTerm
+	}
+	r.send(msg)
+}
+
+func (r *raftConsensus) handleAppend(msg RaftMessage) {
+	bc := r.cfg.Blockchain
+	if msg.Term < r.state.Term {
+		r.send(RaftMessage{Type: RaftAppendReply, To: msg.From, Next: len(bc.chain())})
+		return
+	}
+	r.role = raftFollower
+	r.leader.Store(int64(msg.From))
+	r.resetElection()
+
+	if block := msg.Block; block != nil && block.Index == len(bc.chain()) {
+		// The pending block is sent again until it is committed, it was checked the first time
+		var err error
+		if r.pending == nil || r.pending.Hash != block.Hash {
+			err = validateBlock(bc, block, bc.Validators().Params())
+		}
+		if err != nil {
+			log.Printf("Raft %d: rejected block %d from leader %d: %v", r.cfg.ID, block.Index, msg.From, err)
+		} else if block.Index < msg.Committed {
+			r.commit(block, msg.EntryTerm)
+		} else if r.pending == nil || r.pending.Hash != block.Hash || r.pendingTerm != msg.EntryTerm {
+			// Replaces any block an earlier leader didn't get committed, stored before it is acked
+			r.pending, r.pendingTerm = block, msg.EntryTerm
+			r.saveState()
+		}
+	}
+
+	blocks := bc.chain()
+	reply := RaftMessage{Type: RaftAppendReply, To: msg.From, Next: len(blocks)}
+	switch block := msg.Block; {
+	case block != nil && block.Index < len(blocks) && blocks[block.Index].Hash == block.Hash:
+		// A leader elected with the last committed block still pending needs to hear it is held
+		reply.Pending = block.Hash
+	case r.pending != nil:
+		reply.Pending = r.pending.Hash
+	}
+	r.send(reply)
+}
+
+func (r *raftConsensus) handleAppendReply(msg RaftMessage) {
+	if r.role != raftLeader || msg.Term != r.state.Term {
+		return
+	}
+
+	r.next[msg.From] = min(msg.Next, len(r.cfg.Blockchain.chain()))
+	if r.pending != nil && msg.Pending == r.pending.Hash {
+		r.next[msg.From] = r.pending.Index + 1
+		r.acks[msg.From] = true
+		r.checkCommit()
+	}
+
+	// Followers that are behind get the blocks they miss without waiting for a heartbeat
+	if r.next[msg.From] < len(r.cfg.Blockchain.chain()) {
+		r.sendAppend(msg.From)
+	}
+}
+
+// Commit the pending block once a majority holds it
+func (r *raftConsensus) checkCommit() {
+	if r.pending == nil || len(r.acks) < r.majority() {
+		return
+	}
+
+	r.commit(r.pending, r.pendingTerm)
+	r.sendAppends()
+	r.propose()
+}
+
+func (r *raftConsensus) commit(block *Block, term int) {
+	if err := r.cfg.Blockchain.AddBlock(block); err != nil {
+		log.Printf("Raft %d: failed to add block %d: %v", r.cfg.ID, block.Index, err)
+		return
+	}
+	if r.pending != nil && r.pending.Index <= block.Index {
+		r.pending = nil
+	}
+	r.state.LastTerm = term
+	r.saveState()
+
+	includedReceipts(r.cfg, block)
+	r.cfg.OnCommit(block)
+}
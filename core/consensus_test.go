@@ -0,0 +1,332 @@
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// consensusNetwork runs validators of one consensus kind in a single process
+type consensusNetwork struct {
+	keys     []*ecdsa.PrivateKey
+	chains   []*Blockchain
+	mempools []*Mempool
+	nodes    []Consensus
+	cancels  []context.CancelFunc
+	queues   []chan []byte // Messages to each validator, delivered on its own goroutine like gossip does
+
+	mu   sync.Mutex
+	down map[int]bool
+}
+
+func newConsensusNetwork(t testing.TB, kind string, size int) *consensusNetwork {
+	t.Helper()
+	return newConsensusNetworkWithParams(t, ConsensusParams{TimeoutMs: 100, Consensus: kind}, size)
+}
+
+// newTestValidators generates the keys of n validators with IDs 0 to n-1
+func newTestValidators(t testing.TB, n int) ([]*ecdsa.PrivateKey, []ValidatorInfo) {
+	t.Helper()
+
+	var keys []*ecdsa.PrivateKey
+	var members []ValidatorInfo
+	for id := range n {
+		privKey, pubKey := GenerateKeyPair()
+		keys = append(keys, privKey)
+		members = append(members, ValidatorInfo{ID: id, PublicKey: pubKey})
+	}
+	return keys, members
+}
+
+func newConsensusNetworkWithParams(t testing.TB, params ConsensusParams, size int) *consensusNetwork {
+	t.Helper()
+
+	keys, members := newTestValidators(t, size)
+	genesis := &Genesis{Validators: members, Params: params}
+
+	net := &consensusNetwork{keys: keys, down: make(map[int]bool)}
+	var missing []chan string
+	for id := range size {
+		bc := newTestBlockchain(t)
+		if err := bc.InitGenesis(genesis); err != nil {
+			t.Fatal(err)
+		}
+		mempool := NewMempool(bc, MempoolConfig{})
+		missing = append(missing, make(chan string, 1024))
+
+		node, err := NewConsensus(params.Consensus, ConsensusConfig{
+			ID:         id,
+			PrivateKey: keys[id],
+			Blockchain: bc,
+			Mempool:    mempool,
+			Broadcast:  func(data []byte) { net.deliver(id, data) },
+			OnMissing: func(txID string) {
+				// Queued for one fetcher like a validator does, the next vote asks again if it is full
+				select {
+				case missing[id] <- txID:
+				default:
+				}
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		net.chains = append(net.chains, bc)
+		net.mempools = append(net.mempools, mempool)
+		net.nodes = append(net.nodes, node)
+		net.queues = append(net.queues, make(chan []byte, 4096))
+	}
+
+	for id, node := range net.nodes {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		net.cancels = append(net.cancels, cancel)
+		go node.Run(ctx)
+
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case txID := <-missing[id]:
+					net.fetch(id, txID)
+				}
+			}
+		}()
+
+		queue := net.queues[id]
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case data := <-queue:
+					node.Receive(data)
+				}
+			}
+		}()
+	}
+	return net
+}
+
+func (net *consensusNetwork) deliver(from int, data []byte) {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+
+	for id, queue := range net.queues {
+		if id == from || net.down[id] || net.down[from] {
+			continue
+		}
+		select {
+		case queue <- data:
+		default:
+			// Gossip drops messages for peers that can't keep up as well
+		}
+	}
+}
+
+// Fetch a transaction from the mempools of the other validators, as the mempool sync protocol does
+func (net *consensusNetwork) fetch(id int, txID string) {
+	for _, other := range net.online() {
+		if other == id {
+			continue
+		}
+		for _, tx := range net.mempools[other].answerSync(MempoolSyncRequest{TxIDs: []string{txID}}).Transactions {
+			if net.mempools[id].AddTransaction(tx) == nil {
+				net.nodes[id].Propose(tx)
+			}
+			return
+		}
+	}
+}
+
+func (net *consensusNetwork) online() []int {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+
+	var ids []int
+	for id := range net.nodes {
+		if !net.down[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Crash a validator, it neither sends nor receives anything after this
+func (net *consensusNetwork) crash(id int) {
+	net.mu.Lock()
+	net.down[id] = true
+	net.mu.Unlock()
+	net.cancels[id]()
+}
+
+// Submit a new upload to every online validator and wait until all of them included it
+func (net *consensusNetwork) upload(t *testing.T, asset string) {
+	t.Helper()
+
+	tx, err := net.submit(asset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	net.waitIncluded(t, tx)
+}
+
+// Sign a new upload and submit it to every online validator
+func (net *consensusNetwork) submit(asset string) (LicenseTransaction, error) {
+	privKey, pubKey := GenerateKeyPair()
+	tx := sign(net.chains[0], LicenseTransaction{Owner: pubKey, AssetHash: asset, License: "view", TxType: TxTypeUpload}, privKey)
+	for _, id := range net.online() {
+		// Validators that saw a vote first may have fetched it, or even included it, already
+		err := net.mempools[id].AddTransaction(tx)
+		if height, _ := net.chains[id].FindTransaction(tx.TxID); err != nil && !errors.Is(err, ErrInMempool) && height < 0 {
+			return tx, fmt.Errorf("validator %d did not admit %s: %w", id, tx.TxID, err)
+		}
+		net.nodes[id].Propose(tx)
+	}
+	return tx, nil
+}
+
+// Wait until every online validator included tx
+func (net *consensusNetwork) waitIncluded(t *testing.T, tx LicenseTransaction) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for _, id := range net.online() {
+		for {
+			if height, _ := net.chains[id].FindTransaction(tx.TxID); height >= 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Validator %d didn't include the upload of %s", id, tx.AssetHash)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+// Every online validator must have the same chain
+func (net *consensusNetwork) checkReplicated(t *testing.T, height int) {
+	t.Helper()
+
+	online := net.online()
+	reference := net.chains[online[0]].chain()
+	for _, id := range online {
+		blocks := net.chains[id].chain()
+		if len(blocks) != height {
+			t.Fatalf("Validator %d has %d blocks, expected %d", id, len(blocks), height)
+		}
+		for i, block := range blocks {
+			if i >= len(reference) || block.Hash != reference[i].Hash {
+				t.Fatalf("Validator %d has another block at height %d", id, i)
+			}
+		}
+	}
+}
+
+// The replicated-chain suite every consensus implementation must pass
+func TestConsensusReplicatesChain(t *testing.T) {
+	for _, kind := range []string{ConsensusVote, ConsensusBFT, ConsensusRaft} {
+		t.Run(kind, func(t *testing.T) {
+			net := newConsensusNetwork(t, kind, 4)
+			for i := range 3 {
+				net.upload(t, fmt.Sprintf("asset-%s-%d", kind, i))
+			}
+			net.checkReplicated(t, 4)
+		})
+
+		// Submitted at once, so different validators have different transactions ready
+		t.Run(kind+"/concurrent", func(t *testing.T) {
+			net := newConsensusNetwork(t, kind, 4)
+			txs := make([]LicenseTransaction, 8)
+			errs := make([]error, len(txs))
+			var wg sync.WaitGroup
+			for i := range txs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					txs[i], errs[i] = net.submit(fmt.Sprintf("asset-%s-concurrent-%d", kind, i))
+				}()
+			}
+			wg.Wait()
+
+			for i, tx := range txs {
+				if errs[i] != nil {
+					t.Fatal(errs[i])
+				}
+				net.waitIncluded(t, tx)
+			}
+			net.checkReplicated(t, len(net.chains[0].chain()))
+		})
+	}
+}
+
+func TestRaftElectsNewLeaderAfterCrash(t *testing.T) {
+	net := newConsensusNetwork(t, ConsensusRaft, 3)
+	net.upload(t, "asset-raft-before")
+
+	leader := net.nodes[0].(*raftConsensus).Leader()
+	if leader < 0 {
+		t.Fatal("No leader after a block was committed")
+	}
+	net.crash(leader)
+
+	net.upload(t, "asset-raft-after")
+	net.checkReplicated(t, 3)
+
+	for _, id := range net.online() {
+		if newLeader := net.nodes[id].(*raftConsensus).Leader(); newLeader == leader {
+			t.Errorf("Validator %d still follows the crashed leader", id)
+		}
+	}
+}
+
+func TestRaftFollowerKeepsAckedBlockAcrossRestart(t *testing.T) {
+	keys, members := newTestValidators(t, 3)
+	bc := newTestBlockchain(t)
+	if err := bc.InitGenesis(&Genesis{Validators: members, Params: ConsensusParams{Consensus: ConsensusRaft}}); err != nil {
+		t.Fatal(err)
+	}
+	var replies []RaftMessage
+	cfg := ConsensusConfig{
+		ID:         1,
+		PrivateKey: keys[1],
+		Blockchain: bc,
+		Mempool:    NewMempool(bc, MempoolConfig{}),
+		Broadcast: func(data []byte) {
+			var msg RaftMessage
+			json.Unmarshal(data, &msg)
+			replies = append(replies, msg)
+		},
+		OnCommit:  func(*Block) {},
+		OnReceipt: func(Receipt) {},
+	}
+
+	privKey, pubKey := GenerateKeyPair()
+	tx := sign(bc, LicenseTransaction{Owner: pubKey, AssetHash: "asset-raft-pending", License: "view", TxType: TxTypeUpload}, privKey)
+	block := CreateBlock(*bc.tip(), []LicenseTransaction{tx})
+
+	follower := newRaftConsensus(cfg)
+	follower.handleAppend(RaftMessage{Type: RaftAppend, Term: 0, From: 0, To: 1, Block: block, EntryTerm: 2, Committed: 1})
+	if len(replies) != 1 || replies[0].Pending != block.Hash {
+		t.Fatalf("Expected the block to be acked, got %+v", replies)
+	}
+
+	// The leader may commit it on this ack, so the restarted follower must still hold it
+	restarted := newRaftConsensus(cfg)
+	if restarted.pending == nil || restarted.pending.Hash != block.Hash || restarted.pendingTerm != 2 {
+		t.Fatalf("Expected pending block %s of term 2 after a restart, got %+v", block.Hash, restarted.pending)
+	}
+
+	// Dropped once the chain moved past it
+	restarted.commit(block, 2)
+	if restarted = newRaftConsensus(cfg); restarted.pending != nil || restarted.state.LastTerm != 2 {
+		t.Errorf("Expected no pending block after it was committed, got %+v", restarted.pending)
+	}
+}
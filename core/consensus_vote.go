@@ -0,0 +1,312 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const maxVoteBatch = 256
+
+// voteConsensusMessage carries votes, or a message of the engine ordering the approved
+// transactions. Block proposals come with the approvals of the transactions they include.
+type voteConsensusMessage struct {
+	Votes []VoteMessage `json:",omitempty"`
+	BFT   *BFTMessage   `json:",omitempty"`
+}
+
+// voteConsensus lets validators vote on each transaction. Transactions approved by a quorum are
+// put in blocks by a BFTEngine, so the validators agree on one block at each height.
+type voteConsensus struct {
+	cfg     ConsensusConfig
+	engine  *BFTEngine
+	inbox   chan voteConsensusMessage // Votes in here have verified signatures
+	outbox  chan VoteMessage          // Own votes waiting to be broadcast
+	started int64                     // Start of this run, signed into the votes cast in it
+}
+
+func newVoteConsensus(cfg ConsensusConfig) *voteConsensus {
+	c := &voteConsensus{
+		cfg:     cfg,
+		inbox:   make(chan voteConsensusMessage, 1024),
+		outbox:  make(chan VoteMessage, 1024),
+		started: time.Now().UnixNano(),
+	}
+	c.engine = NewBFTEngine(cfg.ID, cfg.PrivateKey, cfg.Blockchain, cfg.Mempool, c.sendBFT)
+	c.engine.Ready = c.approved
+	c.engine.OnCommit = c.committed
+	c.engine.OnEvidence = cfg.OnEvidence
+	return c
+}
+
+func (c *voteConsensus) Run(ctx context.Context) {
+	go c.sendVotes(ctx)
+	go c.engine.Run(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-c.inbox:
+			for _, vote := range msg.Votes {
+				c.handleVote(vote)
+			}
+			// After the approvals it carries, the engine checks a proposal against them
+			if msg.BFT != nil {
+				c.engine.Receive(*msg.BFT)
+			}
+		}
+	}
+}
+
+// Approve a transaction that passed the validator's checks
+func (c *voteConsensus) Propose(tx LicenseTransaction) {
+	c.vote(tx.TxID, true, "")
+}
+
+// Vote against a transaction, so the others learn it can't be approved instead of waiting for it
+func (c *voteConsensus) Reject(tx LicenseTransaction, reason string) {
+	c.vote(tx.TxID, false, reason)
+}
+
+// Sign and send a vote, stored before it leaves. A transaction proposed again, as after a
+// restart, gets the vote cast on it before, so the validator never contradicts itself.
+func (c *voteConsensus) vote(txID string, approved bool, reason string) {
+	bc := c.cfg.Blockchain
+	if voted := bc.ownVote(txID); voted != nil {
+		if voted.Approved != approved {
+			log.Printf("Validator %d already voted %t on transaction %s, sending that vote again", c.cfg.ID, voted.Approved, txID)
+		}
+		c.outbox <- *voted
+		c.inbox <- voteConsensusMessage{Votes: []VoteMessage{*voted}}
+		return
+	}
+
+	vote := VoteMessage{
+		TxID:        txID,
+		ValidatorID: c.cfg.ID,
+		Timestamp:   time.Now().Unix(),
+		Approved:    approved,
+		Reason:      reason,
+		Started:     c.started,
+	}
+	vote.Signature = SignVote(c.cfg.PrivateKey, &vote)
+	if err := bc.saveOwnVote(vote); err != nil {
+		log.Printf("Validator %d not voting on transaction %s, error saving the vote: %v", c.cfg.ID, txID, err)
+		return
+	}
+
+	c.outbox <- vote
+	c.inbox <- voteConsensusMessage{Votes: []VoteMessage{vote}}
+}
+
+// Broadcast own votes, all those cast since the last broadcast in one message
+func (c *voteConsensus) sendVotes(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case vote := <-c.outbox:
+			votes := []VoteMessage{vote}
+		batch:
+			for len(votes) < maxVoteBatch {
+				select {
+				case vote := <-c.outbox:
+					votes = append(votes, vote)
+				default:
+					break batch
+				}
+			}
+			c.broadcast(voteConsensusMessage{Votes: votes})
+		}
+	}
+}
+
+// Receive checks the signatures of the votes before queueing them, so the consensus loop doesn't
+func (c *voteConsensus) Receive(data []byte) {
+	var msg voteConsensusMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Printf("Validator %d received invalid vote format: %v", c.cfg.ID, err)
+		return
+	}
+
+	// Only votes signed by a member of the validator set count
+	validators := c.cfg.Blockchain.Validators()
+	votes := msg.Votes[:0]
+	for _, vote := range msg.Votes {
+		if !VerifyVote(vote, validators) {
+			log.Printf("Validator %d dropped vote for transaction %s: not signed by validator %d",
+				c.cfg.ID, vote.TxID, vote.ValidatorID)
+			continue
+		}
+		votes = append(votes, vote)
+	}
+	msg.Votes = votes
+
+	// Only proposals have to wait for the approvals they carry, the other messages of the engine
+	// don't queue up behind the votes on transactions
+	if msg.BFT != nil && msg.BFT.Type != BFTProposal {
+		c.engine.Receive(*msg.BFT)
+		if len(msg.Votes) == 0 {
+			return
+		}
+		msg.BFT = nil
+	}
+
+	select {
+	case c.inbox <- msg:
+	default:
+		log.Printf("Validator %d dropped a consensus message, too many queued", c.cfg.ID)
+	}
+}
+
+func (c *voteConsensus) Validate(data []byte) error {
+	var msg voteConsensusMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("invalid vote format: %w", err)
+	}
+	if len(msg.Votes) == 0 && msg.BFT == nil {
+		return errors.New("empty consensus message")
+	}
+
+	// A proposal carries the approvals of every transaction in its block
+	validators := c.cfg.Blockchain.Validators()
+	limit := maxVoteBatch
+	if msg.BFT != nil && msg.BFT.Block != nil {
+		limit += validators.Size() * len(msg.BFT.Block.Transaction)
+	}
+	if len(msg.Votes) > limit {
+		return fmt.Errorf("%d votes in one message, at most %d allowed", len(msg.Votes), limit)
+	}
+
+	for _, vote := range msg.Votes {
+		if err := knownValidator(validators, vote.ValidatorID); err != nil {
+			return err
+		}
+		if !VerifyVote(vote, validators) {
+			return fmt.Errorf("vote for transaction %s not signed by validator %d", vote.TxID, vote.ValidatorID)
+		}
+	}
+	if msg.BFT != nil {
+		return checkBFTMessage(*msg.BFT, validators)
+	}
+	return nil
+}
+
+func (c *voteConsensus) broadcast(msg voteConsensusMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Validator %d error marshaling vote: %v", c.cfg.ID, err)
+		return
+	}
+	c.cfg.Broadcast(data)
+}
+
+// Broadcast a message of the engine, proposals along with the approvals of their transactions
+// so the other validators can accept the block without waiting for the votes
+func (c *voteConsensus) sendBFT(msg BFTMessage) {
+	out := voteConsensusMessage{BFT: &msg}
+	if msg.Type == BFTProposal {
+		for _, tx := range msg.Block.Transaction {
+			out.Votes = append(out.Votes, c.cfg.Blockchain.approvals(tx.TxID)...)
+		}
+	}
+	c.broadcast(out)
+}
+
+// A transaction may go in a block once a quorum approved it
+func (c *voteConsensus) approved(tx LicenseTransaction) bool {
+	bc := c.cfg.Blockchain
+	approvals, _ := bc.voteTally(tx.TxID)
+	return approvals >= bc.Validators().Quorum()
+}
+
+func (c *voteConsensus) handleVote(vote VoteMessage) {
+	bc := c.cfg.Blockchain
+	validators := bc.Validators()
+	if height, _ := bc.FindTransaction(vote.TxID); height >= 0 {
+		return
+	}
+	if vote.Approved && c.cfg.Mempool.GetTransactionByID(vote.TxID) == nil {
+		// Gossiped before this validator joined, or lost on the way
+		c.cfg.OnMissing(vote.TxID)
+	}
+
+	if vote.Approved {
+		log.Printf("Validator %d received vote for transaction %s from validator %d",
+			c.cfg.ID, vote.TxID, vote.ValidatorID)
+	} else {
+		log.Printf("Validator %d received rejection of transaction %s from validator %d: %s",
+			c.cfg.ID, vote.TxID, vote.ValidatorID, vote.Reason)
+	}
+
+	counted, evidence := bc.recordVote(vote)
+	if evidence != nil {
+		log.Printf("EVIDENCE: validator %d voted both %t and %t on transaction %s",
+			evidence.ValidatorID, evidence.First.Approved, evidence.Second.Approved, vote.TxID)
+		c.cfg.OnEvidence(Evidence{Vote: evidence})
+	}
+	if !counted {
+		return
+	}
+
+	// Decide on the vote that makes the outcome certain, without waiting for the rest.
+	// A rejection is final once too few validators are left to reach a quorum of approvals.
+	approvals, rejections := bc.voteTally(vote.TxID)
+	quorum := validators.Quorum()
+	rejected := !vote.Approved && rejections == validators.Size()-quorum+1
+
+	var rejection *Rejection
+	if !vote.Approved {
+		rejection = bc.recordRejection(vote.TxID, vote.ValidatorID, vote.Reason, rejected)
+	}
+
+	switch {
+	case vote.Approved && approvals >= quorum:
+		if approvals == quorum {
+			c.cfg.OnReceipt(Receipt{TxID: vote.TxID, Status: TxStatusApproved})
+		}
+		// Also once a validator that lacked the transaction fetched and approved it
+		c.engine.Notify()
+	case rejected:
+		log.Printf("Transaction %s rejected: %d of %d validators rejected it (%s)", vote.TxID, rejections, validators.Size(), rejection.Summary())
+		c.cfg.OnReceipt(Receipt{
+			TxID:   vote.TxID,
+			Status: TxStatusRejected,
+			Reason: fmt.Sprintf("%d of %d validators rejected it, %d approvals needed: %s", rejections, validators.Size(), quorum, rejection.Summary()),
+		})
+		c.cfg.Mempool.RemoveTransaction(vote.TxID)
+	}
+}
+
+// Once a block is committed, reject the approved transactions it conflicts with, as no block
+// can include them anymore
+func (c *voteConsensus) committed(block *Block) {
+	bc := c.cfg.Blockchain
+	includedReceipts(c.cfg, block)
+
+	// Included for good, a restart doesn't propose them again
+	for _, tx := range block.Transaction {
+		if err := bc.db.Delete(OwnVotePrefix + tx.TxID); err != nil {
+			log.Printf("Validator %d error deleting its vote on %s: %v", c.cfg.ID, tx.TxID, err)
+		}
+	}
+
+	for _, tx := range c.engine.candidates() {
+		reason := checkLicense(tx, bc)
+		if reason == "" {
+			continue
+		}
+		bc.recordRejection(tx.TxID, c.cfg.ID, reason, true)
+		c.cfg.OnReceipt(Receipt{
+			TxID:   tx.TxID,
+			Status: TxStatusRejected,
+			Reason: "transaction conflicts with the current chain state: " + reason,
+		})
+		c.cfg.Mempool.RemoveTransaction(tx.TxID)
+	}
+	c.cfg.OnCommit(block)
+}
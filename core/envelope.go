@@ -0,0 +1,190 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// ProtocolVersion of the envelopes this node sends, envelopes of other versions are dropped
+const ProtocolVersion = 1
+
+// Message types, the payload of each is described next to it
+const (
+	MessageTransaction   = "transaction"    // LicenseTransaction
+	MessageConsensus     = "consensus"      // Message of the consensus implementation, passed to Consensus.Receive
+	MessageBlock         = "block_update"   // Block added by a validator
+	MessageReceipt       = "tx_receipt"     // Receipt
+	MessagePeerDiscovery = "peer_discovery" // PeerDiscoveryMessage
+)
+
+// Message classes, each gossiped on its own topic of the network
+const (
+	TransactionTopic = "transactions"
+	VoteTopic        = "vote"
+	BlockTopic       = "blocks"
+	ReceiptTopic     = "receipts"
+	DiscoveryTopic   = "discovery"
+)
+
+var messageTopics = map[string]string{
+	MessageTransaction:   TransactionTopic,
+	MessageConsensus:     VoteTopic,
+	MessageBlock:         BlockTopic,
+	MessageReceipt:       ReceiptTopic,
+	MessagePeerDiscovery: DiscoveryTopic,
+}
+
+// Envelope wraps every message gossiped between nodes
+type Envelope struct {
+	Version   int             `json:"version"`
+	Type      string          `json:"type"`
+	Sender    peer.ID         `json:"sender"`
+	Timestamp int64           `json:"timestamp"`
+	Payload   json.RawMessage `json:"payload"`
+	Signature []byte          `json:"signature,omitempty"` // By the sender's libp2p key, over the envelope without it
+}
+
+// MessageHandler processes the envelopes of one message type
+type MessageHandler func(env *Envelope)
+
+func (e *Envelope) signedBytes() ([]byte, error) {
+	unsigned := *e
+	unsigned.Signature = nil
+	return json.Marshal(unsigned)
+}
+
+func (e *Envelope) sign(key crypto.PrivKey) error {
+	data, err := e.signedBytes()
+	if err != nil {
+		return err
+	}
+	e.Signature, err = key.Sign(data)
+	return err
+}
+
+// Verify checks the envelope is signed by its sender
+func (e *Envelope) Verify() error {
+	pubKey, err := e.Sender.ExtractPublicKey()
+	if err != nil {
+		return fmt.Errorf("no public key in sender ID: %w", err)
+	}
+	data, err := e.signedBytes()
+	if err != nil {
+		return err
+	}
+	if ok, err := pubKey.Verify(data, e.Signature); err != nil || !ok {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// Decode the payload into v
+func (e *Envelope) Decode(v any) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+// Publish a message of the given type, signed by this node, on the topic of its class
+func (n *Node) Publish(ctx context.Context, msgType string, payload any) error {
+	topic := n.topics[messageTopics[msgType]]
+	if topic == nil {
+		return fmt.Errorf("unknown message type %q", msgType)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s message: %w", msgType, err)
+	}
+	env := Envelope{
+		Version:   ProtocolVersion,
+		Type:      msgType,
+		Sender:    n.Host.ID(),
+		Timestamp: time.Now().Unix(),
+		Payload:   data,
+	}
+
+	if err := env.sign(n.Host.Peerstore().PrivKey(n.Host.ID())); err != nil {
+		return fmt.Errorf("failed to sign %s message: %w", msgType, err)
+	}
+
+	data, err = json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return topic.Publish(ctx, data)
+}
+
+// Handle registers a handler for a message type, subscribing to the topic of its class. Handlers
+// of one class run one message at a time, so a slow handler slows down reading its topic.
+func (n *Node) Handle(msgType string, handler MessageHandler) error {
+	class, ok := messageTopics[msgType]
+	if !ok {
+		return fmt.Errorf("unknown message type %q", msgType)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.handlers[msgType] = append(n.handlers[msgType], handler)
+	if n.subs[class] != nil {
+		return nil
+	}
+
+	sub, err := n.topics[class].Subscribe()
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to topic: %w", err)
+	}
+	n.subs[class] = sub
+	go n.dispatch(class, sub)
+	return nil
+}
+
+// Read a topic and hand its envelopes to the handlers of their type, dropping this node's own
+func (n *Node) dispatch(class string, sub *pubsub.Subscription) {
+	for {
+		msg, err := sub.Next(n.ctx)
+		if err != nil {
+			if n.ctx.Err() == nil {
+				log.Printf("Error reading from topic %s: %v", class, err)
+			}
+			return
+		}
+		if msg.ReceivedFrom == n.Host.ID() {
+			continue
+		}
+
+		// Decoded and checked by the topic validator
+		env, ok := msg.ValidatorData.(*Envelope)
+		if !ok {
+			continue
+		}
+
+		n.mu.Lock()
+		handlers := n.handlers[env.Type]
+		n.mu.Unlock()
+		for _, handler := range handlers {
+			handler(env)
+		}
+	}
+}
+
+// Check an envelope read from the topic of class was sent by the peer that published it
+func checkEnvelope(class string, env *Envelope, author peer.ID) error {
+	if env.Version != ProtocolVersion {
+		return fmt.Errorf("protocol version %d, expected %d", env.Version, ProtocolVersion)
+	}
+	if messageTopics[env.Type] != class {
+		return fmt.Errorf("message type doesn't belong on topic %s", class)
+	}
+	if env.Sender != author {
+		return fmt.Errorf("sender doesn't match the gossip author %s", author)
+	}
+	return env.Verify()
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func TestEnvelopeChecks(t *testing.T) {
+	key, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, _, _ := crypto.GenerateEd25519Key(nil)
+	other, _ := peer.IDFromPrivateKey(otherKey)
+
+	envelope := func(change func(env *Envelope)) *Envelope {
+		env := &Envelope{Version: ProtocolVersion, Type: MessageReceipt, Sender: sender, Payload: json.RawMessage(`{"TxID":"tx"}`)}
+		if err := env.sign(key); err != nil {
+			t.Fatal(err)
+		}
+		change(env)
+		return env
+	}
+
+	if err := checkEnvelope(ReceiptTopic, envelope(func(*Envelope) {}), sender); err != nil {
+		t.Errorf("Valid envelope was dropped: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		class  string
+		env    *Envelope
+		author peer.ID
+	}{
+		{"old version", ReceiptTopic, envelope(func(env *Envelope) { env.Version = 0 }), sender},
+		{"wrong topic", BlockTopic, envelope(func(*Envelope) {}), sender},
+		{"tampered payload", ReceiptTopic, envelope(func(env *Envelope) { env.Payload = json.RawMessage(`{"TxID":"other"}`) }), sender},
+		{"relabeled type", TransactionTopic, envelope(func(env *Envelope) { env.Type = MessageTransaction }), sender},
+		{"forwarded as someone else's", ReceiptTopic, envelope(func(*Envelope) {}), other},
+		{"claimed sender", ReceiptTopic, envelope(func(env *Envelope) { env.Sender = other }), other},
+	}
+	for _, test := range tests {
+		if err := checkEnvelope(test.class, test.env, test.author); err == nil {
+			t.Errorf("%s: envelope was accepted", test.name)
+		}
+	}
+}
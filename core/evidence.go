@@ -0,0 +1,240 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Kinds of misbehavior an evidence transaction can prove
+const (
+	EvidenceVote = "vote" // Approved and rejected the same transaction
+	EvidenceBFT  = "bft"  // Signed two different blocks in the same BFT step
+)
+
+// Status of a validator, derived from the chain
+const (
+	ValidatorActive     = "active"
+	ValidatorJailed     = "jailed"     // Out of the active set for a number of blocks
+	ValidatorTombstoned = "tombstoned" // Out of the active set for good
+)
+
+const defaultJailBlocks = 100
+
+// BFTEvidence holds two BFT messages of one validator for the same step that name different blocks
+type BFTEvidence struct {
+	ValidatorID int
+	First       BFTMessage
+	Second      BFTMessage
+}
+
+// Evidence is stored in the Metadata of an evidence transaction, exactly one field is set
+type Evidence struct {
+	Vote *VoteEvidence `json:",omitempty"`
+	BFT  *BFTEvidence  `json:",omitempty"`
+}
+
+// SlashRecord is a penalty the chain gave a validator
+type SlashRecord struct {
+	TxID    string // Evidence transaction
+	Height  int    // Block including it
+	Kind    string // EvidenceVote or EvidenceBFT
+	Penalty string // ValidatorJailed or ValidatorTombstoned
+	Until   int    `json:",omitempty"` // Height from which a jailed validator is active again
+}
+
+// ValidatorStatus is a member of the validator set with the penalties it got
+type ValidatorStatus struct {
+	ValidatorInfo
+	Status  string
+	Until   int // Height from which a jailed validator is active again
+	History []SlashRecord
+}
+
+func NewEvidenceMetadata(evidence Evidence) string {
+	data, _ := json.Marshal(evidence)
+	return string(data)
+}
+
+func parseEvidence(tx LicenseTransaction) (*Evidence, error) {
+	var evidence Evidence
+	if err := json.Unmarshal([]byte(tx.Metadata), &evidence); err != nil {
+		return nil, fmt.Errorf("invalid evidence: %w", err)
+	}
+	if (evidence.Vote == nil) == (evidence.BFT == nil) {
+		return nil, errors.New("evidence must hold either conflicting votes or conflicting BFT messages")
+	}
+	return &evidence, nil
+}
+
+// Kind of misbehavior the evidence proves
+func (e *Evidence) Kind() string {
+	if e.BFT != nil {
+		return EvidenceBFT
+	}
+	return EvidenceVote
+}
+
+// Offender is the ID of the validator that signed both messages
+func (e *Evidence) Offender() int {
+	if e.BFT != nil {
+		return e.BFT.ValidatorID
+	}
+	return e.Vote.ValidatorID
+}
+
+// ID is the same for every piece of evidence of one misbehavior, so it is only punished once
+func (e *Evidence) ID() string {
+	if e.BFT != nil {
+		return fmt.Sprintf("bft-%d-%d-%d-%s", e.BFT.ValidatorID, e.BFT.First.Height, e.BFT.First.Round, e.BFT.First.Type)
+	}
+	return fmt.Sprintf("vote-%d-%s", e.Vote.ValidatorID, e.Vote.First.TxID)
+}
+
+// Verify checks that the offender signed both messages and that they conflict
+func (e *Evidence) Verify(validators *ValidatorSet) error {
+	if e.BFT != nil {
+		first, second := e.BFT.First, e.BFT.Second
+		if first.ValidatorID != e.BFT.ValidatorID || second.ValidatorID != e.BFT.ValidatorID {
+			return errors.New("messages were sent by another validator")
+		}
+		if first.Type != second.Type || first.Height != second.Height || first.Round != second.Round {
+			return errors.New("messages are for different steps")
+		}
+		if first.BlockHash == second.BlockHash {
+			return errors.New("messages name the same block")
+		}
+		if !VerifyBFTMessage(first, validators) || !VerifyBFTMessage(second, validators) {
+			return fmt.Errorf("messages aren't signed by validator %d", e.BFT.ValidatorID)
+		}
+		return nil
+	}
+
+	first, second := e.Vote.First, e.Vote.Second
+	if first.ValidatorID != e.Vote.ValidatorID || second.ValidatorID != e.Vote.ValidatorID {
+		return errors.New("votes were cast by another validator")
+	}
+	if first.TxID != second.TxID {
+		return errors.New("votes are on different transactions")
+	}
+	if first.Approved == second.Approved {
+		return errors.New("votes agree")
+	}
+	if first.Started != second.Started {
+		return errors.New("votes were cast in different runs of the validator")
+	}
+	if !VerifyVote(first, validators) || !VerifyVote(second, validators) {
+		return fmt.Errorf("votes aren't signed by validator %d", e.Vote.ValidatorID)
+	}
+	return nil
+}
+
+// Persist evidence this validator saw, so it can be submitted later
+func (bc *Blockchain) saveEvidence(evidence Evidence) {
+	data, err := json.Marshal(evidence)
+	if err != nil {
+		log.Println("Error marshaling evidence:", err)
+		return
+	}
+
+	if err := bc.db.Save(EvidencePrefix+evidence.ID(), data); err != nil {
+		log.Println("Error saving evidence:", err)
+	}
+}
+
+// Check an evidence transaction against the penalties given before it
+func (g *governance) validateEvidence(tx LicenseTransaction) (*Evidence, error) {
+	evidence, err := parseEvidence(tx)
+	if err != nil {
+		return nil, err
+	}
+	// Jailed validators are still in g.set, so their misbehavior can be proven
+	if err := evidence.Verify(g.set); err != nil {
+		return nil, err
+	}
+	if g.tombstoned[evidence.Offender()] {
+		return nil, fmt.Errorf("validator %d is already tombstoned", evidence.Offender())
+	}
+	if g.punished[evidence.ID()] {
+		return nil, fmt.Errorf("validator %d was already punished for %s", evidence.Offender(), evidence.ID())
+	}
+	return evidence, nil
+}
+
+// Punish the offender of an evidence transaction included at height. Conflicting votes jail
+// a validator, double signing blocks tombstones it.
+func (g *governance) slash(tx LicenseTransaction, height int) {
+	evidence, err := g.validateEvidence(tx)
+	if err != nil {
+		return
+	}
+	id := evidence.Offender()
+
+	record := SlashRecord{TxID: tx.TxID, Height: height, Kind: evidence.Kind()}
+	switch record.Kind {
+	case EvidenceBFT:
+		record.Penalty = ValidatorTombstoned
+		g.tombstoned[id] = true
+	case EvidenceVote:
+		jailBlocks := g.set.Params().JailBlocks
+		if jailBlocks <= 0 {
+			jailBlocks = defaultJailBlocks
+		}
+		record.Penalty = ValidatorJailed
+		record.Until = height + 1 + jailBlocks
+		g.jailedUntil[id] = max(g.jailedUntil[id], record.Until)
+	}
+	g.punished[evidence.ID()] = true
+	g.slashes[id] = append(g.slashes[id], record)
+}
+
+// The members of the set that are neither jailed nor tombstoned at height
+func (g *governance) activeSet(height int) *ValidatorSet {
+	var members []ValidatorInfo
+	for _, member := range g.set.members {
+		if !g.tombstoned[member.ID] && g.jailedUntil[member.ID] <= height {
+			members = append(members, member)
+		}
+	}
+	// Without active validators no block could be added, not even the governance changes to fix that
+	if len(members) == len(g.set.members) || len(members) == 0 {
+		return g.set
+	}
+
+	params := g.set.Params()
+	if !quorumFits(params.Quorum, len(members), params.Consensus) {
+		params.Quorum = 0
+	}
+	set, err := NewValidatorSet(members, params)
+	if err != nil {
+		return g.set
+	}
+	return set
+}
+
+// ValidatorStatuses returns every member of the validator set with its status and penalties
+func (bc *Blockchain) ValidatorStatuses() ([]ValidatorStatus, error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	height := len(bc.Blocks)
+	g, err := bc.governance()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]ValidatorStatus, 0, g.set.Size())
+	for _, member := range g.set.members {
+		status := ValidatorStatus{ValidatorInfo: member, Status: ValidatorActive, History: g.slashes[member.ID]}
+		switch {
+		case g.tombstoned[member.ID]:
+			status.Status = ValidatorTombstoned
+		case g.jailedUntil[member.ID] > height:
+			status.Status = ValidatorJailed
+			status.Until = g.jailedUntil[member.ID]
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
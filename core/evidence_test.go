@@ -0,0 +1,78 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvidenceJailsAndTombstonesValidators(t *testing.T) {
+	bc := newTestBlockchain(t)
+	keys, members := newTestValidators(t, 4)
+	if err := bc.InitGenesis(&Genesis{Validators: members, Params: ConsensusParams{JailBlocks: 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	vote := func(id int, approved bool) VoteMessage {
+		vote := VoteMessage{TxID: "tx-equivocated", ValidatorID: id, Timestamp: time.Now().Unix(), Approved: approved}
+		vote.Signature = SignVote(keys[id], &vote)
+		return vote
+	}
+	precommit := func(id int, hash string) BFTMessage {
+		msg := BFTMessage{Type: BFTPrecommit, Height: 5, Round: 1, ValidatorID: id, BlockHash: hash}
+		msg.Signature = SignBFTMessage(keys[id], &msg)
+		return msg
+	}
+	reporter, reporterKey := GenerateKeyPair()
+	report := func(evidence Evidence) LicenseTransaction {
+		return sign(bc, LicenseTransaction{Owner: reporterKey, Metadata: NewEvidenceMetadata(evidence), TxType: TxTypeEvidence}, reporter)
+	}
+
+	agreeing := Evidence{Vote: &VoteEvidence{ValidatorID: 1, First: vote(1, true), Second: vote(1, true)}}
+	if RegisterLicense(report(agreeing), bc) {
+		t.Error("Evidence of two agreeing votes was accepted")
+	}
+	forged := Evidence{Vote: &VoteEvidence{ValidatorID: 2, First: vote(1, true), Second: vote(1, false)}}
+	if RegisterLicense(report(forged), bc) {
+		t.Error("Evidence blaming another validator was accepted")
+	}
+
+	votes := Evidence{Vote: &VoteEvidence{ValidatorID: 1, First: vote(1, true), Second: vote(1, false)}}
+	commit(t, bc, report(votes))
+	jailedAt := len(bc.Blocks) - 1
+
+	if validators := bc.Validators(); validators.Size() != 3 || validators.HasKey(members[1].PublicKey) {
+		t.Fatal("Jailed validator is still in the active set")
+	}
+	if RegisterLicense(report(votes), bc) {
+		t.Error("Validator was punished twice for the same votes")
+	}
+
+	blocks := Evidence{BFT: &BFTEvidence{ValidatorID: 2, First: precommit(2, "block-a"), Second: precommit(2, "block-b")}}
+	commit(t, bc, report(blocks))
+
+	statuses, err := bc.ValidatorStatuses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[1].Status != ValidatorJailed || statuses[1].Until != jailedAt+4 || len(statuses[1].History) != 1 {
+		t.Errorf("Unexpected status of the jailed validator %+v", statuses[1])
+	}
+	if statuses[2].Status != ValidatorTombstoned || statuses[2].History[0].Kind != EvidenceBFT {
+		t.Errorf("Unexpected status of the tombstoned validator %+v", statuses[2])
+	}
+	if bc.Validators().Size() != 2 {
+		t.Errorf("Expected 2 active validators, got %d", bc.Validators().Size())
+	}
+
+	// Jail ends after JailBlocks blocks, a tombstone never does
+	for len(bc.Blocks) < jailedAt+4 {
+		bc.AddTransaction(LicenseTransaction{TxID: "filler"})
+	}
+	validators := bc.Validators()
+	if !validators.HasKey(members[1].PublicKey) || validators.HasKey(members[2].PublicKey) {
+		t.Error("Expected validator 1 released and validator 2 still out")
+	}
+	if RegisterLicense(report(Evidence{BFT: &BFTEvidence{ValidatorID: 2, First: precommit(2, "block-a"), Second: precommit(2, "block-c")}}), bc) {
+		t.Error("Evidence against a tombstoned validator was accepted")
+	}
+}
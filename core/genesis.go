@@ -0,0 +1,156 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+const GenesisKey = "genesis"
+
+// Genesis describes the network every validator starts from
+type Genesis struct {
+	Validators []ValidatorInfo `json:"validators"`
+	Params     ConsensusParams `json:"params"`
+}
+
+// ConsensusParams tune how validators reach decisions, zero values use the defaults
+type ConsensusParams struct {
+	Quorum     int    `json:"quorum,omitempty"`      // Votes needed to decide, 0 uses 2f+1 of 3f+1
+	BlockSize  int    `json:"block_size,omitempty"`  // Most transactions in one block
+	TimeoutMs  int64  `json:"timeout_ms,omitempty"`  // How long to wait for a consensus step
+	Consensus  string `json:"consensus,omitempty"`   // Consensus implementation, "vote" if empty
+	JailBlocks int    `json:"jail_blocks,omitempty"` // Blocks a validator is jailed for after conflicting votes
+}
+
+// Load and check a genesis file
+func LoadGenesis(path string) (*Genesis, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no genesis file at %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read genesis: %w", err)
+	}
+
+	var genesis Genesis
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		return nil, fmt.Errorf("failed to parse genesis %s: %w", path, err)
+	}
+	if err := genesis.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid genesis %s: %w", path, err)
+	}
+	return &genesis, nil
+}
+
+// Validate checks that validator IDs and keys are unique and well formed
+func (g *Genesis) Validate() error {
+	if len(g.Validators) == 0 {
+		return errors.New("no validators")
+	}
+
+	ids := make(map[int]bool)
+	keys := make(map[string]bool)
+	for _, val := range g.Validators {
+		if ids[val.ID] {
+			return fmt.Errorf("duplicate validator ID %d", val.ID)
+		}
+		if keys[val.PublicKey] {
+			return fmt.Errorf("validator %d reuses the key of another validator", val.ID)
+		}
+		if len(val.PublicKey) != 128 {
+			return fmt.Errorf("validator %d has an invalid public key", val.ID)
+		}
+		ids[val.ID], keys[val.PublicKey] = true, true
+	}
+
+	switch g.Params.Consensus {
+	case "", ConsensusVote, ConsensusBFT, ConsensusRaft:
+	default:
+		return fmt.Errorf("unknown consensus %q", g.Params.Consensus)
+	}
+
+	_, err := g.ValidatorSet()
+	return err
+}
+
+// ValidatorSet of the genesis
+func (g *Genesis) ValidatorSet() (*ValidatorSet, error) {
+	return NewValidatorSet(g.Validators, g.Params)
+}
+
+// Save writes the genesis file
+func (g *Genesis) Save(path string) error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode genesis: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write genesis: %w", err)
+	}
+	return nil
+}
+
+// InitGenesis stores the genesis of a new chain, or checks it matches the one already stored
+func (bc *Blockchain) InitGenesis(genesis *Genesis) error {
+	if err := genesis.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(genesis)
+	if err != nil {
+		return fmt.Errorf("failed to encode genesis: %w", err)
+	}
+
+	if stored, err := bc.db.Load(GenesisKey); err == nil {
+		if !bytes.Equal(stored, data) {
+			return errors.New("genesis doesn't match the one this chain was started with")
+		}
+	} else if err := bc.db.Save(GenesisKey, data); err != nil {
+		return fmt.Errorf("failed to save genesis: %w", err)
+	}
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	gov, err := replayGovernance(genesis, bc.Blocks)
+	if err != nil {
+		return err
+	}
+	bc.genesis, bc.gov = genesis, gov
+	bc.validators = nil
+	return nil
+}
+
+// Genesis the chain was started with, nil if it has none
+func (bc *Blockchain) Genesis() *Genesis {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	return bc.genesis
+}
+
+func (bc *Blockchain) loadGenesis() {
+	data, err := bc.db.Load(GenesisKey)
+	if err != nil {
+		return
+	}
+
+	var genesis Genesis
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		log.Println("Error unmarshaling genesis:", err)
+		return
+	}
+	if err := genesis.Validate(); err != nil {
+		log.Println("Stored genesis is invalid:", err)
+		return
+	}
+	gov, err := replayGovernance(&genesis, bc.Blocks)
+	if err != nil {
+		log.Println("Error replaying governance:", err)
+		return
+	}
+	bc.genesis, bc.gov = &genesis, gov
+}
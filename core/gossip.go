@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Largest message accepted on the topic of each class, larger ones are dropped before decoding
+var maxMessageSizes = map[string]int{
+	TransactionTopic: 256 << 10,
+	VoteTopic:        pubsub.DefaultMaxMessageSize, // BFT proposals carry a whole block
+	BlockTopic:       pubsub.DefaultMaxMessageSize,
+	ReceiptTopic:     16 << 10,
+	DiscoveryTopic:   16 << 10,
+}
+
+// Peer scoring: every invalid message a peer delivers costs it invalidMessagePenalty times the
+// square of how many it delivered recently, so a few slips are forgiven but a peer that keeps
+// sending invalid data gets graylisted, and this node ignores everything it sends
+const (
+	invalidMessagePenalty = -100
+	invalidMessageMemory  = time.Hour // Until the count of invalid messages of a peer decays away
+	gossipThreshold       = -1000     // No more gossip about messages to or from the peer, after 4 invalid messages
+	publishThreshold      = -2000     // Own messages aren't published to the peer, after 5
+	graylistThreshold     = -4000     // Everything from the peer is ignored, after 7
+)
+
+// ErrStaleMessage is wrapped by checks of messages that are outdated rather than invalid, like a
+// transaction that is already in the chain. They are dropped without counting against the sender.
+var ErrStaleMessage = errors.New("stale message")
+
+// MessageCheck validates the payload of a message before it is handled or relayed to other peers
+type MessageCheck func(env *Envelope) error
+
+// Check registers a check for a message type, messages failing it are neither handled nor relayed
+func (n *Node) Check(msgType string, check MessageCheck) error {
+	if _, ok := messageTopics[msgType]; !ok {
+		return fmt.Errorf("unknown message type %q", msgType)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.checks[msgType] = append(n.checks[msgType], check)
+	return nil
+}
+
+// Decode and check a message read from the topic of class that author published
+func (n *Node) checkMessage(class string, data []byte, author peer.ID) (*Envelope, error) {
+	if len(data) > maxMessageSizes[class] {
+		return nil, fmt.Errorf("%d byte message, at most %d allowed", len(data), maxMessageSizes[class])
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("malformed message: %w", err)
+	}
+	if err := checkEnvelope(class, &env, author); err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	checks := n.checks[env.Type]
+	n.mu.Unlock()
+	for _, check := range checks {
+		if err := check(&env); err != nil {
+			return nil, fmt.Errorf("%s message: %w", env.Type, err)
+		}
+	}
+	return &env, nil
+}
+
+// Topic validator of a class, gossipsub only delivers and relays the messages it accepts and
+// penalizes the peers that sent the ones it rejects
+func (n *Node) validateMessage(class string) pubsub.ValidatorEx {
+	return func(_ context.Context, from peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+		// Published by this node, nothing to check
+		if from == n.Host.ID() {
+			return pubsub.ValidationAccept
+		}
+
+		env, err := n.checkMessage(class, msg.Data, msg.GetFrom())
+		if errors.Is(err, ErrStaleMessage) {
+			return pubsub.ValidationIgnore
+		}
+		if err != nil {
+			log.Printf("Rejected message on topic %s from %s: %v", class, from, err)
+			return pubsub.ValidationReject
+		}
+
+		// Handed to dispatch, so the message isn't decoded twice
+		msg.ValidatorData = env
+		return pubsub.ValidationAccept
+	}
+}
+
+// Scoring of the peers delivering messages on the topics of the network, only invalid messages count
+func peerScoreParams(network string) (*pubsub.PeerScoreParams, *pubsub.PeerScoreThresholds) {
+	topics := make(map[string]*pubsub.TopicScoreParams)
+	for _, class := range messageTopics {
+		topics[network+"/"+class] = &pubsub.TopicScoreParams{
+			SkipAtomicValidation:           true,
+			TopicWeight:                    1,
+			InvalidMessageDeliveriesWeight: invalidMessagePenalty,
+			InvalidMessageDeliveriesDecay:  pubsub.ScoreParameterDecay(invalidMessageMemory),
+			TimeInMeshQuantum:              time.Second, // Unused with no weight, but gossipsub divides by it
+		}
+	}
+
+	params := &pubsub.PeerScoreParams{
+		SkipAtomicValidation: true,
+		Topics:               topics,
+		AppSpecificScore:     func(peer.ID) float64 { return 0 },
+		DecayInterval:        pubsub.DefaultDecayInterval,
+		DecayToZero:          pubsub.DefaultDecayToZero,
+		RetainScore:          invalidMessageMemory, // Reconnecting doesn't wipe the slate
+	}
+	thresholds := &pubsub.PeerScoreThresholds{
+		GossipThreshold:   gossipThreshold,
+		PublishThreshold:  publishThreshold,
+		GraylistThreshold: graylistThreshold,
+	}
+	return params, thresholds
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func TestGossipValidation(t *testing.T) {
+	key, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// What a peer would publish
+	message := func(msgType string, payload any) []byte {
+		data, _ := json.Marshal(payload)
+		env := Envelope{Version: ProtocolVersion, Type: msgType, Sender: sender, Timestamp: time.Now().Unix(), Payload: data}
+		if err := env.sign(key); err != nil {
+			t.Fatal(err)
+		}
+		data, _ = json.Marshal(env)
+		return data
+	}
+
+	validatorKey, validatorPub := GenerateKeyPair()
+	bc := newTestBlockchain(t)
+	if err := bc.InitGenesis(&Genesis{Validators: []ValidatorInfo{{ID: 0, PublicKey: validatorPub}}}); err != nil {
+		t.Fatal(err)
+	}
+	consensus, err := NewConsensus(ConsensusVote, ConsensusConfig{PrivateKey: validatorKey, Blockchain: bc, Mempool: NewMempool(bc, MempoolConfig{})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The checks a validator registers
+	n := &Node{checks: make(map[string][]MessageCheck)}
+	n.Check(MessageTransaction, func(env *Envelope) error { return checkGossipedTransaction(bc, env) })
+	n.Check(MessageConsensus, func(env *Envelope) error { return consensus.Validate(env.Payload) })
+	n.Check(MessageBlock, func(env *Envelope) error { return checkBlockUpdate(bc, env) })
+	n.Check(MessageReceipt, func(env *Envelope) error { return checkReceipt(bc, env) })
+
+	privKey, pubKey := GenerateKeyPair()
+	committed := sign(bc, LicenseTransaction{Owner: pubKey, AssetHash: "asset-gossip", License: "view", TxType: TxTypeUpload}, privKey)
+	commit(t, bc, committed)
+	pending := signedUpload(privKey, pubKey, "asset-pending", committed.Nonce+1)
+	tampered := pending
+	tampered.AssetHash = "asset-other"
+
+	vote := VoteMessage{TxID: pending.TxID, ValidatorID: 0, Timestamp: time.Now().Unix(), Approved: true}
+	vote.Signature = SignVote(validatorKey, &vote)
+	forged := vote
+	forged.Approved = false
+	unknown := VoteMessage{TxID: pending.TxID, ValidatorID: 7, Timestamp: vote.Timestamp, Approved: true}
+	unknown.Signature = SignVote(validatorKey, &unknown)
+
+	// Block updates as validator 0 announces them, certified by its precommit if certify is set
+	update := func(block *Block, certify bool) BlockUpdate {
+		if certify {
+			precommit := BFTMessage{Type: BFTPrecommit, Height: block.Index, ValidatorID: 0, BlockHash: block.Hash}
+			precommit.Signature = SignBFTMessage(validatorKey, &precommit)
+			block.Certificate = &CommitCertificate{Height: block.Index, BlockHash: block.Hash, Precommits: []BFTMessage{precommit}}
+		}
+		update := BlockUpdate{Block: block, ValidatorID: 0}
+		update.Signature = SignBlockUpdate(validatorKey, &update)
+		return update
+	}
+	next := CreateBlock(*bc.tip(), []LicenseTransaction{pending})
+	uncertified, known := *next, *bc.tip()
+	fork := CreateBlock(Block{Index: bc.tip().Index, Hash: "other-tip"}, []LicenseTransaction{pending})
+
+	// Receipts as validator 0 gossips them, or someone else with its ID
+	receipt := func(key *ecdsa.PrivateKey, tx LicenseTransaction, status string, height int) SignedReceipt {
+		receipt := SignedReceipt{Receipt: Receipt{TxID: tx.TxID, Status: status, BlockIndex: height}, ValidatorID: 0}
+		receipt.Signature = SignReceipt(key, &receipt)
+		return receipt
+	}
+	height, _ := bc.FindTransaction(committed.TxID)
+
+	tests := []struct {
+		name  string
+		class string
+		data  []byte
+		want  string // accept, stale or reject
+	}{
+		{"pending transaction", TransactionTopic, message(MessageTransaction, pending), "accept"},
+		{"signed vote", VoteTopic, message(MessageConsensus, voteConsensusMessage{Votes: []VoteMessage{vote}}), "accept"},
+		{"oversized", TransactionTopic, message(MessageTransaction, strings.Repeat("x", maxMessageSizes[TransactionTopic])), "reject"},
+		{"malformed", TransactionTopic, []byte(`{"version":`), "reject"},
+		{"tampered transaction", TransactionTopic, message(MessageTransaction, tampered), "reject"},
+		{"forged vote", VoteTopic, message(MessageConsensus, voteConsensusMessage{Votes: []VoteMessage{vote, forged}}), "reject"},
+		{"empty consensus message", VoteTopic, message(MessageConsensus, voteConsensusMessage{}), "reject"},
+		{"signed receipt", ReceiptTopic, message(MessageReceipt, receipt(validatorKey, pending, TxStatusRejected, 0)), "accept"},
+		{"certified block", BlockTopic, message(MessageBlock, update(next, true)), "accept"},
+		{"uncertified block", BlockTopic, message(MessageBlock, update(&uncertified, false)), "reject"},
+		{"block on another chain", BlockTopic, message(MessageBlock, update(fork, true)), "reject"},
+		{"forged receipt", ReceiptTopic, message(MessageReceipt, receipt(privKey, pending, TxStatusRejected, 0)), "reject"},
+		{"receipt including in a known block", ReceiptTopic, message(MessageReceipt, receipt(validatorKey, pending, TxStatusIncluded, height)), "reject"},
+		{"committed transaction", TransactionTopic, message(MessageTransaction, committed), "stale"},
+		{"vote of unknown validator", VoteTopic, message(MessageConsensus, voteConsensusMessage{Votes: []VoteMessage{unknown}}), "stale"},
+		{"known block", BlockTopic, message(MessageBlock, update(&known, true)), "stale"},
+		{"receipt rejecting a committed transaction", ReceiptTopic, message(MessageReceipt, receipt(validatorKey, committed, TxStatusRejected, 0)), "stale"},
+	}
+	for _, test := range tests {
+		env, err := n.checkMessage(test.class, test.data, sender)
+		got := "reject"
+		switch {
+		case err == nil:
+			got = "accept"
+			if env == nil {
+				t.Errorf("%s: accepted without its envelope", test.name)
+			}
+		case errors.Is(err, ErrStaleMessage):
+			got = "stale"
+		}
+		if got != test.want {
+			t.Errorf("%s: got %s (%v), want %s", test.name, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+)
+
+// Kinds of governance proposals
+const (
+	ProposalAddValidator    = "add-validator"
+	ProposalRemoveValidator = "remove-validator"
+	ProposalSetParams       = "set-params"
+	ProposalTakedown        = "takedown" // Made by a takedown transaction, applies as soon as it passes
+)
+
+// Status of a proposal, derived from the chain
+const (
+	ProposalPending  = "pending"  // Collecting votes
+	ProposalPassed   = "passed"   // Reached quorum, waiting for its activation height
+	ProposalActive   = "active"   // Applied to the validator set
+	ProposalRejected = "rejected" // Can no longer reach quorum
+	ProposalExpired  = "expired"  // Reached its activation height without quorum
+)
+
+// Proposal is stored in the Metadata of a gov-propose transaction
+type Proposal struct {
+	Kind             string
+	Validator        *ValidatorInfo   `json:",omitempty"` // Validator to add or remove
+	Params           *ConsensusParams `json:",omitempty"` // Non-zero fields replace the current ones
+	AssetHash        string           `json:",omitempty"` // Asset to take down
+	ActivationHeight int              // Block height from which the change applies, 0 for takedowns
+}
+
+// ProposalVote is stored in the Metadata of a gov-vote transaction
+type ProposalVote struct {
+	ProposalID string
+	Approve    bool
+}
+
+// ProposalStatus is a proposal with its votes, as seen by the chain
+type ProposalStatus struct {
+	ID        string
+	Proposer  int // Validator ID of the proposer
+	Height    int // Height of the block including the proposal
+	Proposal  Proposal
+	Approvals []int // Validator IDs
+	Rejects   []int
+	Quorum    int // Approvals needed, from the validator set at Height
+	Status    string
+}
+
+func NewProposalMetadata(proposal Proposal) string {
+	data, _ := json.Marshal(proposal)
+	return string(data)
+}
+
+func NewProposalVoteMetadata(vote ProposalVote) string {
+	data, _ := json.Marshal(vote)
+	return string(data)
+}
+
+func parseProposal(tx LicenseTransaction) (*Proposal, error) {
+	var proposal Proposal
+	if err := json.Unmarshal([]byte(tx.Metadata), &proposal); err != nil {
+		return nil, fmt.Errorf("invalid proposal: %w", err)
+	}
+	return &proposal, nil
+}
+
+func parseProposalVote(tx LicenseTransaction) (*ProposalVote, error) {
+	var vote ProposalVote
+	if err := json.Unmarshal([]byte(tx.Metadata), &vote); err != nil {
+		return nil, fmt.Errorf("invalid proposal vote: %w", err)
+	}
+	return &vote, nil
+}
+
+// Apply the proposal to a set, returning the resulting one
+func (p *Proposal) apply(set *ValidatorSet) (*ValidatorSet, error) {
+	members, params := set.Members(), set.Params()
+
+	switch p.Kind {
+	case ProposalAddValidator:
+		if p.Validator == nil || len(p.Validator.PublicKey) != 128 {
+			return nil, errors.New("proposal needs a validator ID and public key")
+		}
+		if _, ok := set.Key(p.Validator.ID); ok || set.HasKey(p.Validator.PublicKey) {
+			return nil, fmt.Errorf("validator %d is already in the set", p.Validator.ID)
+		}
+		members = append(members, *p.Validator)
+	case ProposalRemoveValidator:
+		if p.Validator == nil {
+			return nil, errors.New("proposal needs a validator ID")
+		}
+		if _, ok := set.Key(p.Validator.ID); !ok {
+			return nil, fmt.Errorf("validator %d isn't in the set", p.Validator.ID)
+		}
+		kept := members[:0]
+		for _, member := range members {
+			if member.ID != p.Validator.ID {
+				kept = append(kept, member)
+			}
+		}
+		members = kept
+		if len(members) == 0 {
+			return nil, errors.New("can't remove the last validator")
+		}
+	case ProposalSetParams:
+		if p.Params == nil {
+			return nil, errors.New("proposal needs parameters")
+		}
+		if p.Params.Quorum != 0 {
+			params.Quorum = p.Params.Quorum
+		}
+		if p.Params.BlockSize != 0 {
+			params.BlockSize = p.Params.BlockSize
+		}
+		if p.Params.TimeoutMs != 0 {
+			params.TimeoutMs = p.Params.TimeoutMs
+		}
+		if p.Params.JailBlocks != 0 {
+			params.JailBlocks = p.Params.JailBlocks
+		}
+	default:
+		return nil, fmt.Errorf("unknown proposal kind %q", p.Kind)
+	}
+
+	// A quorum that no longer fits the set size falls back to the default, while one being set
+	// has to be safe for the set
+	if p.Kind != ProposalSetParams && !quorumFits(params.Quorum, len(members), params.Consensus) {
+		params.Quorum = 0
+	}
+	return NewValidatorSet(members, params)
+}
+
+// governance is the validator set and the proposals and penalties changing it, as of a height
+type governance struct {
+	set       *ValidatorSet
+	proposals map[string]*ProposalStatus
+	order     []string // Proposal IDs in inclusion order
+
+	// Penalties from evidence transactions, jailed and tombstoned validators stay in set
+	jailedUntil map[int]int // Validator ID -> height from which it is active again
+	tombstoned  map[int]bool
+	slashes     map[int][]SlashRecord
+	punished    map[string]bool // Evidence IDs
+}
+
+func newGovernance(genesis *Genesis) (*governance, error) {
+	set, err := genesis.ValidatorSet()
+	if err != nil {
+		return nil, err
+	}
+	return &governance{
+		set:         set,
+		proposals:   make(map[string]*ProposalStatus),
+		jailedUntil: make(map[int]int),
+		tombstoned:  make(map[int]bool),
+		slashes:     make(map[int][]SlashRecord),
+		punished:    make(map[string]bool),
+	}, nil
+}
+
+// Apply the passed proposals that activate at height, in inclusion order
+func (g *governance) activate(height int) {
+	for _, id := range g.order {
+		status := g.proposals[id]
+		if status.Proposal.ActivationHeight != height || status.Status == ProposalActive {
+			continue
+		}
+		if status.Status != ProposalPassed {
+			if status.Status == ProposalPending {
+				status.Status = ProposalExpired
+			}
+			continue
+		}
+
+		set, err := status.Proposal.apply(g.set)
+		if err != nil {
+			// Another proposal activated first made this one impossible
+			log.Printf("Proposal %s can't be applied: %v", id, err)
+			status.Status = ProposalExpired
+			continue
+		}
+		g.set = set
+		status.Status = ProposalActive
+	}
+}
+
+// Check a governance, takedown or evidence transaction against the state before it
+func (g *governance) validate(tx LicenseTransaction, height int) error {
+	if tx.TxType == TxTypeEvidence {
+		_, err := g.validateEvidence(tx)
+		return err
+	}
+
+	signer, ok := g.memberID(tx.Owner)
+	if !ok {
+		return errors.New("governance transactions must be signed by a validator")
+	}
+
+	switch tx.TxType {
+	case TxTypeGovPropose:
+		proposal, err := parseProposal(tx)
+		if err != nil {
+			return err
+		}
+		if proposal.ActivationHeight <= height {
+			return fmt.Errorf("activation height %d must be above the current height %d", proposal.ActivationHeight, height)
+		}
+		if _, err := proposal.apply(g.set); err != nil {
+			return err
+		}
+	case TxTypeTakedown:
+		if g.takedown(tx.AssetHash) != nil {
+			return fmt.Errorf("asset %s already has a takedown", tx.AssetHash)
+		}
+	case TxTypeGovVote:
+		vote, err := parseProposalVote(tx)
+		if err != nil {
+			return err
+		}
+		status, ok := g.proposals[vote.ProposalID]
+		if !ok {
+			return fmt.Errorf("unknown proposal %s", vote.ProposalID)
+		}
+		if status.Status != ProposalPending {
+			return fmt.Errorf("proposal %s is %s", vote.ProposalID, status.Status)
+		}
+		if slices.Contains(status.Approvals, signer) || slices.Contains(status.Rejects, signer) {
+			return fmt.Errorf("validator %d already voted on proposal %s", signer, vote.ProposalID)
+		}
+	}
+	return nil
+}
+
+// Record a governance transaction included at height
+func (g *governance) record(tx LicenseTransaction, height int) {
+	if g.validate(tx, height) != nil {
+		return
+	}
+	voter, _ := g.memberID(tx.Owner)
+
+	switch tx.TxType {
+	case TxTypeGovPropose:
+		proposal, _ := parseProposal(tx)
+		// Proposing counts as the proposer's approval
+		g.proposals[tx.TxID] = &ProposalStatus{
+			ID:        tx.TxID,
+			Proposer:  voter,
+			Height:    height,
+			Proposal:  *proposal,
+			Approvals: []int{voter},
+			Quorum:    g.set.Quorum(),
+			Status:    ProposalPending,
+		}
+		g.order = append(g.order, tx.TxID)
+		g.tally(g.proposals[tx.TxID])
+	case TxTypeTakedown:
+		g.proposals[tx.TxID] = &ProposalStatus{
+			ID:        tx.TxID,
+			Proposer:  voter,
+			Height:    height,
+			Proposal:  Proposal{Kind: ProposalTakedown, AssetHash: tx.AssetHash},
+			Approvals: []int{voter},
+			Quorum:    g.set.Quorum(),
+			Status:    ProposalPending,
+		}
+		g.order = append(g.order, tx.TxID)
+		g.tally(g.proposals[tx.TxID])
+	case TxTypeGovVote:
+		vote, _ := parseProposalVote(tx)
+		status := g.proposals[vote.ProposalID]
+		if vote.Approve {
+			status.Approvals = append(status.Approvals, voter)
+		} else {
+			status.Rejects = append(status.Rejects, voter)
+		}
+		g.tally(status)
+	}
+}
+
+func (g *governance) tally(status *ProposalStatus) {
+	switch {
+	case len(status.Approvals) >= status.Quorum && status.Proposal.Kind == ProposalTakedown:
+		status.Status = ProposalActive
+	case len(status.Approvals) >= status.Quorum:
+		status.Status = ProposalPassed
+	case len(status.Rejects) > g.set.Size()-status.Quorum:
+		status.Status = ProposalRejected
+	}
+}
+
+// Takedown proposal of an asset that is pending or took effect, nil if there is none
+func (g *governance) takedown(assetHash string) *ProposalStatus {
+	for _, id := range g.order {
+		status := g.proposals[id]
+		if status.Proposal.Kind == ProposalTakedown && status.Proposal.AssetHash == assetHash &&
+			(status.Status == ProposalPending || status.Status == ProposalActive) {
+			return status
+		}
+	}
+	return nil
+}
+
+func (g *governance) memberID(pubKey string) (int, bool) {
+	for _, member := range g.set.members {
+		if member.PublicKey == pubKey {
+			return member.ID, true
+		}
+	}
+	return 0, false
+}
+
+// Replay the governance transactions of blocks on top of the genesis, done once when the chain
+// is loaded, appended blocks update the state as they come
+func replayGovernance(genesis *Genesis, blocks []*Block) (*governance, error) {
+	g, err := newGovernance(genesis)
+	if err != nil {
+		return nil, err
+	}
+	for height, block := range blocks {
+		g.addBlock(height, block)
+	}
+	return g, nil
+}
+
+// Record the governance transactions of the block at height, then apply the proposals
+// activating at the next one
+func (g *governance) addBlock(height int, block *Block) {
+	for _, tx := range block.Transaction {
+		switch tx.TxType {
+		case TxTypeGovPropose, TxTypeGovVote, TxTypeTakedown:
+			g.record(tx, height)
+		case TxTypeEvidence:
+			g.slash(tx, height)
+		}
+	}
+	g.activate(height + 1)
+}
+
+// Governance state as of the next block, bc.mu must be held
+func (bc *Blockchain) governance() (*governance, error) {
+	if bc.gov == nil {
+		return nil, errors.New("chain has no genesis")
+	}
+	return bc.gov, nil
+}
+
+// Validators returns the validator set deciding on the next block, without the jailed and
+// tombstoned validators, nil if the chain has no genesis
+func (bc *Blockchain) Validators() *ValidatorSet {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	// Blocks are only ever appended, so the set can't change without the height changing
+	height := len(bc.Blocks)
+	if bc.validators != nil && bc.validatorsHeight == height {
+		return bc.validators
+	}
+
+	g, err := bc.governance()
+	if err != nil {
+		return nil
+	}
+	bc.validators, bc.validatorsHeight = g.activeSet(height), height
+	return bc.validators
+}
+
+// Proposals returns all governance proposals with their current status, oldest first
+func (bc *Blockchain) Proposals() ([]ProposalStatus, error) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	g, err := bc.governance()
+	if err != nil {
+		return nil, err
+	}
+
+	proposals := make([]ProposalStatus, 0, len(g.order))
+	for _, id := range g.order {
+		proposals = append(proposals, *g.proposals[id])
+	}
+	return proposals, nil
+}
+
+// TakenDown reports whether a quorum of validators approved taking down an asset
+func (bc *Blockchain) TakenDown(assetHash string) bool {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	g, err := bc.governance()
+	if err != nil {
+		return false
+	}
+	status := g.takedown(assetHash)
+	return status != nil && status.Status == ProposalActive
+}
+
+// Check a governance, takedown or evidence transaction against the current chain state
+func validateGovernanceTransaction(tx LicenseTransaction, bc *Blockchain) bool {
+	bc.mu.Lock()
+	g, err := bc.governance()
+	if err == nil {
+		err = g.validate(tx, len(bc.Blocks))
+	}
+	bc.mu.Unlock()
+
+	if err != nil {
+		log.Printf("%s transaction %s rejected: %v", tx.TxType, tx.TxID, err)
+		return false
+	}
+	return true
+}
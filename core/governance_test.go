@@ -0,0 +1,209 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	storage "github.com/Saumya40-codes/DeSecure/pkg"
+)
+
+func TestGovernanceAddsValidatorAtActivationHeight(t *testing.T) {
+	bc := newTestBlockchain(t)
+	keys, members := newTestValidators(t, 4)
+	if err := bc.InitGenesis(&Genesis{Validators: members}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, newKey := GenerateKeyPair()
+	activation := len(bc.Blocks) + 4
+	proposal := Proposal{Kind: ProposalAddValidator, Validator: &ValidatorInfo{ID: 4, PublicKey: newKey}, ActivationHeight: activation}
+
+	outsider, outsiderKey := GenerateKeyPair()
+	if RegisterLicense(sign(bc, LicenseTransaction{Owner: outsiderKey, Metadata: NewProposalMetadata(proposal), TxType: TxTypeGovPropose}, outsider), bc) {
+		t.Fatal("Proposal from a non-validator was accepted")
+	}
+
+	unsafe := Proposal{Kind: ProposalSetParams, Params: &ConsensusParams{Quorum: 2}, ActivationHeight: activation}
+	if RegisterLicense(sign(bc, LicenseTransaction{Owner: members[0].PublicKey, Metadata: NewProposalMetadata(unsafe), TxType: TxTypeGovPropose}, keys[0]), bc) {
+		t.Fatal("Proposal lowering the quorum to half the set was accepted")
+	}
+
+	propose := sign(bc, LicenseTransaction{Owner: members[0].PublicKey, Metadata: NewProposalMetadata(proposal), TxType: TxTypeGovPropose}, keys[0])
+	commit(t, bc, propose)
+
+	vote := func(id int, approve bool) LicenseTransaction {
+		return sign(bc, LicenseTransaction{
+			Owner:    members[id].PublicKey,
+			Metadata: NewProposalVoteMetadata(ProposalVote{ProposalID: propose.TxID, Approve: approve}),
+			TxType:   TxTypeGovVote,
+		}, keys[id])
+	}
+
+	commit(t, bc, vote(1, true))
+	if RegisterLicense(vote(1, true), bc) {
+		t.Error("Second vote of the same validator was accepted")
+	}
+	commit(t, bc, vote(2, true))
+
+	proposals, err := bc.Proposals()
+	if err != nil || len(proposals) != 1 || proposals[0].Status != ProposalPassed {
+		t.Fatalf("Expected one passed proposal, got %+v (%v)", proposals, err)
+	}
+	if RegisterLicense(vote(3, true), bc) {
+		t.Error("Vote on a decided proposal was accepted")
+	}
+
+	// The set only changes once the activation height is reached
+	if bc.Validators().Size() != 4 {
+		t.Fatal("Validator added before its activation height")
+	}
+	for len(bc.Blocks) < activation {
+		bc.AddTransaction(LicenseTransaction{TxID: "filler"})
+	}
+
+	validators := bc.Validators()
+	if validators.Size() != 5 || !validators.HasKey(newKey) {
+		t.Errorf("Validator wasn't added at height %d", activation)
+	}
+	if validators.Quorum() != DefaultQuorum(5) {
+		t.Errorf("Quorum should follow the set size, got %d", validators.Quorum())
+	}
+	if proposals, _ := bc.Proposals(); proposals[0].Status != ProposalActive {
+		t.Errorf("Proposal should be active, is %s", proposals[0].Status)
+	}
+}
+
+func TestGovernanceProposalOutcomes(t *testing.T) {
+	dir := t.TempDir()
+	db := storage.OpenDB(dir)
+	bc := NewBlockchain(db)
+	keys, members := newTestValidators(t, 4)
+	if err := bc.InitGenesis(&Genesis{Validators: members}); err != nil {
+		t.Fatal(err)
+	}
+
+	propose := func(proposal Proposal) string {
+		tx := sign(bc, LicenseTransaction{Owner: members[0].PublicKey, Metadata: NewProposalMetadata(proposal), TxType: TxTypeGovPropose}, keys[0])
+		commit(t, bc, tx)
+		return tx.TxID
+	}
+	vote := func(id int, proposalID string, approve bool) {
+		commit(t, bc, sign(bc, LicenseTransaction{
+			Owner:    members[id].PublicKey,
+			Metadata: NewProposalVoteMetadata(ProposalVote{ProposalID: proposalID, Approve: approve}),
+			TxType:   TxTypeGovVote,
+		}, keys[id]))
+	}
+
+	// All four activate at the same height, in the order they were proposed
+	activation := len(bc.Blocks) + 12
+	_, newKey := GenerateKeyPair()
+	remove := propose(Proposal{Kind: ProposalRemoveValidator, Validator: &ValidatorInfo{ID: 3}, ActivationHeight: activation})
+	params := propose(Proposal{Kind: ProposalSetParams, Params: &ConsensusParams{Quorum: 3, BlockSize: 10}, ActivationHeight: activation})
+	rejected := propose(Proposal{Kind: ProposalAddValidator, Validator: &ValidatorInfo{ID: 4, PublicKey: newKey}, ActivationHeight: activation})
+	expired := propose(Proposal{Kind: ProposalAddValidator, Validator: &ValidatorInfo{ID: 5, PublicKey: newKey}, ActivationHeight: activation})
+
+	vote(1, remove, true)
+	vote(2, remove, true)
+	vote(1, params, true)
+	vote(3, params, true)
+	// Two rejections out of four leave too few validators for a quorum of three
+	vote(1, rejected, false)
+	vote(2, rejected, false)
+	// expired only has the approval of its proposer
+
+	status := func() map[string]string {
+		proposals, err := bc.Proposals()
+		if err != nil {
+			t.Fatal(err)
+		}
+		statuses := make(map[string]string)
+		for _, proposal := range proposals {
+			statuses[proposal.ID] = proposal.Status
+		}
+		return statuses
+	}
+	want := map[string]string{remove: ProposalPassed, params: ProposalPassed, rejected: ProposalRejected, expired: ProposalPending}
+	for id, got := range status() {
+		if got != want[id] {
+			t.Errorf("Before activation, proposal %s is %s, expected %s", id, got, want[id])
+		}
+	}
+
+	for len(bc.Blocks) < activation {
+		bc.AddTransaction(LicenseTransaction{TxID: "filler"})
+	}
+
+	want = map[string]string{remove: ProposalActive, params: ProposalActive, rejected: ProposalRejected, expired: ProposalExpired}
+	for id, got := range status() {
+		if got != want[id] {
+			t.Errorf("At activation, proposal %s is %s, expected %s", id, got, want[id])
+		}
+	}
+	validators := bc.Validators()
+	if _, ok := validators.Key(3); ok || validators.Size() != 3 {
+		t.Errorf("Validator 3 wasn't removed, %d validators", validators.Size())
+	}
+	if validators.Quorum() != 3 || validators.Params().BlockSize != 10 {
+		t.Errorf("Parameters weren't applied: %+v, quorum %d", validators.Params(), validators.Quorum())
+	}
+
+	// Replaying the stored chain arrives at the state kept up to date block by block
+	statuses := status()
+	db.CloseDB()
+	db = storage.OpenDB(dir)
+	defer db.CloseDB()
+	bc = NewBlockchain(db)
+	if reloaded := bc.Validators(); reloaded.Size() != 3 || reloaded.Quorum() != 3 || reloaded.Params().BlockSize != 10 {
+		t.Errorf("Reloaded chain has %d validators with quorum %d", reloaded.Size(), reloaded.Quorum())
+	}
+	for id, got := range status() {
+		if got != statuses[id] {
+			t.Errorf("Reloaded proposal %s is %s, expected %s", id, got, statuses[id])
+		}
+	}
+}
+
+func TestTakedownNeedsValidatorQuorum(t *testing.T) {
+	bc := newTestBlockchain(t)
+	keys, members := newTestValidators(t, 4)
+	if err := bc.InitGenesis(&Genesis{Validators: members}); err != nil {
+		t.Fatal(err)
+	}
+
+	ownerPriv, ownerKey := GenerateKeyPair()
+	commit(t, bc, sign(bc, LicenseTransaction{Owner: ownerKey, AssetHash: "asset-takedown", License: "view", TxType: TxTypeUpload}, ownerPriv))
+
+	takedown := func(key string, privKey *ecdsa.PrivateKey) LicenseTransaction {
+		return sign(bc, LicenseTransaction{Owner: key, AssetHash: "asset-takedown", Metadata: NewTakedownMetadata("court order"), TxType: TxTypeTakedown}, privKey)
+	}
+	if RegisterLicense(takedown(ownerKey, ownerPriv), bc) {
+		t.Fatal("Takedown from a non-validator was accepted")
+	}
+	request := takedown(members[0].PublicKey, keys[0])
+	commit(t, bc, request)
+	if RegisterLicense(takedown(members[1].PublicKey, keys[1]), bc) {
+		t.Error("Second takedown of the same asset was accepted")
+	}
+
+	vote := func(id int) LicenseTransaction {
+		return sign(bc, LicenseTransaction{
+			Owner:    members[id].PublicKey,
+			Metadata: NewProposalVoteMetadata(ProposalVote{ProposalID: request.TxID, Approve: true}),
+			TxType:   TxTypeGovVote,
+		}, keys[id])
+	}
+
+	// 3 of 4 validators have to approve
+	commit(t, bc, vote(1))
+	if status := AssetStatus("asset-takedown", bc); status != AssetActive {
+		t.Fatalf("Asset is %s before the quorum approved the takedown", status)
+	}
+	commit(t, bc, vote(2))
+	if status := AssetStatus("asset-takedown", bc); status != AssetTakenDown {
+		t.Fatalf("Asset is %s after the quorum approved the takedown", status)
+	}
+	if HasValidLicense("asset-takedown", bc, ownerKey) {
+		t.Error("Taken down asset is still served to its owner")
+	}
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// NodeKeyFile holds the libp2p key of a node inside its data directory, separate from the
+// validator key that signs votes, so the peer ID stays the same across restarts
+const NodeKeyFile = "node_key"
+
+// LoadOrCreateNodeKey reads a libp2p private key, creating an Ed25519 one if there is none yet
+func LoadOrCreateNodeKey(path string) (crypto.PrivKey, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := crypto.UnmarshalPrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node key %s: %w", path, err)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read node key: %w", err)
+	}
+
+	key, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate node key: %w", err)
+	}
+	data, err = crypto.MarshalPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode node key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create node key directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write node key: %w", err)
+	}
+	return key, nil
+}
+
+// NodeID is the peer ID other nodes know a node with the key at path by
+func NodeID(path string) (peer.ID, error) {
+	key, err := LoadOrCreateNodeKey(path)
+	if err != nil {
+		return "", err
+	}
+	return peer.IDFromPrivateKey(key)
+}
@@ -0,0 +1,22 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNodeKeyPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db", NodeKeyFile)
+
+	first, err := NodeID(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NodeID(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Errorf("Peer ID changed from %s to %s", first, second)
+	}
+}
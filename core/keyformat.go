@@ -0,0 +1,347 @@
+package core
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Object identifiers used by encrypted PKCS#8 (PBES2, RFC 8018)
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+const (
+	pbkdf2Iterations    = 600000
+	maxPBKDF2Iterations = 10000000 // Keeps an imported file from stalling the import for hours
+)
+
+type encryptedPrivateKeyInfo struct {
+	Algo          pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// JWK is the JSON Web Key (RFC 7517) form of a P-256 key
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	D   string `json:"d,omitempty"`
+}
+
+// MarshalPKCS8PEM encodes a private key as PKCS#8 PEM, encrypted with
+// PBES2 (PBKDF2-SHA256, AES-256-CBC) when a passphrase is given
+func MarshalPKCS8PEM(privKey *ecdsa.PrivateKey, passphrase []byte) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(privKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	if len(passphrase) == 0 {
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	}
+
+	salt := make([]byte, 16)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	key := pbkdf2.Key(passphrase, salt, pbkdf2Iterations, 32, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// PKCS#7 padding
+	padding := aes.BlockSize - len(der)%aes.BlockSize
+	plaintext := append(der, make([]byte, padding)...)
+	for i := len(der); i < len(plaintext); i++ {
+		plaintext[i] = byte(padding)
+	}
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: pbkdf2Iterations,
+		KeyLength:      32,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ivParams, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	schemeParams, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParams}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		Algo:          pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: schemeParams}},
+		EncryptedData: ciphertext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: encrypted}), nil
+}
+
+// ParsePrivateKeyPEM reads a P-256 key from PKCS#8 (plain or PBES2 encrypted) or SEC 1 PEM
+func ParsePrivateKeyPEM(data, passphrase []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		if len(passphrase) == 0 {
+			return nil, errors.New("key is encrypted, a passphrase is required")
+		}
+		var der []byte
+		if der, err = decryptPKCS8(block.Bytes, passphrase); err == nil {
+			key, err = x509.ParsePKCS8PrivateKey(der)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	privKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok || privKey.Curve != elliptic.P256() {
+		return nil, errors.New("only P-256 ECDSA keys are supported")
+	}
+	return privKey, nil
+}
+
+func decryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("invalid encrypted key: %w", err)
+	}
+	if !info.Algo.Algorithm.Equal(oidPBES2) {
+		return nil, errors.New("only PBES2 encrypted keys are supported")
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algo.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("invalid PBES2 parameters: %w", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, errors.New("only PBKDF2 key derivation is supported")
+	}
+
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, fmt.Errorf("invalid PBKDF2 parameters: %w", err)
+	}
+
+	if kdf.IterationCount < 1 || kdf.IterationCount > maxPBKDF2Iterations {
+		return nil, fmt.Errorf("PBKDF2 iteration count %d out of range, at most %d supported", kdf.IterationCount, maxPBKDF2Iterations)
+	}
+
+	var prf func() hash.Hash
+	switch {
+	case kdf.PRF.Algorithm == nil, kdf.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	default:
+		return nil, fmt.Errorf("unsupported PBKDF2 PRF %v", kdf.PRF.Algorithm)
+	}
+
+	var keyLen int
+	switch {
+	case params.EncryptionScheme.Algorithm.Equal(oidAES128CBC):
+		keyLen = 16
+	case params.EncryptionScheme.Algorithm.Equal(oidAES256CBC):
+		keyLen = 32
+	default:
+		return nil, fmt.Errorf("unsupported cipher %v", params.EncryptionScheme.Algorithm)
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, errors.New("invalid cipher IV")
+	}
+	if len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid encrypted data length")
+	}
+
+	block, err := aes.NewCipher(pbkdf2.Key(passphrase, kdf.Salt, kdf.IterationCount, keyLen, prf))
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, info.EncryptedData)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, ErrWrongPassphrase
+	}
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return nil, ErrWrongPassphrase
+		}
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// MarshalJWK encodes a private key as a JWK, including the private part d
+func MarshalJWK(privKey *ecdsa.PrivateKey) ([]byte, error) {
+	coord := func(n *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(n.FillBytes(make([]byte, 32)))
+	}
+
+	return json.MarshalIndent(JWK{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   coord(privKey.X),
+		Y:   coord(privKey.Y),
+		D:   coord(privKey.D),
+	}, "", "  ")
+}
+
+// ParseJWK reads a P-256 private key from a JWK
+func ParseJWK(data []byte) (*ecdsa.PrivateKey, error) {
+	var jwk JWK
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, fmt.Errorf("invalid JWK: %w", err)
+	}
+	if jwk.Kty != "EC" || jwk.Crv != "P-256" {
+		return nil, fmt.Errorf("unsupported JWK kty %q crv %q, expected EC P-256", jwk.Kty, jwk.Crv)
+	}
+	if jwk.D == "" {
+		return nil, errors.New("JWK has no private part")
+	}
+
+	d, err := base64.RawURLEncoding.DecodeString(jwk.D)
+	if err != nil || len(d) > 32 {
+		return nil, fmt.Errorf("invalid JWK d")
+	}
+	d = append(make([]byte, 32-len(d)), d...)
+
+	privKey, err := privateKeyFromScalar(d)
+	if err != nil {
+		return nil, err
+	}
+
+	// The public coordinates must agree with the private scalar
+	x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+	y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if errX != nil || errY != nil || new(big.Int).SetBytes(x).Cmp(privKey.X) != 0 || new(big.Int).SetBytes(y).Cmp(privKey.Y) != 0 {
+		return nil, errors.New("JWK public key doesn't match its private key")
+	}
+	return privKey, nil
+}
+
+// NewMnemonic returns a fresh 24 word BIP-39 mnemonic
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(256)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// KeyFromMnemonic deterministically derives the P-256 key of a BIP-39
+// mnemonic, using the SLIP-10 master key derivation for NIST P-256
+func KeyFromMnemonic(mnemonic, passphrase string) (*ecdsa.PrivateKey, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mnemonic: %w", err)
+	}
+	return masterKeyFromSeed(seed), nil
+}
+
+// SLIP-10 master key of a seed on NIST P-256
+func masterKeyFromSeed(seed []byte) *ecdsa.PrivateKey {
+	mac := hmac.New(sha512.New, []byte("Nist256p1 seed"))
+	mac.Write(seed)
+	i := mac.Sum(nil)
+
+	// Retry with the previous output in the negligible case the scalar is out of range
+	for {
+		if privKey, err := privateKeyFromScalar(i[:32]); err == nil {
+			return privKey
+		}
+		mac = hmac.New(sha512.New, []byte("Nist256p1 seed"))
+		mac.Write(i)
+		i = mac.Sum(nil)
+	}
+}
+
+func privateKeyFromScalar(d []byte) (*ecdsa.PrivateKey, error) {
+	if len(d) != 32 {
+		return nil, errors.New("private scalar must be 32 bytes")
+	}
+
+	// ecdh rejects zero and out of range scalars and gives us the public point
+	ecdhKey, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private scalar: %w", err)
+	}
+	point := ecdhKey.PublicKey().Bytes() // 0x04 || X || Y
+
+	privKey := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(d)}
+	privKey.Curve = elliptic.P256()
+	privKey.X = new(big.Int).SetBytes(point[1:33])
+	privKey.Y = new(big.Int).SetBytes(point[33:])
+	return privKey, nil
+}
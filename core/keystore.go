@@ -0,0 +1,208 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+const KeystoreVersion = 1
+
+// scrypt cost parameters for newly encrypted keys
+const (
+	scryptN     = 1 << 15
+	scryptR     = 8
+	scryptP     = 1
+	scryptDKLen = 64 // 32 bytes for the cipher key, 32 for the MAC key
+)
+
+// Bounds on the scrypt parameters of keystores being read, so an edited file can neither weaken
+// the key derivation nor make it take all the memory of the machine
+const (
+	minScryptN      = 1 << 14
+	maxScryptMemory = 256 << 20 // scrypt uses 128*N*r bytes
+	maxScryptP      = 16
+	minSaltLen      = 16
+)
+
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted keystore")
+
+// Keystore is the on-disk, passphrase protected form of a private key
+type Keystore struct {
+	Version   int            `json:"version"`
+	PublicKey string         `json:"public_key"`
+	Crypto    KeystoreCrypto `json:"crypto"`
+}
+
+type KeystoreCrypto struct {
+	Cipher     string    `json:"cipher"`
+	Ciphertext string    `json:"ciphertext"`
+	Nonce      string    `json:"nonce"`
+	KDF        string    `json:"kdf"`
+	KDFParams  KDFParams `json:"kdfparams"`
+	MAC        string    `json:"mac"`
+}
+
+type KDFParams struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+}
+
+// PublicKeyHex encodes a public key the way it is used as an identity on chain
+func PublicKeyHex(pub *ecdsa.PublicKey) string {
+	buf := make([]byte, 64)
+	pub.X.FillBytes(buf[:32])
+	pub.Y.FillBytes(buf[32:])
+	return hex.EncodeToString(buf)
+}
+
+// LegacyPublicKeyHex encodes a public key the way older versions did, without padding the
+// coordinates. It differs from PublicKeyHex when a coordinate starts with a zero byte.
+func LegacyPublicKeyHex(pub *ecdsa.PublicKey) string {
+	return hex.EncodeToString(append(pub.X.Bytes(), pub.Y.Bytes()...))
+}
+
+// EncryptKey seals privKey with a key derived from passphrase
+func EncryptKey(privKey *ecdsa.PrivateKey, passphrase []byte) (*Keystore, error) {
+	privBytes, err := x509.MarshalECPrivateKey(privKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	params := KDFParams{N: scryptN, R: scryptR, P: scryptP, DKLen: scryptDKLen, Salt: hex.EncodeToString(salt)}
+	encKey, macKey, err := deriveKeys(passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := secretbox.Seal(nil, privBytes, &nonce, encKey)
+
+	return &Keystore{
+		Version:   KeystoreVersion,
+		PublicKey: PublicKeyHex(&privKey.PublicKey),
+		Crypto: KeystoreCrypto{
+			Cipher:     "nacl-secretbox",
+			Ciphertext: hex.EncodeToString(ciphertext),
+			Nonce:      hex.EncodeToString(nonce[:]),
+			KDF:        "scrypt",
+			KDFParams:  params,
+			MAC:        hex.EncodeToString(keystoreMAC(macKey, nonce[:], ciphertext)),
+		},
+	}, nil
+}
+
+// DecryptKey opens a keystore, returning ErrWrongPassphrase if the MAC doesn't match
+func DecryptKey(ks *Keystore, passphrase []byte) (*ecdsa.PrivateKey, error) {
+	if ks.Version != KeystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version %d", ks.Version)
+	}
+	if ks.Crypto.KDF != "scrypt" || ks.Crypto.Cipher != "nacl-secretbox" {
+		return nil, fmt.Errorf("unsupported keystore kdf %q or cipher %q", ks.Crypto.KDF, ks.Crypto.Cipher)
+	}
+
+	ciphertext, err := hex.DecodeString(ks.Crypto.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore ciphertext: %w", err)
+	}
+	nonceBytes, err := hex.DecodeString(ks.Crypto.Nonce)
+	if err != nil || len(nonceBytes) != 24 {
+		return nil, fmt.Errorf("invalid keystore nonce")
+	}
+	mac, err := hex.DecodeString(ks.Crypto.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore mac: %w", err)
+	}
+
+	if err := ks.Crypto.KDFParams.check(); err != nil {
+		return nil, err
+	}
+	encKey, macKey, err := deriveKeys(passphrase, ks.Crypto.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal(mac, keystoreMAC(macKey, nonceBytes, ciphertext)) {
+		return nil, ErrWrongPassphrase
+	}
+
+	var nonce [24]byte
+	copy(nonce[:], nonceBytes)
+	privBytes, ok := secretbox.Open(nil, ciphertext, &nonce, encKey)
+	if !ok {
+		return nil, ErrWrongPassphrase
+	}
+
+	privKey, err := x509.ParseECPrivateKey(privBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	if PublicKeyHex(&privKey.PublicKey) != ks.PublicKey {
+		return nil, fmt.Errorf("keystore public key doesn't match its private key")
+	}
+	return privKey, nil
+}
+
+// Reject scrypt parameters outside the bounds above
+func (params KDFParams) check() error {
+	if params.N < minScryptN || params.N&(params.N-1) != 0 {
+		return fmt.Errorf("invalid scrypt cost N=%d, must be a power of two of at least %d", params.N, minScryptN)
+	}
+	if params.R < 1 || params.P < 1 || params.P > maxScryptP {
+		return fmt.Errorf("invalid scrypt parameters r=%d p=%d", params.R, params.P)
+	}
+	if 128*int64(params.N)*int64(params.R) > maxScryptMemory {
+		return fmt.Errorf("scrypt parameters N=%d r=%d need more than %d MiB", params.N, params.R, maxScryptMemory>>20)
+	}
+	if len(params.Salt) < 2*minSaltLen {
+		return fmt.Errorf("keystore salt shorter than %d bytes", minSaltLen)
+	}
+	return nil
+}
+
+func deriveKeys(passphrase []byte, params KDFParams) (*[32]byte, []byte, error) {
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid keystore salt: %w", err)
+	}
+	if params.DKLen != scryptDKLen {
+		return nil, nil, fmt.Errorf("unsupported derived key length %d", params.DKLen)
+	}
+
+	derived, err := scrypt.Key(passphrase, salt, params.N, params.R, params.P, params.DKLen)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	var encKey [32]byte
+	copy(encKey[:], derived[:32])
+	return &encKey, derived[32:], nil
+}
+
+func keystoreMAC(macKey, nonce, ciphertext []byte) []byte {
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(nonce)
+	mac.Write(ciphertext)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKeystoreRoundTrip(t *testing.T) {
+	privKey, pubKey := GenerateKeyPair()
+
+	ks, err := EncryptKey(privKey, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("Failed to encrypt key: %v", err)
+	}
+	if ks.PublicKey != pubKey {
+		t.Errorf("Keystore public key %s, expected %s", ks.PublicKey, pubKey)
+	}
+
+	decrypted, err := DecryptKey(ks, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("Failed to decrypt key: %v", err)
+	}
+	if !decrypted.Equal(privKey) {
+		t.Error("Decrypted key doesn't match the original")
+	}
+
+	if _, err := DecryptKey(ks, []byte("battery staple")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Expected ErrWrongPassphrase, got %v", err)
+	}
+
+	// Two encryptions of the same key must not share salt or nonce
+	other, err := EncryptKey(privKey, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("Failed to encrypt key: %v", err)
+	}
+	if other.Crypto.Nonce == ks.Crypto.Nonce || other.Crypto.KDFParams.Salt == ks.Crypto.KDFParams.Salt {
+		t.Error("Expected a fresh nonce and salt for every encryption")
+	}
+
+	// Parameters of an edited keystore are checked before deriving anything
+	for name, params := range map[string]KDFParams{
+		"weak N":     {N: 1 << 10, R: 8, P: 1},
+		"N not 2^k":  {N: 3 << 14, R: 8, P: 1},
+		"huge N":     {N: 1 << 30, R: 8, P: 1},
+		"huge r":     {N: 1 << 15, R: 1 << 20, P: 1},
+		"zero p":     {N: 1 << 15, R: 8, P: 0},
+		"short salt": {N: 1 << 15, R: 8, P: 1, Salt: "00"},
+	} {
+		edited := *ks
+		params.DKLen = scryptDKLen
+		if params.Salt == "" {
+			params.Salt = ks.Crypto.KDFParams.Salt
+		}
+		edited.Crypto.KDFParams = params
+		if _, err := DecryptKey(&edited, []byte("correct horse")); err == nil || errors.Is(err, ErrWrongPassphrase) {
+			t.Errorf("%s: expected invalid parameters, got %v", name, err)
+		}
+	}
+}
+
+func TestLegacyPublicKeyHex(t *testing.T) {
+	privKey, pubKey := GenerateKeyPair()
+	for len(privKey.X.Bytes()) == 32 && len(privKey.Y.Bytes()) == 32 {
+		if LegacyPublicKeyHex(&privKey.PublicKey) != pubKey {
+			t.Fatal("Legacy encoding differs for a key without leading zero bytes")
+		}
+		privKey, pubKey = GenerateKeyPair()
+	}
+
+	// Older versions recorded this key on chain under a shorter identity
+	if legacy := LegacyPublicKeyHex(&privKey.PublicKey); legacy == pubKey || len(legacy) >= len(pubKey) {
+		t.Errorf("Legacy encoding %s of a key with a leading zero coordinate should be shorter than %s", legacy, pubKey)
+	}
+}
@@ -1,51 +1,260 @@
 package core
 
-import "sync"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
 
+// Journal of the pending transactions, in the validator's database
+const MempoolPrefix = "mempool-"
+
+// Limits of a mempool with a zero MempoolConfig
+const (
+	defaultMempoolTxs       = 5000
+	defaultMempoolBytes     = 16 << 20
+	defaultMempoolPerSender = 64
+	defaultMempoolTTL       = 10 * time.Minute
+)
+
+// MempoolConfig limits what a mempool holds, zero values use the defaults
+type MempoolConfig struct {
+	MaxTxs       int   `json:"max_txs,omitempty"`        // Most transactions held at once
+	MaxBytes     int   `json:"max_bytes,omitempty"`      // Most bytes of JSON encoded transactions held at once
+	MaxPerSender int   `json:"max_per_sender,omitempty"` // Most pending transactions of one signer
+	TTLSeconds   int64 `json:"ttl_seconds,omitempty"`    // How long a transaction may wait for a block
+}
+
+// Reasons a mempool doesn't take a transaction that isn't invalid itself
+var (
+	ErrInMempool   = errors.New("already in mempool")
+	ErrMempoolFull = errors.New("mempool is full")
+	ErrSenderLimit = errors.New("sender has too many pending transactions")
+)
+
+// RejectedError is returned for invalid transactions, Reason is one of the Reject* codes
+type RejectedError struct {
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return "transaction rejected: " + e.Reason
+}
+
+// What the journal keeps of a pending transaction
+type mempoolRecord struct {
+	Tx    LicenseTransaction
+	Added int64 // Unix time of admission, the TTL keeps running across restarts
+}
+
+type mempoolEntry struct {
+	tx     LicenseTransaction
+	sender string
+	size   int
+	added  time.Time
+	seq    uint64 // Admission order
+}
+
+// Mempool holds admitted transactions until they are included in a block or expire. The
+// transactions of each signer are queued in nonce order, and readers only ever get copies.
+// Every pending transaction is journaled in the chain's database so restarts don't lose them.
 type Mempool struct {
-	mu           sync.Mutex
-	transactions []LicenseTransaction
+	mu      sync.Mutex
+	bc      *Blockchain
+	cfg     MempoolConfig
+	now     func() time.Time
+	txs     map[string]*mempoolEntry   // TxID -> entry
+	senders map[string][]*mempoolEntry // Signer -> entries sorted by nonce
+	bytes   int
+	seq     uint64
 }
 
-func NewMempool() *Mempool {
+// NewMempool creates a mempool admitting transactions that are valid on top of bc
+func NewMempool(bc *Blockchain, cfg MempoolConfig) *Mempool {
+	if cfg.MaxTxs <= 0 {
+		cfg.MaxTxs = defaultMempoolTxs
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultMempoolBytes
+	}
+	if cfg.MaxPerSender <= 0 {
+		cfg.MaxPerSender = defaultMempoolPerSender
+	}
+	if cfg.TTLSeconds <= 0 {
+		cfg.TTLSeconds = int64(defaultMempoolTTL.Seconds())
+	}
+
 	return &Mempool{
-		mu:           sync.Mutex{},
-		transactions: []LicenseTransaction{},
+		bc:      bc,
+		cfg:     cfg,
+		now:     time.Now,
+		txs:     make(map[string]*mempoolEntry),
+		senders: make(map[string][]*mempoolEntry),
 	}
 }
 
-func (m *Mempool) AddTransaction(tx LicenseTransaction) {
+// AddTransaction admits a transaction that is valid on top of the chain and fits in the limits
+func (m *Mempool) AddTransaction(tx LicenseTransaction) error {
+	return m.add(tx, time.Time{})
+}
+
+// Admit a transaction, added is when it was first admitted if it is restored from the journal
+func (m *Mempool) add(tx LicenseTransaction, added time.Time) error {
+	if m.GetTransactionByID(tx.TxID) != nil {
+		return ErrInMempool
+	}
+
+	// Checked without holding the lock, they go through the whole chain
+	if !ValidateTransaction(tx) {
+		return &RejectedError{Reason: RejectBadSignature}
+	}
+	if height, _ := m.bc.FindTransaction(tx.TxID); height >= 0 {
+		return fmt.Errorf("already included in block #%d", height)
+	}
+	if reason := checkState(tx, m.bc); reason != "" {
+		return &RejectedError{Reason: reason}
+	}
+
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+	entry := &mempoolEntry{tx: tx, sender: TransactionSigner(tx), size: len(data)}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.transactions = append(m.transactions, tx)
+	m.evictExpired()
+	if m.txs[tx.TxID] != nil {
+		return ErrInMempool
+	}
+	queue := m.senders[entry.sender]
+	for _, pending := range queue {
+		if pending.tx.Nonce == tx.Nonce {
+			return &RejectedError{Reason: RejectBadNonce}
+		}
+	}
+	if len(queue) >= m.cfg.MaxPerSender {
+		return ErrSenderLimit
+	}
+	if len(m.txs) >= m.cfg.MaxTxs || m.bytes+entry.size > m.cfg.MaxBytes {
+		return ErrMempoolFull
+	}
+
+	if added.IsZero() {
+		added = m.now()
+	}
+	m.seq++
+	entry.seq, entry.added = m.seq, added
+	i := sort.Search(len(queue), func(i int) bool { return queue[i].tx.Nonce > tx.Nonce })
+	m.senders[entry.sender] = slices.Insert(queue, i, entry)
+	m.txs[tx.TxID] = entry
+	m.bytes += entry.size
+	m.journal(entry)
+	return nil
+}
+
+// Restore reloads the journaled transactions that are still valid on top of the chain, as
+// after a restart, and returns them in admission order so they can be proposed again
+func (m *Mempool) Restore() []LicenseTransaction {
+	values, err := m.bc.db.LoadPrefix(MempoolPrefix)
+	if err != nil {
+		log.Println("Error loading mempool journal:", err)
+		return nil
+	}
+
+	var records []mempoolRecord
+	for key, data := range values {
+		var record mempoolRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			log.Printf("Mempool: dropping unreadable journal entry %s: %v", key, err)
+			m.unjournal(strings.TrimPrefix(key, MempoolPrefix))
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Added != records[j].Added {
+			return records[i].Added < records[j].Added
+		}
+		return records[i].Tx.Nonce < records[j].Tx.Nonce
+	})
+
+	var restored []LicenseTransaction
+	for _, record := range records {
+		// Revalidated like any new transaction, the chain may have moved on while we were down
+		m.unjournal(record.Tx.TxID)
+		if err := m.add(record.Tx, time.Unix(record.Added, 0)); err != nil {
+			log.Printf("Mempool: dropping journaled transaction %s: %v", record.Tx.TxID, err)
+			continue
+		}
+		restored = append(restored, record.Tx)
+	}
+	log.Printf("Mempool: restored %d of %d journaled transactions", len(restored), len(records))
+	return restored
 }
 
+// GetTransactions returns a copy of the pending transactions in admission order, except that
+// the transactions of each signer come in nonce order
 func (m *Mempool) GetTransactions() []LicenseTransaction {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	return m.transactions
+	m.evictExpired()
+	entries := make([]*mempoolEntry, 0, len(m.txs))
+	for _, entry := range m.txs {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+
+	// A signer's k-th admitted transaction takes the place of its k-th lowest nonce
+	taken := make(map[string]int)
+	transactions := make([]LicenseTransaction, len(entries))
+	for i, entry := range entries {
+		transactions[i] = m.senders[entry.sender][taken[entry.sender]].tx
+		taken[entry.sender]++
+	}
+	return transactions
+}
+
+// Size is the number of pending transactions
+func (m *Mempool) Size() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.evictExpired()
+	return len(m.txs)
 }
 
 func (m *Mempool) Clear() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.transactions = []LicenseTransaction{}
+	for txID := range m.txs {
+		m.unjournal(txID)
+	}
+	m.txs = make(map[string]*mempoolEntry)
+	m.senders = make(map[string][]*mempoolEntry)
+	m.bytes = 0
 }
 
-// Get a transaction by ID
+// Get a copy of a transaction by ID
 func (m *Mempool) GetTransactionByID(txID string) *LicenseTransaction {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	for i, tx := range m.transactions {
-		if tx.TxID == txID {
-			return &m.transactions[i]
-		}
+	entry := m.txs[txID]
+	if entry == nil {
+		return nil
 	}
-	return nil
+	tx := entry.tx
+	return &tx
 }
 
 // Remove a transaction by ID
@@ -53,11 +262,49 @@ func (m *Mempool) RemoveTransaction(txID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	for i, tx := range m.transactions {
-		if tx.TxID == txID {
-			// Remove the transaction from the slice
-			m.transactions = append(m.transactions[:i], m.transactions[i+1:]...)
-			return
+	if entry := m.txs[txID]; entry != nil {
+		m.remove(entry)
+	}
+}
+
+func (m *Mempool) remove(entry *mempoolEntry) {
+	delete(m.txs, entry.tx.TxID)
+	m.bytes -= entry.size
+	m.unjournal(entry.tx.TxID)
+
+	queue := slices.DeleteFunc(m.senders[entry.sender], func(e *mempoolEntry) bool { return e == entry })
+	if len(queue) == 0 {
+		delete(m.senders, entry.sender)
+	} else {
+		m.senders[entry.sender] = queue
+	}
+}
+
+// Drop the transactions that waited longer than the TTL
+func (m *Mempool) evictExpired() {
+	cutoff := m.now().Add(-time.Duration(m.cfg.TTLSeconds) * time.Second)
+	for _, entry := range m.txs {
+		if entry.added.Before(cutoff) {
+			log.Printf("Mempool: transaction %s expired after %ds", entry.tx.TxID, m.cfg.TTLSeconds)
+			m.remove(entry)
 		}
 	}
 }
+
+func (m *Mempool) journal(entry *mempoolEntry) {
+	data, err := json.Marshal(mempoolRecord{Tx: entry.tx, Added: entry.added.Unix()})
+	if err != nil {
+		log.Println("Error marshaling mempool entry:", err)
+		return
+	}
+
+	if err := m.bc.db.Save(MempoolPrefix+entry.tx.TxID, data); err != nil {
+		log.Println("Error journaling mempool entry:", err)
+	}
+}
+
+func (m *Mempool) unjournal(txID string) {
+	if err := m.bc.db.Delete(MempoolPrefix + txID); err != nil {
+		log.Println("Error removing mempool entry from the journal:", err)
+	}
+}
@@ -0,0 +1,237 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// MempoolSyncProtocol lets a validator fetch the pending transactions it missed from its peers,
+// after it joined late or when votes arrive for a transaction it never saw
+const MempoolSyncProtocol = "/drm/mempool/1.0.0"
+
+const (
+	maxSyncBatch        = 256     // Most transactions asked for or sent in one request
+	maxSyncRequestSize  = 1 << 20 // Upper bound on an encoded request
+	maxSyncResponseSize = 8 << 20 // Upper bound on an encoded response
+	syncTimeout         = 10 * time.Second
+	syncRetry           = 5 * time.Second // How long before a transaction that wasn't found is asked for again
+)
+
+// MempoolSyncRequest asks for the IDs of all pending transactions, or for some transactions by ID
+type MempoolSyncRequest struct {
+	Digest bool
+	TxIDs  []string `json:",omitempty"`
+}
+
+// MempoolSyncResponse holds the requested digest or the requested transactions the peer has
+type MempoolSyncResponse struct {
+	TxIDs        []string             `json:",omitempty"`
+	Transactions []LicenseTransaction `json:",omitempty"`
+}
+
+// Answer a sync request from the pending transactions
+func (m *Mempool) answerSync(req MempoolSyncRequest) MempoolSyncResponse {
+	var resp MempoolSyncResponse
+	if req.Digest {
+		for _, tx := range m.GetTransactions() {
+			resp.TxIDs = append(resp.TxIDs, tx.TxID)
+		}
+		return resp
+	}
+
+	for _, txID := range req.TxIDs[:min(len(req.TxIDs), maxSyncBatch)] {
+		if tx := m.GetTransactionByID(txID); tx != nil {
+			resp.Transactions = append(resp.Transactions, *tx)
+		}
+	}
+	return resp
+}
+
+// Missing returns the transactions neither pending here nor included in the chain
+func (m *Mempool) Missing(txIDs []string) []string {
+	var missing []string
+	for _, txID := range txIDs {
+		if m.GetTransactionByID(txID) != nil {
+			continue
+		}
+		if height, _ := m.bc.FindTransaction(txID); height >= 0 {
+			continue
+		}
+		missing = append(missing, txID)
+	}
+	return missing
+}
+
+func (v *Validator) handleMempoolSyncStream(s network.Stream) {
+	defer s.Close()
+
+	s.SetDeadline(time.Now().Add(syncTimeout))
+
+	var req MempoolSyncRequest
+	if err := json.NewDecoder(io.LimitReader(s, maxSyncRequestSize)).Decode(&req); err != nil {
+		log.Printf("Validator %d received malformed mempool sync request from %s: %v", v.ID, s.Conn().RemotePeer(), err)
+		s.Reset()
+		return
+	}
+
+	if err := json.NewEncoder(s).Encode(v.Mempool.answerSync(req)); err != nil {
+		log.Printf("Validator %d error answering mempool sync from %s: %v", v.ID, s.Conn().RemotePeer(), err)
+	}
+}
+
+// Send a sync request to a peer and wait for the answer
+func (n *Node) requestMempool(ctx context.Context, p peer.ID, req MempoolSyncRequest) (*MempoolSyncResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+
+	s, err := n.Host.NewStream(ctx, p, MempoolSyncProtocol)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	s.SetDeadline(time.Now().Add(syncTimeout))
+
+	if err := json.NewEncoder(s).Encode(req); err != nil {
+		s.Reset()
+		return nil, fmt.Errorf("failed to send mempool sync request: %w", err)
+	}
+	s.CloseWrite()
+
+	var resp MempoolSyncResponse
+	if err := json.NewDecoder(bufio.NewReader(io.LimitReader(s, maxSyncResponseSize))).Decode(&resp); err != nil {
+		s.Reset()
+		return nil, fmt.Errorf("failed to read mempool sync response: %w", err)
+	}
+	return &resp, nil
+}
+
+// Ask a peer for transactions and submit the ones it has, returning the IDs it didn't have
+func (v *Validator) fetchFrom(ctx context.Context, p peer.ID, txIDs []string) ([]string, error) {
+	var notFound []string
+	for start := 0; start < len(txIDs); start += maxSyncBatch {
+		batch := txIDs[start:min(start+maxSyncBatch, len(txIDs))]
+		resp, err := v.Node.requestMempool(ctx, p, MempoolSyncRequest{TxIDs: batch})
+		if err != nil {
+			return append(notFound, txIDs[start:]...), err
+		}
+
+		found := make(map[string]bool)
+		for _, tx := range resp.Transactions {
+			// Only what was asked for, the pipeline checks them like any other transaction
+			if !slices.Contains(batch, tx.TxID) || found[tx.TxID] {
+				continue
+			}
+			found[tx.TxID] = true
+			if err := v.Pipeline.Submit(ctx, tx); err != nil {
+				return nil, err
+			}
+		}
+		for _, txID := range batch {
+			if !found[txID] {
+				notFound = append(notFound, txID)
+			}
+		}
+	}
+	return notFound, nil
+}
+
+// Catch up with the pending transactions of a peer, as after joining the network
+func (v *Validator) syncMempool(ctx context.Context, p peer.ID) {
+	resp, err := v.Node.requestMempool(ctx, p, MempoolSyncRequest{Digest: true})
+	if err != nil {
+		// Not a validator, or gone already
+		return
+	}
+
+	missing := v.Mempool.Missing(resp.TxIDs)
+	if len(missing) == 0 {
+		return
+	}
+	notFound, err := v.fetchFrom(ctx, p, missing)
+	if err != nil {
+		log.Printf("Validator %d failed to sync mempool with %s: %v", v.ID, p, err)
+	}
+	log.Printf("Validator %d fetched %d pending transactions from %s", v.ID, len(missing)-len(notFound), p)
+}
+
+// Queue a transaction that votes arrived for but isn't in the mempool, to be fetched from peers
+func (v *Validator) requestMissing(txID string) {
+	select {
+	case v.missing <- txID:
+	default:
+		// Asked again by the next vote for it
+	}
+}
+
+// Fetch the transactions queued by requestMissing in batches, asking the peers in turn
+func (v *Validator) fetchMissing(ctx context.Context) {
+	requested := make(map[string]time.Time)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case txID := <-v.missing:
+			batch := []string{txID}
+		drain:
+			for len(batch) < maxSyncBatch {
+				select {
+				case txID := <-v.missing:
+					batch = append(batch, txID)
+				default:
+					break drain
+				}
+			}
+
+			// Every vote for a missing transaction asks for it, only ask peers once in a while
+			now := time.Now()
+			for txID, at := range requested {
+				if now.Sub(at) >= syncRetry {
+					delete(requested, txID)
+				}
+			}
+			var wanted []string
+			for _, txID := range v.Mempool.Missing(batch) {
+				if _, ok := requested[txID]; !ok {
+					requested[txID] = now
+					wanted = append(wanted, txID)
+				}
+			}
+
+			for _, p := range v.Node.Host.Network().Peers() {
+				if len(wanted) == 0 {
+					break
+				}
+				wanted, _ = v.fetchFrom(ctx, p, wanted)
+			}
+			if len(wanted) > 0 {
+				log.Printf("Validator %d: no peer has %d transactions votes arrived for", v.ID, len(wanted))
+			}
+		}
+	}
+}
+
+// Sync the mempool with every validator this one connects to, now and later
+func (v *Validator) startMempoolSync(ctx context.Context) {
+	v.Node.Host.SetStreamHandler(MempoolSyncProtocol, v.handleMempoolSyncStream)
+
+	v.Node.Host.Network().Notify(&network.NotifyBundle{
+		ConnectedF: func(_ network.Network, conn network.Conn) {
+			go v.syncMempool(ctx, conn.RemotePeer())
+		},
+	})
+	for _, p := range v.Node.Host.Network().Peers() {
+		go v.syncMempool(ctx, p)
+	}
+
+	go v.fetchMissing(ctx)
+}
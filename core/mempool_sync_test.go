@@ -0,0 +1,61 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestMempoolSyncDigest(t *testing.T) {
+	bc := newTestBlockchain(t)
+	peer := NewMempool(bc, MempoolConfig{})
+	joined := NewMempool(bc, MempoolConfig{})
+
+	var txs []LicenseTransaction
+	for i := range 3 {
+		privKey, pubKey := GenerateKeyPair()
+		tx := sign(bc, LicenseTransaction{Owner: pubKey, AssetHash: fmt.Sprintf("asset-synced-%d", i), License: "view", TxType: TxTypeUpload}, privKey)
+		if err := peer.AddTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, tx)
+	}
+	if err := joined.AddTransaction(txs[0]); err != nil {
+		t.Fatal(err)
+	}
+	commit(t, bc, txs[1])
+
+	// The joined validator only lacks what is neither pending there nor in the chain
+	digest := peer.answerSync(MempoolSyncRequest{Digest: true})
+	missing := joined.Missing(digest.TxIDs)
+	if len(digest.TxIDs) != 3 || !slices.Equal(missing, []string{txs[2].TxID}) {
+		t.Fatalf("Expected only %s missing out of 3, got %v of %d", txs[2].TxID, missing, len(digest.TxIDs))
+	}
+
+	resp := peer.answerSync(MempoolSyncRequest{TxIDs: append(missing, "unknown")})
+	if len(resp.Transactions) != 1 || resp.Transactions[0].TxID != txs[2].TxID {
+		t.Fatalf("Expected the missing transaction, got %d transactions", len(resp.Transactions))
+	}
+}
+
+func TestVoteConsensusFetchesMissingTransactions(t *testing.T) {
+	net := newConsensusNetwork(t, ConsensusVote, 4)
+
+	// Only validator 0 saw the transactions, the others fetch them when its votes arrive, so the
+	// proposer of a block is often one that had to fetch what it includes
+	var txs []LicenseTransaction
+	for i := range 4 {
+		privKey, pubKey := GenerateKeyPair()
+		tx := sign(net.chains[0], LicenseTransaction{Owner: pubKey, AssetHash: fmt.Sprintf("asset-fetched-%d", i), License: "view", TxType: TxTypeUpload}, privKey)
+		if err := net.mempools[0].AddTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+		net.nodes[0].Propose(tx)
+		txs = append(txs, tx)
+	}
+
+	for _, tx := range txs {
+		net.waitIncluded(t, tx)
+	}
+	net.checkReplicated(t, len(net.chains[0].chain()))
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// An upload signed with a given nonce, for several pending transactions of one sender
+func signedUpload(privKey *ecdsa.PrivateKey, pubKey, asset string, nonce uint64) LicenseTransaction {
+	tx := LicenseTransaction{Owner: pubKey, AssetHash: asset, License: "view", TxType: TxTypeUpload, Timestamp: time.Now().Unix(), Nonce: nonce}
+	tx.TxID = GenerateTransactionID(tx)
+	tx.Signature = SignTransaction(privKey, &tx)
+	return tx
+}
+
+func TestMempoolAdmission(t *testing.T) {
+	bc := newTestBlockchain(t)
+	mempool := NewMempool(bc, MempoolConfig{MaxTxs: 4, MaxPerSender: 3})
+	privKey, pubKey := GenerateKeyPair()
+
+	// Admitted out of order, handed out in nonce order
+	for _, nonce := range []uint64{2, 0, 1} {
+		if err := mempool.AddTransaction(signedUpload(privKey, pubKey, fmt.Sprintf("asset-pending-%d", nonce), nonce)); err != nil {
+			t.Fatalf("Nonce %d was not admitted: %v", nonce, err)
+		}
+	}
+	for i, tx := range mempool.GetTransactions() {
+		if tx.Nonce != uint64(i) {
+			t.Errorf("Transaction %d has nonce %d", i, tx.Nonce)
+		}
+	}
+
+	tampered := signedUpload(privKey, pubKey, "asset-tampered", 5)
+	tampered.License = "download"
+	var rejected *RejectedError
+	tests := []struct {
+		name   string
+		tx     LicenseTransaction
+		reason string
+	}{
+		{"tampered", tampered, RejectBadSignature},
+		{"unsigned", LicenseTransaction{TxID: "garbage", TxType: TxTypeUpload}, RejectBadSignature},
+		{"pending nonce", signedUpload(privKey, pubKey, "asset-same-nonce", 1), RejectBadNonce},
+	}
+	for _, test := range tests {
+		if err := mempool.AddTransaction(test.tx); !errors.As(err, &rejected) || rejected.Reason != test.reason {
+			t.Errorf("%s: expected rejection %q, got %v", test.name, test.reason, err)
+		}
+	}
+
+	if err := mempool.AddTransaction(mempool.GetTransactions()[0]); !errors.Is(err, ErrInMempool) {
+		t.Errorf("Expected a duplicate to be refused, got %v", err)
+	}
+	if err := mempool.AddTransaction(signedUpload(privKey, pubKey, "asset-over-limit", 3)); !errors.Is(err, ErrSenderLimit) {
+		t.Errorf("Expected the sender limit to be hit, got %v", err)
+	}
+
+	otherPriv, otherKey := GenerateKeyPair()
+	if err := mempool.AddTransaction(signedUpload(otherPriv, otherKey, "asset-other-1", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := mempool.AddTransaction(signedUpload(otherPriv, otherKey, "asset-other-2", 1)); !errors.Is(err, ErrMempoolFull) {
+		t.Errorf("Expected the mempool to be full, got %v", err)
+	}
+
+	// Snapshots are copies
+	snapshot := mempool.GetTransactions()
+	snapshot[0].AssetHash = "changed"
+	mempool.GetTransactionByID(snapshot[1].TxID).AssetHash = "changed"
+	for _, tx := range mempool.GetTransactions() {
+		if tx.AssetHash == "changed" {
+			t.Error("Changing a returned transaction changed the mempool")
+		}
+	}
+
+	// Everything expires after the TTL
+	now := time.Now()
+	mempool.now = func() time.Time { return now.Add(defaultMempoolTTL + time.Second) }
+	if mempool.Size() != 0 {
+		t.Errorf("Expected expired transactions to be evicted, %d left", mempool.Size())
+	}
+	if err := mempool.AddTransaction(signedUpload(otherPriv, otherKey, "asset-other-2", 1)); err != nil {
+		t.Errorf("Expected room after eviction, got %v", err)
+	}
+}
+
+func TestMempoolSurvivesRestart(t *testing.T) {
+	bc := newTestBlockchain(t)
+	mempool := NewMempool(bc, MempoolConfig{})
+	privKey, pubKey := GenerateKeyPair()
+
+	var txs []LicenseTransaction
+	for nonce := range uint64(4) {
+		tx := signedUpload(privKey, pubKey, fmt.Sprintf("asset-journaled-%d", nonce), nonce)
+		if err := mempool.AddTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, tx)
+	}
+	mempool.RemoveTransaction(txs[1].TxID)
+	// Included by the others while this validator was down
+	commit(t, bc, txs[0])
+
+	restarted := NewMempool(bc, MempoolConfig{})
+	restored := restarted.Restore()
+	if len(restored) != 2 || restored[0].TxID != txs[2].TxID || restored[1].TxID != txs[3].TxID {
+		t.Fatalf("Expected the last two transactions back, got %d", len(restored))
+	}
+	if restarted.Size() != 2 {
+		t.Errorf("Expected 2 pending transactions after the restart, got %d", restarted.Size())
+	}
+
+	restarted.RemoveTransaction(txs[2].TxID)
+	if restored := NewMempool(bc, MempoolConfig{}).Restore(); len(restored) != 1 {
+		t.Errorf("Expected 1 journaled transaction left, got %d", len(restored))
+	}
+}
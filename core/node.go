@@ -2,38 +2,43 @@ package core
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
 
 	"github.com/libp2p/go-libp2p"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/p2p/discovery/mdns"
+	ma "github.com/multiformats/go-multiaddr"
 )
 
+// Network every node joins unless configured otherwise, its topics are named after it
+const ConsensusTopic = "drm-consensus"
+
 // Node represents a blockchain node (uploader or validator)
 type Node struct {
-	Host      host.Host
-	PubSub    *pubsub.PubSub
-	Topic     *pubsub.Topic
-	Sub       *pubsub.Subscription
-	VoteTopic *pubsub.Topic
-	VoteSub   *pubsub.Subscription
+	Host   host.Host
+	PubSub *pubsub.PubSub
 	// Balance   uint64  a hypothetical blockchain, no we dont need price
+
+	ctx      context.Context
+	topics   map[string]*pubsub.Topic        // Message class -> topic
+	mu       sync.Mutex                      // Guards handlers, checks and subs
+	handlers map[string][]MessageHandler     // Message type -> handlers
+	checks   map[string][]MessageCheck       // Message type -> checks run before it is handled or relayed
+	subs     map[string]*pubsub.Subscription // Message class -> subscription, once something handles it
 }
 
 // PeerDiscoveryMessage represents a message broadcast when a new peer joins
 type PeerDiscoveryMessage struct {
-	Type      string `json:"type"`
-	PeerID    string `json:"peer_id"`
+	PeerID    string   `json:"peer_id"`
 	Addresses []string `json:"addresses"`
 }
 
 func (n *Node) BroadcastTransaction(tx LicenseTransaction) {
-	txData, _ := json.Marshal(tx)
-	if err := n.Topic.Publish(context.Background(), txData); err != nil {
+	if err := n.Publish(context.Background(), MessageTransaction, tx); err != nil {
 		log.Println("Error broadcasting transaction:", err)
 	}
 	log.Println("Broadcasted!!")
@@ -42,18 +47,11 @@ func (n *Node) BroadcastTransaction(tx LicenseTransaction) {
 // BroadcastNewPeer broadcasts a message when a new peer is discovered
 func (n *Node) BroadcastNewPeer(peerID peer.ID, addresses []string) {
 	msg := PeerDiscoveryMessage{
-		Type:      "peer_discovery",
 		PeerID:    peerID.String(),
 		Addresses: addresses,
 	}
-	
-	msgData, err := json.Marshal(msg)
-	if err != nil {
-		log.Println("Error marshaling peer discovery message:", err)
-		return
-	}
 
-	if err := n.Topic.Publish(context.Background(), msgData); err != nil {
+	if err := n.Publish(context.Background(), MessagePeerDiscovery, msg); err != nil {
 		log.Println("Error broadcasting peer discovery:", err)
 	} else {
 		log.Printf("Broadcasted new peer discovery: %s", peerID)
@@ -85,85 +83,118 @@ type Service interface {
 	Close() error
 }
 
-func NewNode(ctx context.Context, topicName string, isValidator bool) (*Node, error) {
-	h, err := libp2p.New()
+// NodeConfig holds the networking options of a node
+type NodeConfig struct {
+	Topic         string   // Network name, the topic of every message class is <Topic>/<class>
+	ListenAddrs   []string // libp2p multiaddrs (TCP and QUIC), random ports if empty
+	AnnounceAddrs []string // Multiaddrs advertised to peers instead of the listen ones, behind NAT or in containers
+	KeyFile       string   // libp2p identity, created on first start, a random one for every start if empty
+	Peers         []string // Static or bootstrap peer multiaddrs (with /p2p/<id>), redialed whenever they drop
+	NoMDNS        bool     // Only connect to Peers, for networks without multicast
+}
+
+func NewNode(ctx context.Context, topicName string) (*Node, error) {
+	return NewNodeWithConfig(ctx, NodeConfig{Topic: topicName})
+}
+
+func NewNodeWithConfig(ctx context.Context, cfg NodeConfig) (*Node, error) {
+	staticPeers, err := ParsePeers(cfg.Peers)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create host: %w", err)
+		return nil, err
 	}
 
-	ps, err := pubsub.NewGossipSub(ctx, h, pubsub.WithFloodPublish(true))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create pubsub: %w", err)
+	var opts []libp2p.Option
+	if len(cfg.ListenAddrs) > 0 {
+		opts = append(opts, libp2p.ListenAddrStrings(cfg.ListenAddrs...))
+	}
+	if len(cfg.AnnounceAddrs) > 0 {
+		announce, err := parseMultiaddrs(cfg.AnnounceAddrs)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, libp2p.AddrsFactory(func([]ma.Multiaddr) []ma.Multiaddr { return announce }))
+	}
+	if cfg.KeyFile != "" {
+		key, err := LoadOrCreateNodeKey(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, libp2p.Identity(key))
 	}
 
-	topic, err := ps.Join(topicName)
+	h, err := libp2p.New(opts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to join topic: %w", err)
+		return nil, fmt.Errorf("failed to create host: %w", err)
 	}
 
-	sub, err := topic.Subscribe()
+	scoreParams, scoreThresholds := peerScoreParams(cfg.Topic)
+	ps, err := pubsub.NewGossipSub(ctx, h, pubsub.WithFloodPublish(true), pubsub.WithPeerScore(scoreParams, scoreThresholds))
 	if err != nil {
-		return nil, fmt.Errorf("failed to subscribe to topic: %w", err)
+		return nil, fmt.Errorf("failed to create pubsub: %w", err)
 	}
 
-	var voteTopic *pubsub.Topic
-	var voteSub *pubsub.Subscription
-	if isValidator {
-		voteTopic, err = ps.Join("vote")
-		if err != nil {
-			return nil, fmt.Errorf("failed to join topic: %w", err)
+	// Every node joins every topic to publish, it only subscribes to those it handles
+	newNode := &Node{
+		Host:     h,
+		PubSub:   ps,
+		ctx:      ctx,
+		topics:   make(map[string]*pubsub.Topic),
+		handlers: make(map[string][]MessageHandler),
+		checks:   make(map[string][]MessageCheck),
+		subs:     make(map[string]*pubsub.Subscription),
+	}
+	for _, class := range messageTopics {
+		if newNode.topics[class] != nil {
+			continue
 		}
-
-		voteSub, err = voteTopic.Subscribe()
+		name := cfg.Topic + "/" + class
+		if err := ps.RegisterTopicValidator(name, newNode.validateMessage(class)); err != nil {
+			return nil, fmt.Errorf("failed to register topic validator: %w", err)
+		}
+		topic, err := ps.Join(name)
 		if err != nil {
-			return nil, fmt.Errorf("failed to subscribe to topic: %w", err)
+			return nil, fmt.Errorf("failed to join topic: %w", err)
 		}
-	}
-
-	newNode := &Node{
-		Host:      h,
-		PubSub:    ps,
-		Topic:     topic,
-		Sub:       sub,
-		VoteTopic: voteTopic,
-		VoteSub:   voteSub,
+		newNode.topics[class] = topic
 	}
 
 	// Enable peer discovery using mDNS
-	notifee := &DiscoveryNotifee{host: h, node: newNode}
-	service := mdns.NewMdnsService(h, "blockchain-network", notifee)
-	if err := service.Start(); err != nil {
-		log.Println("Failed to start mDNS:", err)
+	var service Service
+	if !cfg.NoMDNS {
+		notifee := &DiscoveryNotifee{host: h, node: newNode}
+		mdnsService := mdns.NewMdnsService(h, "blockchain-network", notifee)
+		if err := mdnsService.Start(); err != nil {
+			log.Println("Failed to start mDNS:", err)
+		}
+		service = mdnsService
+	} else {
+		log.Println("mDNS disabled, only connecting to configured peers")
 	}
 
-	// Start listening for peer discovery messages
-	go func() {
-		for {
-			msg, err := sub.Next(ctx)
-			if err != nil {
-				log.Println("Error reading from topic:", err)
-				continue
-			}
-
-			var discoveryMsg PeerDiscoveryMessage
-			if err := json.Unmarshal(msg.Data, &discoveryMsg); err == nil && discoveryMsg.Type == "peer_discovery" {
-				log.Printf("Received peer discovery message for peer: %s", discoveryMsg.PeerID)
-				// Here you can add additional logic to handle the new peer
-				// For example, connecting to the peer if not already connected
-			}
+	// Peers from the config are dialed directly, for networks mDNS can't see
+	newNode.connectStaticPeers(ctx, staticPeers)
+
+	err = newNode.Handle(MessagePeerDiscovery, func(env *Envelope) {
+		var discoveryMsg PeerDiscoveryMessage
+		if err := env.Decode(&discoveryMsg); err == nil {
+			log.Printf("Received peer discovery message for peer: %s", discoveryMsg.PeerID)
+			// Here you can add additional logic to handle the new peer
+			// For example, connecting to the peer if not already connected
 		}
-	}()
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	go func(ctx context.Context, service Service, node *Node) {
-		select {
-		case <-ctx.Done():
-			log.Println("Context cancelled, shutting down mDNS service...")
-			if node.Sub != nil {
-				node.Sub.Cancel()
-			}
-			if node.VoteSub != nil {
-				node.VoteSub.Cancel()
-			}
+		<-ctx.Done()
+		log.Println("Context cancelled, shutting down mDNS service...")
+		node.mu.Lock()
+		for _, sub := range node.subs {
+			sub.Cancel()
+		}
+		node.mu.Unlock()
+		if service != nil {
 			service.Close()
 		}
 	}(ctx, service, newNode)
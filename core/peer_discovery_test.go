@@ -13,21 +13,21 @@ func TestPeerDiscovery(t *testing.T) {
 	defer cancel()
 
 	// Create first node
-	node1, err := NewNode(ctx, "test-network", false)
+	node1, err := NewNode(ctx, "test-network")
 	if err != nil {
 		t.Fatalf("Failed to create node1: %v", err)
 	}
 	log.Printf("Node1 created with ID: %s", node1.Host.ID().String())
 
 	// Create second node
-	node2, err := NewNode(ctx, "test-network", false)
+	node2, err := NewNode(ctx, "test-network")
 	if err != nil {
 		t.Fatalf("Failed to create node2: %v", err)
 	}
 	log.Printf("Node2 created with ID: %s", node2.Host.ID().String())
 
 	// Create third node
-	node3, err := NewNode(ctx, "test-network", false)
+	node3, err := NewNode(ctx, "test-network")
 	if err != nil {
 		t.Fatalf("Failed to create node3: %v", err)
 	}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+	"github.com/libp2p/go-libp2p/p2p/net/swarm"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const (
+	peerDialTimeout = 10 * time.Second
+	minPeerBackoff  = time.Second
+	maxPeerBackoff  = time.Minute
+)
+
+func parseMultiaddrs(addrs []string) ([]ma.Multiaddr, error) {
+	var maddrs []ma.Multiaddr
+	for _, addr := range addrs {
+		maddr, err := ma.NewMultiaddr(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %s: %w", addr, err)
+		}
+		maddrs = append(maddrs, maddr)
+	}
+	return maddrs, nil
+}
+
+// ParsePeers turns multiaddrs ending in /p2p/<id> into peers, merging addresses of the same peer
+func ParsePeers(addrs []string) ([]peer.AddrInfo, error) {
+	maddrs, err := parseMultiaddrs(addrs)
+	if err != nil {
+		return nil, err
+	}
+
+	peers, err := peer.AddrInfosFromP2pAddrs(maddrs...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer address: %w", err)
+	}
+	return peers, nil
+}
+
+// Dial the static peers, returning after the first attempt at each, and keep redialing the
+// ones that can't be reached or drop with exponential backoff until ctx is canceled
+func (n *Node) connectStaticPeers(ctx context.Context, peers []peer.AddrInfo) {
+	wake := make(map[peer.ID]chan struct{})
+	for _, info := range peers {
+		n.Host.Peerstore().AddAddrs(info.ID, info.Addrs, peerstore.PermanentAddrTTL)
+		n.Host.ConnManager().Protect(info.ID, "static-peer")
+		wake[info.ID] = make(chan struct{}, 1)
+	}
+
+	n.Host.Network().Notify(&network.NotifyBundle{
+		DisconnectedF: func(net network.Network, conn network.Conn) {
+			id := conn.RemotePeer()
+			if wake[id] == nil || net.Connectedness(id) == network.Connected {
+				return
+			}
+			select {
+			case wake[id] <- struct{}{}:
+			default:
+			}
+		},
+	})
+
+	var dialed sync.WaitGroup
+	for _, info := range peers {
+		dialed.Add(1)
+		go n.keepConnected(ctx, info, wake[info.ID], sync.OnceFunc(dialed.Done))
+	}
+	dialed.Wait()
+}
+
+func (n *Node) keepConnected(ctx context.Context, info peer.AddrInfo, wake <-chan struct{}, dialed func()) {
+	defer dialed()
+
+	backoff := minPeerBackoff
+	for {
+		if n.Host.Network().Connectedness(info.ID) != network.Connected {
+			// The swarm's own dial backoff would fail our retries without trying
+			if sw, ok := n.Host.Network().(*swarm.Swarm); ok {
+				sw.Backoff().Clear(info.ID)
+			}
+			dialCtx, cancel := context.WithTimeout(ctx, peerDialTimeout)
+			err := n.Host.Connect(dialCtx, info)
+			cancel()
+			dialed()
+
+			if err != nil {
+				log.Printf("Error connecting to peer %s, retrying in %s: %v", info.ID, backoff, err)
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+					return
+				}
+				backoff = min(backoff*2, maxPeerBackoff)
+				continue
+			}
+			log.Println("Connected to static peer:", info.ID)
+			backoff = minPeerBackoff
+		}
+		dialed()
+
+		select {
+		case <-wake:
+			log.Printf("Lost connection to static peer %s, reconnecting", info.ID)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package core
+
+import "testing"
+
+func TestParsePeers(t *testing.T) {
+	const id = "12D3KooWJWoaqZhDaoEFshF7Rh1bpY9ohihFhzcW6d69Lr2NASuq"
+	peers, err := ParsePeers([]string{
+		"/ip4/10.0.0.1/tcp/4001/p2p/" + id,
+		"/ip4/10.0.0.1/udp/4001/quic-v1/p2p/" + id,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 || peers[0].ID.String() != id || len(peers[0].Addrs) != 2 {
+		t.Errorf("Expected one peer with two addresses, got %v", peers)
+	}
+
+	for _, addr := range []string{"not-a-multiaddr", "/ip4/10.0.0.1/tcp/4001"} {
+		if _, err := ParsePeers([]string{addr}); err == nil {
+			t.Errorf("Accepted peer address %s", addr)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log"
+	"runtime"
+)
+
+const txQueueSize = 1024
+
+// TxPipeline admits transactions into the mempool and hands them to the consensus. A pool of
+// workers checks signatures and the chain state in parallel, the mempool takes the ones that fit.
+// The queue is bounded, so once it is full Submit blocks and a flood of transactions slows down
+// its reader instead of piling up in memory.
+type TxPipeline struct {
+	ID        int // Validator ID, for logs
+	OnReceipt func(receipt Receipt)
+
+	mempool   *Mempool
+	consensus Consensus
+	workers   int
+
+	queue chan LicenseTransaction // Waiting to be admitted
+}
+
+func NewTxPipeline(id int, mempool *Mempool, consensus Consensus) *TxPipeline {
+	return &TxPipeline{
+		ID:        id,
+		OnReceipt: func(Receipt) {},
+		mempool:   mempool,
+		consensus: consensus,
+		workers:   runtime.NumCPU(),
+		queue:     make(chan LicenseTransaction, txQueueSize),
+	}
+}
+
+// Run the admission workers until ctx is canceled
+func (p *TxPipeline) Run(ctx context.Context) {
+	for range p.workers - 1 {
+		go p.work(ctx)
+	}
+	p.work(ctx)
+}
+
+// Submit queues a transaction, waiting while the pipeline is full
+func (p *TxPipeline) Submit(ctx context.Context, tx LicenseTransaction) error {
+	select {
+	case p.queue <- tx:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Process admits a transaction right away, returning whether it was accepted and why not
+func (p *TxPipeline) Process(tx LicenseTransaction) (bool, string) {
+	return p.admit(tx)
+}
+
+func (p *TxPipeline) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case tx := <-p.queue:
+			p.admit(tx)
+		}
+	}
+}
+
+// Add a transaction to the mempool and propose it, or vote against it if it is invalid
+func (p *TxPipeline) admit(tx LicenseTransaction) (bool, string) {
+	err := p.mempool.AddTransaction(tx)
+	var rejected *RejectedError
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrInMempool):
+		return true, err.Error()
+	case errors.As(err, &rejected):
+		log.Printf("Validator %d rejected transaction %s: %s", p.ID, tx.TxID, rejected.Reason)
+		p.consensus.Reject(tx, rejected.Reason)
+		return false, rejected.Reason
+	default:
+		// Full mempool or already in the chain, nothing wrong with the transaction itself
+		log.Printf("Validator %d dropped transaction %s: %v", p.ID, tx.TxID, err)
+		return false, err.Error()
+	}
+
+	log.Printf("Validator %d received transaction %s", p.ID, tx.TxID)
+	p.OnReceipt(Receipt{TxID: tx.TxID, Status: TxStatusInMempool})
+
+	p.consensus.Propose(tx)
+	return true, ""
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Uploads of each benchmark run, enough for blocks to fill up
+const throughputTxs = 500
+
+// Submit txs uploads to every validator of a new network, as gossip would, and return how many
+// transactions per second made it into the chain of every validator
+func measureThroughput(tb testing.TB, kind string, validators, txs int) float64 {
+	tb.Helper()
+
+	// The default timeouts, blocks of hundreds of transactions take longer than the short test ones
+	net := newConsensusNetworkWithParams(tb, ConsensusParams{Consensus: kind}, validators)
+	// Stopped right away, so the next measurement doesn't share the CPU with it
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, stop := range net.cancels {
+		defer stop()
+	}
+
+	var pipelines []*TxPipeline
+	for id := range validators {
+		pipeline := NewTxPipeline(id, net.mempools[id], net.nodes[id])
+		go pipeline.Run(ctx)
+		pipelines = append(pipelines, pipeline)
+	}
+
+	uploads := make([]LicenseTransaction, txs)
+	for i := range uploads {
+		privKey, pubKey := GenerateKeyPair()
+		uploads[i] = sign(net.chains[0], LicenseTransaction{Owner: pubKey, AssetHash: fmt.Sprintf("asset-throughput-%d", i), License: "view", TxType: TxTypeUpload}, privKey)
+	}
+
+	start := time.Now()
+	for _, pipeline := range pipelines {
+		go func() {
+			for _, tx := range uploads {
+				pipeline.Submit(context.Background(), tx)
+			}
+		}()
+	}
+
+	deadline := start.Add(time.Minute)
+	for id, bc := range net.chains {
+		for countTransactions(bc) < txs {
+			if time.Now().After(deadline) {
+				tb.Fatalf("Validator %d included %d of %d transactions", id, countTransactions(bc), txs)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	return float64(txs) / time.Since(start).Seconds()
+}
+
+func countTransactions(bc *Blockchain) int {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	count := 0
+	for _, block := range bc.Blocks {
+		count += len(block.Transaction)
+	}
+	return count
+}
+
+// Only checks every consensus gets a burst of uploads through the pipeline in time, run
+// "go test -bench PipelineThroughput ./core" for the throughput
+func TestPipelineThroughput(t *testing.T) {
+	for _, kind := range []string{ConsensusVote, ConsensusBFT, ConsensusRaft} {
+		t.Run(kind, func(t *testing.T) {
+			tps := measureThroughput(t, kind, 4, 50)
+			t.Logf("%.0f transactions per second with 4 validators", tps)
+		})
+	}
+}
+
+func BenchmarkPipelineThroughput(b *testing.B) {
+	for _, kind := range []string{ConsensusVote, ConsensusBFT, ConsensusRaft} {
+		for _, validators := range []int{4, 7} {
+			b.Run(fmt.Sprintf("%s/%d", kind, validators), func(b *testing.B) {
+				var tps float64
+				for range b.N {
+					tps = measureThroughput(b, kind, validators, throughputTxs)
+				}
+				b.ReportMetric(tps, "tx/s")
+			})
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+const ReceiptPrefix = "receipt-"
+
+// Lifecycle of a transaction, from submission to finality
+const (
+	TxStatusPending   = "pending"
+	TxStatusInMempool = "in_mempool"
+	TxStatusApproved  = "approved"
+	TxStatusRejected  = "rejected"
+	TxStatusIncluded  = "included"
+)
+
+var txStatusRank = map[string]int{
+	TxStatusPending:   0,
+	TxStatusInMempool: 1,
+	TxStatusApproved:  2,
+	TxStatusRejected:  3,
+	TxStatusIncluded:  4, // What the chain says beats any rejection
+}
+
+// Receipt records what happened to a transaction once a node learned about it
+type Receipt struct {
+	TxID       string
+	Status     string
+	BlockIndex int    // Height of the including block, only set once included
+	BlockHash  string // Hash of the including block, only set once included
+	Reason     string // Why the transaction was rejected
+	Timestamp  int64  // Unix timestamp of the last status change
+}
+
+// SignedReceipt is a receipt a validator gossips, signed so nodes only store receipts of validators
+type SignedReceipt struct {
+	Receipt
+	ValidatorID int
+	Signature   string
+}
+
+func receiptDigest(receipt *SignedReceipt) []byte {
+	data := fmt.Sprintf("receipt|%d|%s|%s|%d|%s|%s|%d", receipt.ValidatorID, receipt.TxID, receipt.Status,
+		receipt.BlockIndex, receipt.BlockHash, receipt.Reason, receipt.Timestamp)
+	hash := sha256.Sum256([]byte(data))
+	return hash[:]
+}
+
+// SignReceipt signs a receipt with the validator's key
+func SignReceipt(privKey *ecdsa.PrivateKey, receipt *SignedReceipt) string {
+	return signDigest(privKey, receiptDigest(receipt))
+}
+
+// VerifyReceipt checks that a receipt is signed by the key the validator set has for its sender
+func VerifyReceipt(receipt SignedReceipt, validators *ValidatorSet) bool {
+	pubKey, ok := validators.Key(receipt.ValidatorID)
+	if !ok {
+		return false
+	}
+	return verifySignature(pubKey, receipt.Signature, receiptDigest(&receipt))
+}
+
+// IsFinal reports whether a transaction status can no longer change
+func IsFinal(status string) bool {
+	return status == TxStatusRejected || status == TxStatusIncluded
+}
+
+// SaveReceipt persists a receipt unless the stored one is already further along
+func (bc *Blockchain) SaveReceipt(receipt Receipt) {
+	if existing := bc.Receipt(receipt.TxID); existing != nil && txStatusRank[existing.Status] >= txStatusRank[receipt.Status] {
+		return
+	}
+
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		log.Println("Error marshaling receipt:", err)
+		return
+	}
+
+	if err := bc.db.Save(ReceiptPrefix+receipt.TxID, data); err != nil {
+		log.Println("Error saving receipt:", err)
+	}
+}
+
+// Receipt loads the stored receipt for a transaction, nil if there is none
+func (bc *Blockchain) Receipt(txID string) *Receipt {
+	data, err := bc.db.Load(ReceiptPrefix + txID)
+	if err != nil {
+		return nil
+	}
+
+	var receipt Receipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		log.Println("Error unmarshaling receipt:", err)
+		return nil
+	}
+	return &receipt
+}
+
+// FindTransaction returns the chain height of the block including txID, or -1
+func (bc *Blockchain) FindTransaction(txID string) (int, *LicenseTransaction) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	return bc.findTransaction(txID)
+}
+
+// Same as FindTransaction, bc.mu must be held
+func (bc *Blockchain) findTransaction(txID string) (int, *LicenseTransaction) {
+	height, ok := bc.txIndex[txID]
+	if !ok {
+		return -1, nil
+	}
+	block := bc.Blocks[height]
+	for i, tx := range block.Transaction {
+		if tx.TxID == txID {
+			return height, &block.Transaction[i]
+		}
+	}
+	return -1, nil
+}
+
+// TransactionStatus combines the local chain and the stored receipt into the
+// best known status of txID, along with its number of confirmations
+func (bc *Blockchain) TransactionStatus(txID string) (*Receipt, int) {
+	bc.mu.Lock()
+	height, _ := bc.findTransaction(txID)
+	var receipt *Receipt
+	confirmations := 0
+	if height >= 0 {
+		receipt = &Receipt{
+			TxID:       txID,
+			Status:     TxStatusIncluded,
+			BlockIndex: height,
+			BlockHash:  bc.Blocks[height].Hash,
+		}
+		confirmations = len(bc.Blocks) - height
+	}
+	bc.mu.Unlock()
+
+	if receipt == nil {
+		return bc.Receipt(txID), 0
+	}
+	if stored := bc.Receipt(txID); stored != nil {
+		receipt.Timestamp = stored.Timestamp
+	}
+	return receipt, confirmations
+}
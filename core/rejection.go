@@ -0,0 +1,109 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Reasons a validator gives for rejecting a transaction
+const (
+	RejectBadSignature   = "bad_signature"   // Missing fields or a signature that doesn't verify
+	RejectBadNonce       = "bad_nonce"       // Nonce not above the signer's last one
+	RejectDuplicateAsset = "duplicate_asset" // Upload of an asset that already exists
+	RejectUnknownAsset   = "unknown_asset"   // Transaction on an asset that was never uploaded
+	RejectExpired        = "expired"         // Expiry already passed
+	RejectUnauthorized   = "unauthorized"    // Signer isn't allowed to do this
+	RejectInvalid        = "invalid"         // Any other conflict with the chain state
+)
+
+const RejectionPrefix = "rejection-"
+
+// Rejection collects the reasons validators gave for rejecting a transaction
+type Rejection struct {
+	TxID    string
+	Reasons map[int]string // Validator ID -> reason
+	Final   bool           // Enough validators rejected it that it can't be approved
+}
+
+// TransactionRejection checks a transaction like a validator does before voting on it,
+// returning the reason to reject it or "" if it is valid
+func TransactionRejection(tx LicenseTransaction, bc *Blockchain) string {
+	if !ValidateTransaction(tx) {
+		return RejectBadSignature
+	}
+	return checkState(tx, bc)
+}
+
+// Rejection loads the stored rejection reasons of a transaction, nil if there are none
+func (bc *Blockchain) Rejection(txID string) *Rejection {
+	data, err := bc.db.Load(RejectionPrefix + txID)
+	if err != nil || data == nil {
+		return nil
+	}
+
+	var rejection Rejection
+	if err := json.Unmarshal(data, &rejection); err != nil {
+		log.Println("Error unmarshaling rejection:", err)
+		return nil
+	}
+	return &rejection
+}
+
+// RecordRejection stores the reason a validator gave for not admitting a transaction, without
+// deciding its outcome
+func (bc *Blockchain) RecordRejection(txID string, validatorID int, reason string) {
+	bc.recordRejection(txID, validatorID, reason, false)
+}
+
+// Persist the reason a validator gave, and whether the rejection is final
+func (bc *Blockchain) recordRejection(txID string, validatorID int, reason string, final bool) *Rejection {
+	bc.rejectionMu.Lock()
+	defer bc.rejectionMu.Unlock()
+
+	rejection := bc.Rejection(txID)
+	if rejection == nil {
+		rejection = &Rejection{TxID: txID, Reasons: make(map[int]string)}
+	}
+	if reason != "" {
+		rejection.Reasons[validatorID] = reason
+	}
+	rejection.Final = rejection.Final || final
+
+	data, err := json.Marshal(rejection)
+	if err != nil {
+		log.Println("Error marshaling rejection:", err)
+		return rejection
+	}
+	if err := bc.db.Save(RejectionPrefix+txID, data); err != nil {
+		log.Println("Error saving rejection:", err)
+	}
+	return rejection
+}
+
+// Summary counts the validators per reason, most common first, e.g. "duplicate_asset (3), bad_nonce (1)"
+func (r *Rejection) Summary() string {
+	counts := make(map[string]int)
+	for _, reason := range r.Reasons {
+		counts[reason]++
+	}
+
+	reasons := make([]string, 0, len(counts))
+	for reason := range counts {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if counts[reasons[i]] != counts[reasons[j]] {
+			return counts[reasons[i]] > counts[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+
+	parts := make([]string, len(reasons))
+	for i, reason := range reasons {
+		parts[i] = fmt.Sprintf("%s (%d)", reason, counts[reason])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransactionRejectionReasons(t *testing.T) {
+	bc := newTestBlockchain(t)
+	ownerPriv, ownerKey := GenerateKeyPair()
+	buyerPriv, buyerKey := GenerateKeyPair()
+	otherPriv, otherKey := GenerateKeyPair()
+
+	upload := sign(bc, LicenseTransaction{Owner: ownerKey, AssetHash: "asset-rejected", License: "view", TxType: TxTypeUpload}, ownerPriv)
+	commit(t, bc, upload)
+
+	replayed := LicenseTransaction{Owner: ownerKey, AssetHash: "asset-replayed", License: "view", TxType: TxTypeUpload, Timestamp: time.Now().Unix()}
+	replayed.TxID = GenerateTransactionID(replayed)
+	replayed.Signature = SignTransaction(ownerPriv, &replayed)
+
+	tampered := sign(bc, LicenseTransaction{Owner: otherKey, AssetHash: "asset-tampered", License: "view", TxType: TxTypeUpload}, otherPriv)
+	tampered.License = "download"
+
+	tests := []struct {
+		name   string
+		tx     LicenseTransaction
+		reason string
+	}{
+		{"valid", sign(bc, LicenseTransaction{Owner: otherKey, AssetHash: "asset-new", License: "view", TxType: TxTypeUpload}, otherPriv), ""},
+		{"tampered", tampered, RejectBadSignature},
+		{"replayed nonce", replayed, RejectBadNonce},
+		{"duplicate upload", sign(bc, LicenseTransaction{Owner: otherKey, AssetHash: "asset-rejected", License: "view", TxType: TxTypeUpload}, otherPriv), RejectDuplicateAsset},
+		{"unknown asset", sign(bc, LicenseTransaction{Owner: ownerKey, Licensee: buyerKey, AssetHash: "asset-missing", License: "view", TxType: TxTypePurchase}, buyerPriv), RejectUnknownAsset},
+		{"expired", sign(bc, LicenseTransaction{Owner: ownerKey, Licensee: buyerKey, AssetHash: "asset-rejected", License: "view", TxType: TxTypePurchase, Expiry: time.Now().Unix() - 60}, buyerPriv), RejectExpired},
+	}
+	for _, test := range tests {
+		if reason := TransactionRejection(test.tx, bc); reason != test.reason {
+			t.Errorf("%s: expected reason %q, got %q", test.name, test.reason, reason)
+		}
+	}
+}
+
+func TestVoteConsensusFinalizesRejection(t *testing.T) {
+	net := newConsensusNetwork(t, ConsensusVote, 4)
+	privKey, pubKey := GenerateKeyPair()
+	tx := sign(net.chains[0], LicenseTransaction{Owner: pubKey, AssetHash: "asset-voted-down", License: "view", TxType: TxTypeUpload}, privKey)
+
+	// With a quorum of 3 out of 4, two rejections make approval impossible
+	net.nodes[0].Propose(tx)
+	net.nodes[1].Reject(tx, RejectDuplicateAsset)
+	net.nodes[2].Reject(tx, RejectBadNonce)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for id, bc := range net.chains {
+		for {
+			if rejection := bc.Rejection(tx.TxID); rejection != nil && rejection.Final {
+				if rejection.Reasons[1] != RejectDuplicateAsset || rejection.Reasons[2] != RejectBadNonce {
+					t.Errorf("Validator %d stored reasons %v", id, rejection.Reasons)
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Validator %d didn't finalize the rejection", id)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if summary := net.chains[0].Rejection(tx.TxID).Summary(); summary != "bad_nonce (1), duplicate_asset (1)" {
+		t.Errorf("Unexpected summary %q", summary)
+	}
+}
+
+func TestBFTLocalRejectionIsNotFinal(t *testing.T) {
+	net := newConsensusNetwork(t, ConsensusBFT, 4)
+	privKey, pubKey := GenerateKeyPair()
+	tx := sign(net.chains[0], LicenseTransaction{Owner: pubKey, AssetHash: "asset-rejected-once", License: "view", TxType: TxTypeUpload}, privKey)
+
+	// One validator's check failing doesn't keep the others from including it
+	net.nodes[1].Reject(tx, RejectDuplicateAsset)
+	for _, id := range []int{0, 2, 3} {
+		if err := net.mempools[id].AddTransaction(tx); err != nil {
+			t.Fatal(err)
+		}
+		net.nodes[id].Propose(tx)
+	}
+	net.waitIncluded(t, tx)
+
+	rejection := net.chains[1].Rejection(tx.TxID)
+	if rejection == nil || rejection.Final || rejection.Reasons[1] != RejectDuplicateAsset {
+		t.Errorf("Expected the reason of validator 1 without a final rejection, got %+v", rejection)
+	}
+}
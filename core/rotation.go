@@ -0,0 +1,154 @@
+package core
+
+import (
+	"log"
+	"time"
+)
+
+// Longest rotation chain followed, guards against cycles in a corrupted chain
+const maxRotationDepth = 64
+
+type keyRotation struct {
+	NewKey      string
+	EffectiveAt int64 // Unix timestamp from which NewKey is in control, counted from the including block
+	Recovery    bool  // Rotation signed by the recovery key rather than the old key
+}
+
+type recoverySetting struct {
+	Key   string
+	Delay int64
+}
+
+// KeyResolver answers which key currently controls an identity, following
+// the rotate-key transactions recorded on the chain
+type KeyResolver struct {
+	rotations map[string]keyRotation     // old key -> rotation
+	recovery  map[string]recoverySetting // key -> registered recovery key
+	used      map[string]bool            // keys that appeared on chain in any role
+	now       int64                      // Unix timestamp rotations are resolved at
+}
+
+// NewKeyResolver resolves keys as of now, for answering queries about the chain
+func NewKeyResolver(bc *Blockchain) *KeyResolver {
+	return newKeyResolverAt(bc, time.Now())
+}
+
+// Resolves keys as of at, the time of the block a transaction goes into, so every validator
+// agrees on whether a rotation took effect regardless of its clock
+func newKeyResolverAt(bc *Blockchain, at time.Time) *KeyResolver {
+	r := &KeyResolver{
+		rotations: make(map[string]keyRotation),
+		recovery:  make(map[string]recoverySetting),
+		used:      make(map[string]bool),
+		now:       at.Unix(),
+	}
+
+	for _, block := range bc.chain() {
+		// Not the timestamps of the transactions, which their signers choose
+		at, err := BlockTime(block)
+		if err != nil {
+			log.Printf("Block %d has an invalid timestamp: %v", block.Index, err)
+		}
+		for _, tx := range block.Transaction {
+			r.apply(tx, at.Unix())
+		}
+	}
+	return r
+}
+
+// Apply a transaction included in a block timestamped at
+func (r *KeyResolver) apply(tx LicenseTransaction, at int64) {
+	for _, key := range []string{tx.Owner, tx.Licensee, tx.NewKey, tx.RecoveryKey} {
+		if key != "" {
+			r.used[key] = true
+		}
+	}
+
+	switch tx.TxType {
+	case TxTypeSetRecovery:
+		// The owner acting before a recovery rotation takes effect cancels it
+		if pending, ok := r.rotations[tx.Owner]; ok && pending.Recovery && pending.EffectiveAt > at {
+			delete(r.rotations, tx.Owner)
+		}
+		if tx.RecoveryKey == "" {
+			delete(r.recovery, tx.Owner)
+		} else {
+			r.recovery[tx.Owner] = recoverySetting{Key: tx.RecoveryKey, Delay: tx.RecoveryDelay}
+		}
+	case TxTypeRotateKey:
+		rotation := keyRotation{NewKey: tx.NewKey, EffectiveAt: at}
+		if tx.Licensee != "" {
+			rotation.Recovery = true
+			rotation.EffectiveAt += r.recovery[tx.Owner].Delay
+		}
+		r.rotations[tx.Owner] = rotation
+
+		// Recovery settings move along with the identity
+		if setting, ok := r.recovery[tx.Owner]; ok && !rotation.Recovery {
+			r.recovery[tx.NewKey] = setting
+		}
+	}
+}
+
+// Resolve follows the rotation chain from key to the key currently in control
+func (r *KeyResolver) Resolve(key string) string {
+	for range maxRotationDepth {
+		rotation, ok := r.rotations[key]
+		if !ok || rotation.EffectiveAt > r.now {
+			break
+		}
+		key = rotation.NewKey
+	}
+	return key
+}
+
+// ResolveKey returns the key currently controlling the identity that started as key
+func ResolveKey(bc *Blockchain, key string) string {
+	return NewKeyResolver(bc).Resolve(key)
+}
+
+// PendingRotation reports a recovery rotation of key that hasn't taken effect yet
+func (r *KeyResolver) PendingRotation(key string) (string, int64, bool) {
+	rotation, ok := r.rotations[key]
+	if !ok || rotation.EffectiveAt <= r.now {
+		return "", 0, false
+	}
+	return rotation.NewKey, rotation.EffectiveAt, true
+}
+
+// validateKeyTransaction checks rotate-key and set-recovery transactions against the chain
+func validateKeyTransaction(tx LicenseTransaction, r *KeyResolver) bool {
+	switch tx.TxType {
+	case TxTypeSetRecovery:
+		if tx.RecoveryKey == tx.Owner {
+			log.Println("Recovery key must differ from the key it protects")
+			return false
+		}
+		if tx.RecoveryKey != "" && tx.RecoveryDelay <= 0 {
+			log.Println("Recovery delay must be positive")
+			return false
+		}
+	case TxTypeRotateKey:
+		if tx.NewKey == "" || tx.NewKey == tx.Owner {
+			log.Println("Rotation needs a new key")
+			return false
+		}
+		if r.used[tx.NewKey] {
+			log.Println("Rotation target key is already in use on chain:", tx.NewKey)
+			return false
+		}
+		if r.Resolve(tx.Owner) != tx.Owner {
+			log.Println("Key has already been rotated:", tx.Owner)
+			return false
+		}
+		if _, _, pending := r.PendingRotation(tx.Owner); pending && tx.Licensee != "" {
+			log.Println("A recovery rotation is already pending for:", tx.Owner)
+			return false
+		}
+		if tx.Licensee != "" && r.recovery[tx.Owner].Key != tx.Licensee {
+			log.Println("Rotation not signed by the registered recovery key of:", tx.Owner)
+			return false
+		}
+	}
+	return true
+}
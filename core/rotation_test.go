@@ -0,0 +1,107 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"testing"
+	"time"
+
+	storage "github.com/Saumya40-codes/DeSecure/pkg"
+)
+
+func newTestBlockchain(t testing.TB) *Blockchain {
+	t.Helper()
+
+	db := storage.OpenDB(t.TempDir())
+	t.Cleanup(db.CloseDB)
+	return NewBlockchain(db)
+}
+
+// sign fills in the ID and signatures, the second key (if any) co-signs
+func sign(bc *Blockchain, tx LicenseTransaction, keys ...*ecdsa.PrivateKey) LicenseTransaction {
+	if tx.Timestamp == 0 {
+		tx.Timestamp = time.Now().Unix()
+	}
+	tx.Nonce = NextNonce(bc, TransactionSigner(tx))
+	tx.TxID = GenerateTransactionID(tx)
+	tx.Signature = SignTransaction(keys[0], &tx)
+	if len(keys) > 1 {
+		tx.CoSignature = SignTransaction(keys[1], &tx)
+	}
+	return tx
+}
+
+func commit(t *testing.T, bc *Blockchain, tx LicenseTransaction) {
+	t.Helper()
+
+	if !RegisterLicense(tx, bc) {
+		t.Fatalf("%s transaction was rejected", tx.TxType)
+	}
+	bc.AddTransaction(tx)
+}
+
+func TestKeyRotationMovesOwnership(t *testing.T) {
+	bc := newTestBlockchain(t)
+	oldPriv, oldKey := GenerateKeyPair()
+	newPriv, newKey := GenerateKeyPair()
+
+	commit(t, bc, sign(bc, LicenseTransaction{Owner: oldKey, AssetHash: "asset-rotation", License: "view", TxType: TxTypeUpload}, oldPriv))
+
+	// Both keys have to sign
+	rotation := sign(bc, LicenseTransaction{Owner: oldKey, NewKey: newKey, TxType: TxTypeRotateKey}, oldPriv)
+	if RegisterLicense(rotation, bc) {
+		t.Fatal("Rotation without the new key's signature was accepted")
+	}
+	commit(t, bc, sign(bc, rotation, oldPriv, newPriv))
+
+	if !HasValidLicense("asset-rotation", bc, newKey) {
+		t.Error("New key should own the asset after rotation")
+	}
+	if HasValidLicense("asset-rotation", bc, oldKey) {
+		t.Error("Old key should lose access after rotation")
+	}
+
+	delist := sign(bc, LicenseTransaction{Owner: oldKey, AssetHash: "asset-rotation", TxType: TxTypeDelist}, oldPriv)
+	if RegisterLicense(delist, bc) {
+		t.Error("Rotated key must not be able to sign transactions")
+	}
+	commit(t, bc, sign(bc, LicenseTransaction{Owner: newKey, AssetHash: "asset-rotation", TxType: TxTypeDelist}, newPriv))
+}
+
+func TestRecoveryRotationWaitsForDelay(t *testing.T) {
+	bc := newTestBlockchain(t)
+	ownerPriv, ownerKey := GenerateKeyPair()
+	recoveryPriv, recoveryKey := GenerateKeyPair()
+	newPriv, newKey := GenerateKeyPair()
+
+	commit(t, bc, sign(bc, LicenseTransaction{Owner: ownerKey, RecoveryKey: recoveryKey, RecoveryDelay: 3600, TxType: TxTypeSetRecovery}, ownerPriv))
+
+	// Only the registered recovery key may rotate without the owner
+	_, strangerKey := GenerateKeyPair()
+	forged := sign(bc, LicenseTransaction{Owner: ownerKey, Licensee: strangerKey, NewKey: newKey, TxType: TxTypeRotateKey}, recoveryPriv, newPriv)
+	if RegisterLicense(forged, bc) {
+		t.Fatal("Rotation signed by an unknown recovery key was accepted")
+	}
+
+	// Claims to have been signed two hours ago, the delay still runs from the block including it
+	recovery := LicenseTransaction{Owner: ownerKey, Licensee: recoveryKey, NewKey: newKey, TxType: TxTypeRotateKey, Timestamp: time.Now().Add(-2 * time.Hour).Unix()}
+	commit(t, bc, sign(bc, recovery, recoveryPriv, newPriv))
+
+	if got := ResolveKey(bc, ownerKey); got != ownerKey {
+		t.Error("Backdated recovery rotation took effect before its delay")
+	}
+
+	// Two hours later the one hour delay has passed
+	later := time.Now().Add(2 * time.Hour)
+	if got := newKeyResolverAt(bc, later).Resolve(ownerKey); got != newKey {
+		t.Errorf("Expected recovery rotation to be effective, resolved to %s", got)
+	}
+
+	// Whether the old key still signs depends on the time of the block, not the clock of the validator
+	upload := sign(bc, LicenseTransaction{Owner: ownerKey, AssetHash: "asset-recovery", License: "view", TxType: TxTypeUpload}, ownerPriv)
+	if reason := checkStateAt(upload, bc, time.Now()); reason != "" {
+		t.Errorf("Old key rejected in a block before the delay passed: %s", reason)
+	}
+	if reason := checkStateAt(upload, bc, later); reason != RejectUnauthorized {
+		t.Errorf("Old key accepted in a block after the delay passed: %q", reason)
+	}
+}
@@ -0,0 +1,213 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// SubmitProtocol lets clients hand a transaction directly to a validator and
+// learn whether it made it into the validator's mempool
+const SubmitProtocol = "/drm/submit/1.0.0"
+
+// Upper bound on an encoded transaction accepted over a submit stream
+const maxSubmitSize = 1 << 20
+
+// SubmitResult is the admission decision a validator sends back, signed so clients know it came
+// from the validator it names
+type SubmitResult struct {
+	TxID        string
+	Accepted    bool
+	Reason      string
+	ValidatorID int
+	Signature   string
+
+	Gossiped   bool           `json:",omitempty"` // No validator answered, the transaction was only gossiped
+	Rejections map[int]string `json:",omitempty"` // Validator ID -> reason, of the validators that didn't admit it
+}
+
+func submitResultDigest(result *SubmitResult) []byte {
+	data := fmt.Sprintf("submit-result|%s|%d|%t|%q", result.TxID, result.ValidatorID, result.Accepted, result.Reason)
+	hash := sha256.Sum256([]byte(data))
+	return hash[:]
+}
+
+// SignSubmitResult signs an admission decision with the validator's key
+func SignSubmitResult(privKey *ecdsa.PrivateKey, result *SubmitResult) string {
+	return signDigest(privKey, submitResultDigest(result))
+}
+
+// VerifySubmitResult checks that an admission decision is signed by the key the validator set has for its sender
+func VerifySubmitResult(result SubmitResult, validators *ValidatorSet) bool {
+	if validators == nil {
+		return false
+	}
+	pubKey, ok := validators.Key(result.ValidatorID)
+	if !ok {
+		return false
+	}
+	return verifySignature(pubKey, result.Signature, submitResultDigest(&result))
+}
+
+// SubmitOptions controls how hard SubmitTransaction tries to reach validators
+type SubmitOptions struct {
+	Attempts       int           // Rounds over the connected peers before falling back to gossip
+	Backoff        time.Duration // Wait before the first retry, doubled after every round
+	StreamTimeout  time.Duration // Deadline for a single validator to answer
+	ValidatorCount int           // Number of validators that must accept the transaction
+	Validators     *ValidatorSet // Answers not signed by one of them are ignored
+}
+
+func DefaultSubmitOptions() SubmitOptions {
+	return SubmitOptions{
+		Attempts:       5,
+		Backoff:        time.Second,
+		StreamTimeout:  10 * time.Second,
+		ValidatorCount: 1,
+	}
+}
+
+func (v *Validator) handleSubmitStream(s network.Stream) {
+	defer s.Close()
+
+	s.SetDeadline(time.Now().Add(30 * time.Second))
+
+	var result SubmitResult
+	var transaction LicenseTransaction
+	if err := json.NewDecoder(io.LimitReader(s, maxSubmitSize)).Decode(&transaction); err != nil {
+		result = SubmitResult{ValidatorID: v.ID, Reason: "malformed transaction: " + err.Error()}
+		result.Signature = SignSubmitResult(v.PrivateKey, &result)
+	} else {
+		result = v.answerSubmit(transaction)
+		if result.Accepted {
+			// Let the other validators see it so they can vote as well
+			v.Node.BroadcastTransaction(transaction)
+		}
+	}
+
+	if err := json.NewEncoder(s).Encode(result); err != nil {
+		log.Printf("Validator %d error answering submit from %s: %v", v.ID, s.Conn().RemotePeer(), err)
+	}
+}
+
+// Admit a submitted transaction and sign the decision
+func (v *Validator) answerSubmit(transaction LicenseTransaction) SubmitResult {
+	result := SubmitResult{TxID: transaction.TxID, ValidatorID: v.ID}
+	result.Accepted, result.Reason = v.processTransaction(transaction)
+	result.Signature = SignSubmitResult(v.PrivateKey, &result)
+	return result
+}
+
+// SubmitTransaction sends tx to validators over SubmitProtocol and waits for
+// their admission result. Peers that don't speak the protocol are skipped; if
+// no validator answers after all attempts the transaction is gossiped instead.
+func (n *Node) SubmitTransaction(ctx context.Context, tx LicenseTransaction, opts SubmitOptions) (*SubmitResult, error) {
+	send := func(ctx context.Context, p peer.ID) (*SubmitResult, error) {
+		return n.submitTo(ctx, p, tx, opts.StreamTimeout)
+	}
+	return submitToValidators(ctx, tx, opts, n.Host.Network().Peers, send, n.BroadcastTransaction)
+}
+
+// Ask the peers for their decision on tx until enough validators accepted it or all of them
+// answered. A validator not admitting it is only its own decision, so the others are still asked.
+func submitToValidators(ctx context.Context, tx LicenseTransaction, opts SubmitOptions, peers func() []peer.ID,
+	send func(context.Context, peer.ID) (*SubmitResult, error), gossip func(LicenseTransaction)) (*SubmitResult, error) {
+	backoff := opts.Backoff
+	var accepted *SubmitResult
+	acks := 0
+	answered := make(map[peer.ID]bool)
+	rejections := make(map[int]string)
+
+	for attempt := 0; attempt < opts.Attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			backoff *= 2
+		}
+
+		current := peers()
+		for _, p := range current {
+			if answered[p] {
+				continue
+			}
+
+			result, err := send(ctx, p)
+			if err != nil {
+				continue
+			}
+			answered[p] = true
+			if result.TxID != tx.TxID || !VerifySubmitResult(*result, opts.Validators) {
+				log.Printf("Ignoring submit result from %s not signed by validator %d", p, result.ValidatorID)
+				continue
+			}
+
+			if !result.Accepted {
+				rejections[result.ValidatorID] = result.Reason
+				continue
+			}
+			acks++
+			if accepted == nil {
+				accepted = result
+			}
+			if acks >= opts.ValidatorCount {
+				accepted.Rejections = rejections
+				return accepted, nil
+			}
+		}
+
+		// Nobody left to ask, waiting won't change any answer
+		if (accepted != nil || len(rejections) > 0) && len(answered) >= len(current) {
+			break
+		}
+	}
+
+	if accepted != nil {
+		accepted.Rejections = rejections
+		return accepted, nil
+	}
+	if len(rejections) > 0 {
+		return &SubmitResult{TxID: tx.TxID, Reason: "no validator admitted the transaction", Rejections: rejections}, nil
+	}
+
+	log.Println("No validator acknowledged the transaction, falling back to gossip")
+	gossip(tx)
+	return &SubmitResult{TxID: tx.TxID, Gossiped: true, Reason: "no validator reachable over " + SubmitProtocol}, nil
+}
+
+func (n *Node) submitTo(ctx context.Context, p peer.ID, tx LicenseTransaction, timeout time.Duration) (*SubmitResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	s, err := n.Host.NewStream(ctx, p, SubmitProtocol)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	s.SetDeadline(time.Now().Add(timeout))
+
+	if err := json.NewEncoder(s).Encode(tx); err != nil {
+		s.Reset()
+		return nil, fmt.Errorf("failed to send transaction: %w", err)
+	}
+	s.CloseWrite()
+
+	var result SubmitResult
+	if err := json.NewDecoder(bufio.NewReader(s)).Decode(&result); err != nil {
+		s.Reset()
+		return nil, fmt.Errorf("failed to read submit result: %w", err)
+	}
+	return &result, nil
+}
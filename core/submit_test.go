@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+func TestSubmitToValidators(t *testing.T) {
+	net := newConsensusNetwork(t, ConsensusBFT, 4)
+
+	// Validators answering submit streams, one per peer
+	var peers []peer.ID
+	validators := make(map[peer.ID]*Validator)
+	for id, key := range net.keys {
+		v := NewValidator(id, nil, PublicKeyHex(&key.PublicKey), key, net.mempools[id])
+		v.Pipeline = NewTxPipeline(id, net.mempools[id], net.nodes[id])
+		p := peer.ID(fmt.Sprintf("validator-%d", id))
+		peers = append(peers, p)
+		validators[p] = v
+	}
+
+	opts := SubmitOptions{Attempts: 2, Backoff: time.Millisecond, ValidatorCount: 1, Validators: net.chains[0].Validators()}
+	submit := func(tx LicenseTransaction, answer func(p peer.ID) (*SubmitResult, error)) (*SubmitResult, []LicenseTransaction) {
+		t.Helper()
+
+		var gossiped []LicenseTransaction
+		send := func(_ context.Context, p peer.ID) (*SubmitResult, error) { return answer(p) }
+		result, err := submitToValidators(context.Background(), tx, opts, func() []peer.ID { return peers }, send,
+			func(tx LicenseTransaction) { gossiped = append(gossiped, tx) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result, gossiped
+	}
+	upload := func(asset string) LicenseTransaction {
+		privKey, pubKey := GenerateKeyPair()
+		return sign(net.chains[0], LicenseTransaction{Owner: pubKey, AssetHash: asset, License: "view", TxType: TxTypeUpload}, privKey)
+	}
+	honest := func(tx LicenseTransaction) func(p peer.ID) (*SubmitResult, error) {
+		return func(p peer.ID) (*SubmitResult, error) {
+			result := validators[p].answerSubmit(tx)
+			return &result, nil
+		}
+	}
+
+	tx := upload("asset-submitted")
+	if result, _ := submit(tx, honest(tx)); !result.Accepted || result.ValidatorID != 0 {
+		t.Errorf("Expected validator 0 to accept, got %+v", result)
+	}
+
+	// The first validator can't be reached, and the second one signs as someone else
+	tx = upload("asset-retried")
+	result, _ := submit(tx, func(p peer.ID) (*SubmitResult, error) {
+		switch p {
+		case peers[0]:
+			return nil, errors.New("connection refused")
+		case peers[1]:
+			forged := SubmitResult{TxID: tx.TxID, ValidatorID: 3, Accepted: true}
+			forged.Signature = SignSubmitResult(net.keys[1], &forged)
+			return &forged, nil
+		}
+		return honest(tx)(p)
+	})
+	if !result.Accepted || result.ValidatorID != 2 {
+		t.Errorf("Expected validator 2 to accept, got %+v", result)
+	}
+
+	// Every validator is asked before the transaction is given up on
+	net.upload(t, "asset-duplicate")
+	tx = upload("asset-duplicate")
+	result, gossiped := submit(tx, honest(tx))
+	if result.Accepted || result.Gossiped || len(result.Rejections) != 4 || len(gossiped) != 0 {
+		t.Errorf("Expected rejections of all 4 validators, got %+v", result)
+	}
+	if result.Rejections[3] != RejectDuplicateAsset {
+		t.Errorf("Validator 3 rejected with %q", result.Rejections[3])
+	}
+
+	tx = upload("asset-gossiped")
+	result, gossiped = submit(tx, func(peer.ID) (*SubmitResult, error) { return nil, errors.New("connection refused") })
+	if !result.Gossiped || len(gossiped) != 1 || gossiped[0].TxID != tx.TxID {
+		t.Errorf("Expected the transaction to be gossiped, got %+v", result)
+	}
+}
@@ -2,13 +2,14 @@ package core
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"encoding/json"
-	"hash/fnv"
+	"fmt"
 	"log"
 	"sync"
 	"time"
 
-	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/network"
 )
 
 type Validator struct {
@@ -16,16 +17,16 @@ type Validator struct {
 	Node       *Node
 	VotePool   map[string]int // Track votes per transaction
 	mu         sync.Mutex
-	PublicKey  string      // The validator's public key
-	PrivateKey interface{} // The validator's private key
-	Mempool    *Mempool    // Add this field
+	PublicKey  string            // The validator's public key
+	PrivateKey *ecdsa.PrivateKey // The validator's private key, signs its votes
+	Mempool    *Mempool          // Add this field
+	Consensus  Consensus         // Set by StartConsensus
+	Pipeline   *TxPipeline       // Set by StartConsensus
+
+	evidenceNonce uint64      // Next nonce for evidence transactions, ahead of the chain while some are pending
+	missing       chan string // Transactions votes arrived for, to fetch from peers
 }
 
-var (
-	topicJoin     *pubsub.Topic
-	topicJoinOnce sync.Once
-)
-
 // Find a transaction by ID from the mempool
 func (v *Validator) findTransactionByID(txID string) *LicenseTransaction {
 	if v.Mempool == nil {
@@ -36,14 +37,7 @@ func (v *Validator) findTransactionByID(txID string) *LicenseTransaction {
 	return v.Mempool.GetTransactionByID(txID)
 }
 
-// electProposer selects a proposer deterministically based on TxID
-func electProposer(txID string, totalValidators int) int {
-	h := fnv.New32a()
-	h.Write([]byte(txID))
-	return int(h.Sum32()) % totalValidators
-}
-
-func NewValidator(id int, node *Node, publicKey string, privateKey interface{}, mempool *Mempool) *Validator {
+func NewValidator(id int, node *Node, publicKey string, privateKey *ecdsa.PrivateKey, mempool *Mempool) *Validator {
 	return &Validator{
 		ID:         id,
 		Node:       node,
@@ -51,203 +45,169 @@ func NewValidator(id int, node *Node, publicKey string, privateKey interface{},
 		PublicKey:  publicKey,
 		PrivateKey: privateKey,
 		Mempool:    mempool,
+		missing:    make(chan string, 1024),
 	}
 }
 
-func (v *Validator) StartConsensus(ctx context.Context, blockchain *Blockchain) {
-	log.Printf("Validator %d starting consensus process", v.ID)
-
-	go v.handleTransactions(ctx, blockchain)
-
-	go v.handleVotes(ctx, blockchain)
-}
-
-func (v *Validator) handleTransactions(ctx context.Context, blockchain *Blockchain) {
-	topicJoinOnce.Do(func() {
-		var err error
-		topicJoin, err = v.Node.PubSub.Join("transactions")
-		if err != nil {
-			log.Printf("Validator %d failed to join transactions topic: %v", v.ID, err)
-			return
-		}
+// StartConsensus runs the consensus the genesis of the chain asks for
+func (v *Validator) StartConsensus(ctx context.Context, blockchain *Blockchain) error {
+	validators := blockchain.Validators()
+	if validators == nil {
+		return fmt.Errorf("validator %d: chain has no genesis", v.ID)
+	}
+	kind := validators.Params().Consensus
+
+	consensus, err := NewConsensus(kind, ConsensusConfig{
+		ID:         v.ID,
+		PrivateKey: v.PrivateKey,
+		Blockchain: blockchain,
+		Mempool:    v.Mempool,
+		Broadcast:  v.broadcastConsensus,
+		OnCommit:   v.broadcastBlockchainUpdate,
+		OnReceipt: func(receipt Receipt) {
+			v.recordReceipt(blockchain, receipt)
+		},
+		OnEvidence: func(evidence Evidence) {
+			// Submitting goes through the consensus, which is busy calling this
+			go v.submitEvidence(blockchain, evidence)
+		},
+		OnMissing: v.requestMissing,
 	})
-
-	if topicJoin == nil {
-		log.Printf("Validator %d: Topic join failed earlier", v.ID)
-		return
+	if err != nil {
+		return fmt.Errorf("validator %d: %w", v.ID, err)
 	}
+	v.Consensus = consensus
 
-	txSub, err := topicJoin.Subscribe()
-	if err != nil {
-		log.Printf("Validator %d failed to subscribe to transactions: %v", v.ID, err)
-		return
+	v.Pipeline = NewTxPipeline(v.ID, v.Mempool, consensus)
+	v.Pipeline.OnReceipt = func(receipt Receipt) {
+		v.recordReceipt(blockchain, receipt)
 	}
 
-	for {
-		select {
-		case <-ctx.Done():
-			log.Printf("Validator %d transaction handler exiting", v.ID)
-			return
-		default:
-			msg, err := txSub.Next(ctx)
-			if err != nil {
-				log.Printf("Validator %d error reading transaction: %v", v.ID, err)
-				continue
-			}
+	if kind == "" {
+		kind = ConsensusVote
+	}
+	log.Printf("Validator %d starting %s consensus", v.ID, kind)
 
-			if msg.ReceivedFrom == v.Node.Host.ID() {
-				continue
-			}
+	// Before anything else can admit transactions, so the journal is only read here
+	restored := v.Mempool.Restore()
 
-			var transaction LicenseTransaction
-			if err := json.Unmarshal(msg.Data, &transaction); err != nil {
-				log.Printf("Validator %d received invalid transaction format: %v", v.ID, err)
-				continue
-			}
+	v.Node.Host.SetStreamHandler(SubmitProtocol, v.handleSubmitStream)
+	v.Node.Host.SetStreamHandler(BlockSyncProtocol, func(s network.Stream) { v.handleBlockSyncStream(blockchain, s) })
+	v.startMempoolSync(ctx)
 
-			v.Mempool.AddTransaction(transaction)
-			log.Printf("Validator %d received transaction %s", v.ID, transaction.TxID)
+	go v.Consensus.Run(ctx)
 
-			if ValidateTransaction(transaction) {
-				vote := VoteMessage{
-					TxID:        transaction.TxID,
-					ValidatorID: v.ID,
-					Timestamp:   time.Now().Unix(),
-					Approved:    true,
-				}
-				v.broadcastVote(vote)
-			} else {
-				log.Printf("Validator %d rejected transaction %s", v.ID, transaction.TxID)
-			}
+	go v.Pipeline.Run(ctx)
 
-			select {
-			case <-time.After(2 * time.Second):
-			case <-ctx.Done():
-				log.Printf("Validator %d transaction handler exiting after sleep", v.ID)
-				return
-			}
-		}
+	// Checked before they are relayed, so invalid ones don't spread and count against their sender
+	if err := v.Node.Check(MessageTransaction, func(env *Envelope) error { return checkGossipedTransaction(blockchain, env) }); err != nil {
+		return fmt.Errorf("validator %d: %w", v.ID, err)
+	}
+	if err := v.Node.Check(MessageConsensus, func(env *Envelope) error { return v.Consensus.Validate(env.Payload) }); err != nil {
+		return fmt.Errorf("validator %d: %w", v.ID, err)
+	}
 
-		time.Sleep(4 * time.Second)
+	if err := v.Node.Handle(MessageTransaction, func(env *Envelope) { v.handleTransaction(ctx, env) }); err != nil {
+		return fmt.Errorf("validator %d: %w", v.ID, err)
+	}
+	// Consensus messages of the other validators go straight to the consensus
+	if err := v.Node.Handle(MessageConsensus, func(env *Envelope) { v.Consensus.Receive(env.Payload) }); err != nil {
+		return fmt.Errorf("validator %d: %w", v.ID, err)
 	}
-}
 
-type VoteMessage struct {
-	TxID        string
-	ValidatorID int
-	Timestamp   int64
-	Approved    bool
+	// Pending transactions of the last run, the others may have missed them or lost them too
+	for _, tx := range restored {
+		v.Consensus.Propose(tx)
+		v.Node.BroadcastTransaction(tx)
+	}
+	return nil
 }
 
-func (v *Validator) broadcastVote(vote VoteMessage) {
-	voteData, err := json.Marshal(vote)
-	if err != nil {
-		log.Printf("Validator %d error marshaling vote: %v", v.ID, err)
+// Feed a transaction gossiped by another node into the pipeline, waiting while it is full
+func (v *Validator) handleTransaction(ctx context.Context, env *Envelope) {
+	var transaction LicenseTransaction
+	if err := env.Decode(&transaction); err != nil {
+		log.Printf("Validator %d received invalid transaction format: %v", v.ID, err)
 		return
 	}
 
-	ctx := context.Background()
-	if err := v.Node.VoteTopic.Publish(ctx, voteData); err != nil {
-		log.Printf("Validator %d error publishing vote: %v", v.ID, err)
-	} else {
-		log.Printf("Validator %d broadcast vote for transaction %s", v.ID, vote.TxID)
+	if err := v.Pipeline.Submit(ctx, transaction); err != nil {
+		log.Printf("Validator %d dropped transaction %s: %v", v.ID, transaction.TxID, err)
 	}
 }
 
-func (v *Validator) handleVotes(ctx context.Context, blockchain *Blockchain) {
-	for {
-		select {
-		case <-ctx.Done():
-			log.Printf("Validator %d vote handler exiting", v.ID)
-			return
-		default:
-			msg, err := v.Node.VoteSub.Next(ctx)
-			if err != nil {
-				time.Sleep(2 * time.Second)
-				continue
-			}
-
-			var vote VoteMessage
-			if err := json.Unmarshal(msg.Data, &vote); err != nil {
-				var oldVote map[string]string
-				if err2 := json.Unmarshal(msg.Data, &oldVote); err2 != nil {
-					log.Printf("Validator %d received invalid vote format: %v", v.ID, err)
-				}
-				continue
-			}
-
-			log.Printf("Validator %d received vote for transaction %s from validator %d",
-				v.ID, vote.TxID, vote.ValidatorID)
-
-			blockchain.mu.Lock()
-			if blockchain.VoteCount[vote.TxID] == nil {
-				blockchain.VoteCount[vote.TxID] = make(map[int]bool)
-			}
-			blockchain.VoteCount[vote.TxID][vote.ValidatorID] = vote.Approved
-			voteCount := len(blockchain.VoteCount[vote.TxID])
-			blockchain.mu.Unlock()
-
-			if voteCount == 5 {
-				blockchain.mu.Lock()
-				approvals := 0
-				for _, approved := range blockchain.VoteCount[vote.TxID] {
-					if approved {
-						approvals++
-					}
-				}
-				blockchain.mu.Unlock()
+// Reject gossiped transactions that can never be valid, the pipeline checks them against the state
+func checkGossipedTransaction(blockchain *Blockchain, env *Envelope) error {
+	var transaction LicenseTransaction
+	if err := env.Decode(&transaction); err != nil {
+		return fmt.Errorf("invalid transaction format: %w", err)
+	}
+	if !ValidateTransaction(transaction) {
+		return fmt.Errorf("transaction %s is malformed or badly signed", transaction.TxID)
+	}
+	if height, _ := blockchain.FindTransaction(transaction.TxID); height >= 0 {
+		return fmt.Errorf("%w: transaction %s is already in block %d", ErrStaleMessage, transaction.TxID, height)
+	}
+	return nil
+}
 
-				if approvals >= 4 {
-					tx := v.findTransactionByID(vote.TxID)
-					if tx != nil {
-						if RegisterLicense(*tx, blockchain) {
-							proposerID := electProposer(vote.TxID, 5)
-							if v.ID == proposerID {
-								tx.IsValidated = true
-								tx.ValidatorID = v.ID
-								blockchain.AddTransaction(*tx)
-								v.broadcastBlockchainUpdate(blockchain.Blocks[len(blockchain.Blocks)-1])
-							} else {
-								log.Printf("Validator %d: Not proposer for transaction %s", v.ID, vote.TxID)
-							}
-							v.Mempool.RemoveTransaction(vote.TxID)
-						}
-					} else {
-						log.Printf("Validator %d: Transaction %s not found in mempool", v.ID, vote.TxID)
-					}
-				} else {
-					log.Printf("Transaction %s rejected: only %d approvals", vote.TxID, approvals)
-					v.Mempool.RemoveTransaction(vote.TxID)
-				}
-			}
+// processTransaction admits a transaction into the mempool and proposes it, or votes against it,
+// returning whether it was accepted and why not
+func (v *Validator) processTransaction(transaction LicenseTransaction) (bool, string) {
+	return v.Pipeline.Process(transaction)
+}
 
-			select {
-			case <-time.After(4 * time.Second):
-			case <-ctx.Done():
-				log.Printf("Validator %d vote handler exiting after sleep", v.ID)
-				return
-			}
-		}
+// Report misbehavior seen by this validator to the network in an evidence transaction
+func (v *Validator) submitEvidence(blockchain *Blockchain, evidence Evidence) {
+	v.mu.Lock()
+	nonce := max(NextNonce(blockchain, v.PublicKey), v.evidenceNonce)
+	v.evidenceNonce = nonce + 1
+	v.mu.Unlock()
+
+	tx := LicenseTransaction{
+		Owner:     v.PublicKey,
+		Metadata:  NewEvidenceMetadata(evidence),
+		Timestamp: time.Now().Unix(),
+		Nonce:     nonce,
+		TxType:    TxTypeEvidence,
+	}
+	tx.TxID = GenerateTransactionID(tx)
+	tx.Signature = SignTransaction(v.PrivateKey, &tx)
 
-		time.Sleep(4 * time.Second)
+	if accepted, reason := v.processTransaction(tx); !accepted {
+		log.Printf("Validator %d didn't submit evidence against validator %d: %s", v.ID, evidence.Offender(), reason)
+		return
 	}
+	log.Printf("Validator %d submitted evidence against validator %d in transaction %s", v.ID, evidence.Offender(), tx.TxID)
+	v.Node.BroadcastTransaction(tx)
 }
 
-// Broadcast blockchain update to all nodes
-func (v *Validator) broadcastBlockchainUpdate(block *Block) {
-	updateMsg := map[string]interface{}{
-		"type":  "block_update",
-		"block": block,
+func (v *Validator) broadcastConsensus(data []byte) {
+	if err := v.Node.Publish(context.Background(), MessageConsensus, json.RawMessage(data)); err != nil {
+		log.Printf("Validator %d error publishing consensus message: %v", v.ID, err)
 	}
+}
 
-	msgData, err := json.Marshal(updateMsg)
-	if err != nil {
-		log.Printf("Validator %d error creating block update message: %v", v.ID, err)
+// Persist a receipt locally, and share final ones so clients learn the outcome of the transaction
+func (v *Validator) recordReceipt(blockchain *Blockchain, receipt Receipt) {
+	receipt.Timestamp = time.Now().Unix()
+	blockchain.SaveReceipt(receipt)
+	if !IsFinal(receipt.Status) {
 		return
 	}
 
-	ctx := context.Background()
-	if err := v.Node.Topic.Publish(ctx, msgData); err != nil {
+	signed := SignedReceipt{Receipt: receipt, ValidatorID: v.ID}
+	signed.Signature = SignReceipt(v.PrivateKey, &signed)
+	if err := v.Node.Publish(context.Background(), MessageReceipt, signed); err != nil {
+		log.Printf("Validator %d error broadcasting receipt: %v", v.ID, err)
+	}
+}
+
+// Broadcast blockchain update to all nodes, signed so they know a validator added the block
+func (v *Validator) broadcastBlockchainUpdate(block *Block) {
+	update := BlockUpdate{Block: block, ValidatorID: v.ID}
+	update.Signature = SignBlockUpdate(v.PrivateKey, &update)
+	if err := v.Node.Publish(context.Background(), MessageBlock, update); err != nil {
 		log.Printf("Validator %d error broadcasting block update: %v", v.ID, err)
 	} else {
 		log.Printf("Validator %d broadcast block update: %s", v.ID, block.Hash)
@@ -255,7 +215,7 @@ func (v *Validator) broadcastBlockchainUpdate(block *Block) {
 }
 
 func ValidateTransaction(tx LicenseTransaction) bool {
-	if tx.Owner == "" || tx.AssetHash == "" {
+	if tx.Owner == "" || (IsAssetTransaction(tx.TxType) && tx.AssetHash == "") {
 		return false
 	}
 
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Default file names inside a validator directory
+const (
+	ValidatorConfigFile = "validator.json"
+	ValidatorKeyFile    = "validator_key.json"
+	ValidatorDataDir    = "db"
+)
+
+// ValidatorConfig describes how to run one validator process
+type ValidatorConfig struct {
+	ID            int      `json:"id"`
+	KeyFile       string   `json:"key_file"`                 // Encrypted keystore holding the validator key
+	DataDir       string   `json:"data_dir"`                 // BadgerDB directory of the validator's chain
+	ListenAddrs   []string `json:"listen_addrs,omitempty"`   // libp2p multiaddrs, random ports if empty
+	AnnounceAddrs []string `json:"announce_addrs,omitempty"` // Multiaddrs advertised to peers instead of the listen ones
+	Peers         []string `json:"peers,omitempty"`          // Static peer multiaddrs (with /p2p/<id>), kept connected
+	NoMDNS        bool     `json:"no_mdns,omitempty"`        // Only connect to Peers, without multicast discovery
+	Genesis       string   `json:"genesis,omitempty"`        // Genesis file listing the validator set
+
+	Mempool MempoolConfig `json:"mempool,omitzero"` // Mempool limits, the defaults if unset
+
+	// Directory of the config file, relative paths are resolved against it
+	dir string
+}
+
+// Load a validator config, resolving relative paths against its directory
+func LoadValidatorConfig(path string) (*ValidatorConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no validator config at %s, create one with \"drmcli validator init\"", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read validator config: %w", err)
+	}
+
+	var cfg ValidatorConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse validator config %s: %w", path, err)
+	}
+	if cfg.ID < 0 {
+		return nil, fmt.Errorf("invalid validator ID %d in %s", cfg.ID, path)
+	}
+	if cfg.KeyFile == "" || cfg.DataDir == "" {
+		return nil, fmt.Errorf("validator config %s needs key_file and data_dir", path)
+	}
+
+	cfg.dir = filepath.Dir(path)
+	return &cfg, nil
+}
+
+// Write the config, creating its directory if needed
+func (c *ValidatorConfig) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode validator config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create validator directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write validator config: %w", err)
+	}
+	c.dir = filepath.Dir(path)
+	return nil
+}
+
+func (c *ValidatorConfig) resolve(path string) string {
+	if filepath.IsAbs(path) || c.dir == "" {
+		return path
+	}
+	return filepath.Join(c.dir, path)
+}
+
+// Path of the validator's keystore
+func (c *ValidatorConfig) KeyPath() string {
+	return c.resolve(c.KeyFile)
+}
+
+// Path of the genesis file, empty if none is configured
+func (c *ValidatorConfig) GenesisPath() string {
+	if c.Genesis == "" {
+		return ""
+	}
+	return c.resolve(c.Genesis)
+}
+
+// Path of the validator's database
+func (c *ValidatorConfig) DataPath() string {
+	return c.resolve(c.DataDir)
+}
+
+// Path of the libp2p key in the data directory, it fixes the validator's peer ID
+func (c *ValidatorConfig) NodeKeyPath() string {
+	return filepath.Join(c.DataPath(), NodeKeyFile)
+}
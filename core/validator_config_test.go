@@ -0,0 +1,32 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestValidatorConfigResolvesRelativePaths(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "validator_3")
+	path := filepath.Join(dir, ValidatorConfigFile)
+
+	cfg := &ValidatorConfig{ID: 3, KeyFile: ValidatorKeyFile, DataDir: ValidatorDataDir, ListenAddrs: []string{"/ip4/127.0.0.1/tcp/4003"}}
+	if err := cfg.Save(path); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	loaded, err := LoadValidatorConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if loaded.ID != 3 || len(loaded.ListenAddrs) != 1 {
+		t.Errorf("Config didn't round-trip: %+v", loaded)
+	}
+	if loaded.KeyPath() != filepath.Join(dir, ValidatorKeyFile) || loaded.DataPath() != filepath.Join(dir, ValidatorDataDir) {
+		t.Errorf("Paths not resolved against the config directory: %s, %s", loaded.KeyPath(), loaded.DataPath())
+	}
+
+	loaded.DataDir = "/var/lib/drm"
+	if loaded.DataPath() != "/var/lib/drm" {
+		t.Error("Absolute data dir was changed")
+	}
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ValidatorInfo identifies one member of the validator set
+type ValidatorInfo struct {
+	ID        int    `json:"id"`
+	PublicKey string `json:"public_key"`
+}
+
+// ValidatorSet is the group of validators whose votes decide on transactions
+type ValidatorSet struct {
+	members []ValidatorInfo // Sorted by ID
+	quorum  int
+	params  ConsensusParams
+}
+
+// NewValidatorSet builds a set, a zero quorum picks the Byzantine default
+func NewValidatorSet(members []ValidatorInfo, params ConsensusParams) (*ValidatorSet, error) {
+	sorted := append([]ValidatorInfo(nil), members...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	set := &ValidatorSet{members: sorted, quorum: params.Quorum, params: params}
+	if set.quorum == 0 {
+		set.quorum = DefaultQuorum(len(sorted))
+	}
+	if set.quorum < 1 || set.quorum > len(sorted) {
+		return nil, fmt.Errorf("quorum %d is impossible with %d validators", set.quorum, len(sorted))
+	}
+	if minimum := MinQuorum(len(sorted), params.Consensus); set.quorum < minimum {
+		return nil, fmt.Errorf("quorum %d of %d validators is unsafe, at least %d needed", set.quorum, len(sorted), minimum)
+	}
+	return set, nil
+}
+
+// MinQuorum is the smallest safe quorum of n validators: any two quorums have to share a
+// validator, an honest one unless the consensus only tolerates crashes
+func MinQuorum(n int, consensus string) int {
+	if consensus == ConsensusRaft {
+		return n/2 + 1
+	}
+	return DefaultQuorum(n)
+}
+
+// Whether a quorum fixed for another set size still works with n validators
+func quorumFits(quorum, n int, consensus string) bool {
+	return quorum <= n && quorum >= MinQuorum(n, consensus)
+}
+
+// MaxFaulty is the number of Byzantine validators n validators tolerate, n = 3f+1
+func MaxFaulty(n int) int {
+	if n < 1 {
+		return 0
+	}
+	return (n - 1) / 3
+}
+
+// DefaultQuorum is the smallest number of votes such that any two quorums share an honest
+// validator, 2f+1 when n = 3f+1
+func DefaultQuorum(n int) int {
+	return (n+MaxFaulty(n))/2 + 1
+}
+
+func (s *ValidatorSet) Size() int {
+	return len(s.members)
+}
+
+func (s *ValidatorSet) Quorum() int {
+	return s.quorum
+}
+
+// Params the set was configured with
+func (s *ValidatorSet) Params() ConsensusParams {
+	return s.params
+}
+
+// Members of the set, sorted by ID
+func (s *ValidatorSet) Members() []ValidatorInfo {
+	return append([]ValidatorInfo(nil), s.members...)
+}
+
+// Key returns the public key of a validator in the set
+func (s *ValidatorSet) Key(id int) (string, bool) {
+	for _, member := range s.members {
+		if member.ID == id {
+			return member.PublicKey, true
+		}
+	}
+	return "", false
+}
+
+// HasKey reports whether pubKey belongs to a validator in the set
+func (s *ValidatorSet) HasKey(pubKey string) bool {
+	for _, member := range s.members {
+		if member.PublicKey == pubKey {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package core
+
+import "testing"
+
+func TestDefaultQuorum(t *testing.T) {
+	// n -> votes needed so that two quorums always share an honest validator
+	cases := map[int]int{1: 1, 2: 2, 3: 2, 4: 3, 5: 4, 6: 4, 7: 5, 10: 7}
+	for n, want := range cases {
+		if got := DefaultQuorum(n); got != want {
+			t.Errorf("DefaultQuorum(%d) = %d, want %d", n, got, want)
+		}
+	}
+}
+
+func TestGenesisFixesValidatorSet(t *testing.T) {
+	_, members := newTestValidators(t, 4)
+
+	if _, err := NewValidatorSet(members, ConsensusParams{Quorum: 5}); err == nil {
+		t.Error("Quorum larger than the set was accepted")
+	}
+	// Two quorums of 2 out of 4 can be disjoint, 3 still lets a Byzantine validator tip them apart
+	for _, params := range []ConsensusParams{{Quorum: 2}, {Quorum: 2, Consensus: ConsensusBFT}, {Quorum: 2, Consensus: ConsensusRaft}} {
+		if _, err := NewValidatorSet(members, params); err == nil {
+			t.Errorf("Unsafe quorum %d for %q consensus was accepted", params.Quorum, params.Consensus)
+		}
+	}
+	if _, err := NewValidatorSet(members[:3], ConsensusParams{Quorum: 2, Consensus: ConsensusRaft}); err != nil {
+		t.Errorf("Majority quorum for raft was refused: %v", err)
+	}
+
+	genesis := &Genesis{Validators: members}
+	bc := newTestBlockchain(t)
+	if bc.Validators() != nil {
+		t.Fatal("Chain without genesis shouldn't have validators")
+	}
+	if err := bc.InitGenesis(genesis); err != nil {
+		t.Fatalf("Failed to init genesis: %v", err)
+	}
+
+	validators := bc.Validators()
+	if validators.Size() != 4 || validators.Quorum() != 3 {
+		t.Errorf("Expected 3 of 4, got %d of %d", validators.Quorum(), validators.Size())
+	}
+	if !validators.HasKey(members[2].PublicKey) {
+		t.Error("Genesis validator key not in the set")
+	}
+
+	// Restarting with another validator set must fail
+	other := &Genesis{Validators: members[:3]}
+	if err := bc.InitGenesis(other); err == nil {
+		t.Error("Chain accepted a different genesis")
+	}
+	if err := bc.InitGenesis(genesis); err != nil {
+		t.Errorf("Same genesis was refused: %v", err)
+	}
+}
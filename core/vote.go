@@ -0,0 +1,125 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	EvidencePrefix = "evidence-"
+	OwnVotePrefix  = "own-vote-" // Votes this validator cast, sent again instead of voting anew after a restart
+)
+
+type VoteMessage struct {
+	TxID        string
+	ValidatorID int
+	Timestamp   int64
+	Approved    bool
+	Reason      string `json:",omitempty"` // Why the validator rejected the transaction
+	Started     int64  // Start of the validator's run, only votes of one run are evidence against it
+	Signature   string // Signature of the validator's key over the fields above
+}
+
+// VoteEvidence holds two signed votes of one validator on the same transaction that disagree
+type VoteEvidence struct {
+	ValidatorID int
+	First       VoteMessage
+	Second      VoteMessage
+}
+
+func voteDigest(vote *VoteMessage) []byte {
+	data := fmt.Sprintf("vote|%s|%d|%d|%t|%s|%d", vote.TxID, vote.ValidatorID, vote.Timestamp, vote.Approved, vote.Reason, vote.Started)
+	hash := sha256.Sum256([]byte(data))
+	return hash[:]
+}
+
+// SignVote signs a vote with the validator's key
+func SignVote(privKey *ecdsa.PrivateKey, vote *VoteMessage) string {
+	return signDigest(privKey, voteDigest(vote))
+}
+
+// VerifyVote checks that a vote is signed by the key the validator set has for its sender
+func VerifyVote(vote VoteMessage, validators *ValidatorSet) bool {
+	if validators == nil {
+		return false
+	}
+	pubKey, ok := validators.Key(vote.ValidatorID)
+	if !ok {
+		return false
+	}
+	return verifySignature(pubKey, vote.Signature, voteDigest(&vote))
+}
+
+// Record a verified vote, returning false if the validator already voted on the transaction.
+// A second vote with a different decision in the same run of the validator is kept as evidence.
+func (bc *Blockchain) recordVote(vote VoteMessage) (bool, *VoteEvidence) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if bc.Votes[vote.TxID] == nil {
+		bc.Votes[vote.TxID] = make(map[int]VoteMessage)
+	}
+
+	first, voted := bc.Votes[vote.TxID][vote.ValidatorID]
+	if !voted {
+		bc.Votes[vote.TxID][vote.ValidatorID] = vote
+		return true, nil
+	}
+	if first.Approved == vote.Approved || first.Started != vote.Started {
+		// A validator that lost its votes in a restart may vote again, only the first one counts
+		return false, nil
+	}
+
+	evidence := &VoteEvidence{ValidatorID: vote.ValidatorID, First: first, Second: vote}
+	bc.saveEvidence(Evidence{Vote: evidence})
+	return false, evidence
+}
+
+// Vote this validator cast on a transaction, nil if it didn't vote on it
+func (bc *Blockchain) ownVote(txID string) *VoteMessage {
+	data, err := bc.db.Load(OwnVotePrefix + txID)
+	if err != nil {
+		return nil
+	}
+	var vote VoteMessage
+	if err := json.Unmarshal(data, &vote); err != nil {
+		return nil
+	}
+	return &vote
+}
+
+func (bc *Blockchain) saveOwnVote(vote VoteMessage) error {
+	data, _ := json.Marshal(vote)
+	return bc.db.Save(OwnVotePrefix+vote.TxID, data)
+}
+
+// Count the approvals and rejections recorded for a transaction
+func (bc *Blockchain) voteTally(txID string) (approvals, rejections int) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	for _, vote := range bc.Votes[txID] {
+		if vote.Approved {
+			approvals++
+		} else {
+			rejections++
+		}
+	}
+	return approvals, rejections
+}
+
+// Approvals recorded for a transaction, the proof a block may include it
+func (bc *Blockchain) approvals(txID string) []VoteMessage {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	var votes []VoteMessage
+	for _, vote := range bc.Votes[txID] {
+		if vote.Approved {
+			votes = append(votes, vote)
+		}
+	}
+	return votes
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVotesMustBeSignedByKnownValidator(t *testing.T) {
+	privKey, pubKey := GenerateKeyPair()
+	forger, _ := GenerateKeyPair()
+	validators, err := NewValidatorSet([]ValidatorInfo{{ID: 9001, PublicKey: pubKey}}, ConsensusParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vote := VoteMessage{TxID: "tx-vote", ValidatorID: 9001, Timestamp: time.Now().Unix(), Approved: true}
+	vote.Signature = SignVote(privKey, &vote)
+	if !VerifyVote(vote, validators) {
+		t.Fatal("Vote signed by the validator was rejected")
+	}
+
+	forged := vote
+	forged.Signature = SignVote(forger, &forged)
+	if VerifyVote(forged, validators) {
+		t.Error("Vote signed by another key was accepted")
+	}
+
+	flipped := vote
+	flipped.Approved = false
+	if VerifyVote(flipped, validators) {
+		t.Error("Vote with a changed decision was accepted")
+	}
+
+	unknown := VoteMessage{TxID: "tx-vote", ValidatorID: 9002, Timestamp: vote.Timestamp, Approved: true}
+	unknown.Signature = SignVote(privKey, &unknown)
+	if VerifyVote(unknown, validators) {
+		t.Error("Vote from an unknown validator was accepted")
+	}
+}
+
+func TestConflictingVotesAreEvidence(t *testing.T) {
+	bc := newTestBlockchain(t)
+	privKey, _ := GenerateKeyPair()
+
+	approve := VoteMessage{TxID: "tx-conflict", ValidatorID: 1, Timestamp: time.Now().Unix(), Approved: true}
+	approve.Signature = SignVote(privKey, &approve)
+	reject := approve
+	reject.Approved = false
+	reject.Signature = SignVote(privKey, &reject)
+
+	if counted, _ := bc.recordVote(approve); !counted {
+		t.Fatal("First vote wasn't counted")
+	}
+	if counted, evidence := bc.recordVote(approve); counted || evidence != nil {
+		t.Error("Repeated vote should be ignored without evidence")
+	}
+
+	counted, evidence := bc.recordVote(reject)
+	if counted || evidence == nil {
+		t.Fatal("Conflicting vote should produce evidence")
+	}
+	if evidence.First.Approved != true || evidence.Second.Approved != false {
+		t.Errorf("Unexpected evidence %+v", evidence)
+	}
+	if data, err := bc.db.Load(EvidencePrefix + "vote-1-tx-conflict"); err != nil || len(data) == 0 {
+		t.Error("Evidence wasn't persisted")
+	}
+}
+
+func TestRestartedValidatorRepeatsItsVote(t *testing.T) {
+	keys, members := newTestValidators(t, 4)
+	bc := newTestBlockchain(t)
+	if err := bc.InitGenesis(&Genesis{Validators: members}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := ConsensusConfig{ID: 1, PrivateKey: keys[1], Blockchain: bc, Mempool: NewMempool(bc, MempoolConfig{})}
+
+	privKey, pubKey := GenerateKeyPair()
+	tx := sign(bc, LicenseTransaction{Owner: pubKey, AssetHash: "asset-revoted", License: "view", TxType: TxTypeUpload}, privKey)
+
+	first := newVoteConsensus(cfg)
+	first.Propose(tx)
+	approve := <-first.outbox
+
+	// Restored from the mempool journal and checked again, it now fails a check
+	restarted := newVoteConsensus(cfg)
+	restarted.Reject(tx, RejectDuplicateAsset)
+	if vote := <-restarted.outbox; vote.Signature != approve.Signature || !vote.Approved {
+		t.Errorf("Expected the approval to be sent again, got %+v", vote)
+	}
+
+	// A validator that lost its votes isn't punished for voting differently after a restart
+	reject := VoteMessage{TxID: tx.TxID, ValidatorID: 1, Timestamp: time.Now().Unix(), Approved: false, Started: restarted.started}
+	reject.Signature = SignVote(keys[1], &reject)
+	bc.recordVote(approve)
+	if counted, evidence := bc.recordVote(reject); counted || evidence != nil {
+		t.Errorf("Votes of different runs counted %t with evidence %+v", counted, evidence)
+	}
+	evidence := Evidence{Vote: &VoteEvidence{ValidatorID: 1, First: approve, Second: reject}}
+	if err := evidence.Verify(bc.Validators()); err == nil {
+		t.Error("Evidence from votes of different runs was accepted")
+	}
+}
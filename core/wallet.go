@@ -11,6 +11,7 @@ import (
 	"log"
 	"math/big"
 	"sync"
+	"time"
 )
 
 // LicenseTransaction struct with additional fields
@@ -29,6 +30,12 @@ type LicenseTransaction struct {
 	Nonce       uint64 // We can use this for transaction replay protection
 	TxType      string // Transaction type: "upload", "purchase", etc.
 	// Price       float64 // a hypothetical blockchain, no we dont need price
+
+	// Key management fields, only set on rotate-key and set-recovery transactions
+	NewKey        string `json:",omitempty"` // Key that takes over from Owner
+	CoSignature   string `json:",omitempty"` // Signature of NewKey, proving it is held
+	RecoveryKey   string `json:",omitempty"` // Key allowed to rotate Owner without its signature
+	RecoveryDelay int64  `json:",omitempty"` // Seconds before a recovery rotation takes effect
 }
 
 // Global License Registry
@@ -45,108 +52,248 @@ func GenerateKeyPair() (*ecdsa.PrivateKey, string) {
 		return nil, ""
 	}
 
-	pubKey := append(privKey.PublicKey.X.Bytes(), privKey.PublicKey.Y.Bytes()...)
-	pubKeyHex := hex.EncodeToString(pubKey)
-
-	return privKey, pubKeyHex
+	return privKey, PublicKeyHex(&privKey.PublicKey)
 }
 
 // Generate a unique transaction ID
 func GenerateTransactionID(transaction LicenseTransaction) string {
-	data := transaction.Owner + transaction.AssetHash + transaction.License + fmt.Sprintf("%d", transaction.Timestamp)
+	return hex.EncodeToString(transactionDigest(&transaction))
+}
+
+// Every field the signer sets, quoted and delimited so none can be changed or moved into another.
+// The ID and the signatures are both over this digest, ValidatorID and IsValidated are left out
+// since the proposer fills them in.
+func transactionDigest(transaction *LicenseTransaction) []byte {
+	data := fmt.Sprintf("license-tx|%q|%q|%q|%q|%q|%q|%d|%d|%d|%q|%q|%d",
+		transaction.TxType, transaction.Owner, transaction.AssetHash, transaction.License,
+		transaction.Licensee, transaction.Metadata, transaction.Timestamp, transaction.Expiry,
+		transaction.Nonce, transaction.NewKey, transaction.RecoveryKey, transaction.RecoveryDelay)
 	hash := sha256.Sum256([]byte(data))
-	return hex.EncodeToString(hash[:])
+	return hash[:]
 }
 
 // Sign the license transaction
 func SignTransaction(privKey *ecdsa.PrivateKey, transaction *LicenseTransaction) string {
-	data := transaction.Owner + transaction.AssetHash + transaction.License + transaction.TxID + fmt.Sprintf("%d", transaction.Timestamp)
-	hash := sha256.Sum256([]byte(data))
+	return signDigest(privKey, transactionDigest(transaction))
+}
 
-	r, s, err := ecdsa.Sign(rand.Reader, privKey, hash[:])
+func signDigest(privKey *ecdsa.PrivateKey, hash []byte) string {
+	r, s, err := ecdsa.Sign(rand.Reader, privKey, hash)
 	if err != nil {
-		fmt.Println("Error signing transaction:", err)
+		fmt.Println("Error signing:", err)
 		return ""
 	}
 
-	signature := append(r.Bytes(), s.Bytes()...)
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
 	return hex.EncodeToString(signature)
 }
 
 // Verify the transaction signature
 func VerifyTransaction(transaction LicenseTransaction) bool {
-	pubKeyBytes, _ := hex.DecodeString(transaction.Owner)
+	// The ID is derived from the contents, so one ID can't stand for two transactions
+	if transaction.TxID != GenerateTransactionID(transaction) {
+		return false
+	}
+
+	if !verifySignature(TransactionSigner(transaction), transaction.Signature, transactionDigest(&transaction)) {
+		return false
+	}
+
+	// A rotation must also be signed by the key taking over
+	if transaction.TxType == TxTypeRotateKey {
+		return verifySignature(transaction.NewKey, transaction.CoSignature, transactionDigest(&transaction))
+	}
+	return true
+}
+
+func verifySignature(pubKeyHex, signatureHex string, hash []byte) bool {
+	pubKeyBytes, _ := hex.DecodeString(pubKeyHex)
+	if len(pubKeyBytes) != 64 {
+		return false
+	}
 	x, y := new(big.Int).SetBytes(pubKeyBytes[:32]), new(big.Int).SetBytes(pubKeyBytes[32:])
 	pubKey := ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
 
-	data := transaction.Owner + transaction.AssetHash + transaction.License + transaction.TxID + fmt.Sprintf("%d", transaction.Timestamp)
-	hash := sha256.Sum256([]byte(data))
-
-	signBytes, _ := hex.DecodeString(transaction.Signature)
+	signBytes, _ := hex.DecodeString(signatureHex)
+	if len(signBytes) != 64 {
+		return false
+	}
 	r, s := new(big.Int).SetBytes(signBytes[:32]), new(big.Int).SetBytes(signBytes[32:])
 
-	return ecdsa.Verify(&pubKey, hash[:], r, s)
+	return ecdsa.Verify(&pubKey, hash, r, s)
 }
 
 // Register a new license
 func RegisterLicense(transaction LicenseTransaction, bc *Blockchain) bool {
+	if !CheckLicense(transaction, bc) {
+		return false
+	}
+
+	if transaction.TxType == TxTypeUpload {
+		recordUpload(transaction, bc)
+	}
+	return true
+}
+
+func recordUpload(transaction LicenseTransaction, bc *Blockchain) {
+	licenseRegistry.Lock()
+	defer licenseRegistry.Unlock()
+
+	licenseRegistry.licenses[transaction.AssetHash] = transaction
+	jsonTransac, err := json.Marshal(transaction)
+	if err != nil {
+		log.Fatal("error occured")
+	}
+
+	bc.db.Save(transaction.AssetHash, jsonTransac)
+	fmt.Println("License registered:", transaction.AssetHash, "Owner:", transaction.Owner)
+}
+
+// CheckLicense validates a transaction against the chain state without recording it
+func CheckLicense(transaction LicenseTransaction, bc *Blockchain) bool {
+	return checkLicense(transaction, bc) == ""
+}
+
+// Check a transaction against the chain state, returning why it is rejected or "" if it is valid
+func checkLicense(transaction LicenseTransaction, bc *Blockchain) string {
+	return checkLicenseAt(transaction, bc, time.Now())
+}
+
+// Same as checkLicense for a transaction going into a block timestamped at
+func checkLicenseAt(transaction LicenseTransaction, bc *Blockchain, at time.Time) string {
 	if !VerifyTransaction(transaction) {
 		fmt.Println("Invalid license transaction")
-		return false
+		return RejectBadSignature
+	}
+	return checkStateAt(transaction, bc, at)
+}
+
+// Check a transaction whose signature was already verified against the chain state
+func checkState(transaction LicenseTransaction, bc *Blockchain) string {
+	return checkStateAt(transaction, bc, time.Now())
+}
+
+// Same as checkState for a transaction going into a block timestamped at. Blocks are checked
+// at their own time, so validators with different clocks agree on them.
+func checkStateAt(transaction LicenseTransaction, bc *Blockchain, at time.Time) string {
+	if transaction.Expiry != 0 && transaction.Expiry <= at.Unix() {
+		log.Println("Transaction already expired:", transaction.TxID)
+		return RejectExpired
 	}
 
 	licenseRegistry.Lock()
 	defer licenseRegistry.Unlock()
 
-	for _, block := range bc.Blocks {
+	signer := TransactionSigner(transaction)
+	keys := newKeyResolverAt(bc, at)
+	if keys.Resolve(signer) != signer {
+		log.Println("Signing key has been rotated away:", signer)
+		return RejectUnauthorized
+	}
+
+	for _, block := range bc.chain() {
 		for _, existingTx := range block.Transaction {
 			// Check for proper nonce sequence
-			if existingTx.Owner == transaction.Owner && existingTx.Nonce >= transaction.Nonce {
+			if TransactionSigner(existingTx) == signer && existingTx.Nonce >= transaction.Nonce {
 				log.Println("Invalid nonce")
-				return false
+				return RejectBadNonce
 			}
 		}
 	}
 
-	if transaction.TxType == "upload" {
-		if val, _ := bc.db.Load(transaction.AssetHash); val != nil {
+	switch transaction.TxType {
+	case TxTypeUpload:
+		if val, _ := bc.db.Load(transaction.AssetHash); val != nil || FindUpload(transaction.AssetHash, bc) != nil {
 			log.Println("License already exists for asset:", transaction.AssetHash)
-			return false
+			return RejectDuplicateAsset
 		}
-
-		licenseRegistry.licenses[transaction.AssetHash] = transaction
-		jsonTransac, err := json.Marshal(transaction)
-		if err != nil {
-			log.Fatal("error occured")
+	case TxTypePurchase:
+		upload := FindUpload(transaction.AssetHash, bc)
+		if upload == nil {
+			log.Println("The asset doesn't exists:", transaction.AssetHash)
+			return RejectUnknownAsset
 		}
-
-		bc.db.Save(transaction.AssetHash, jsonTransac)
-		fmt.Println("License registered:", transaction.AssetHash, "Owner:", transaction.Owner)
-	} else {
-		if val, _ := bc.db.Load(transaction.AssetHash); val == nil {
-			log.Fatal("The asset doesn't exists")
+		if keys.Resolve(upload.Owner) != keys.Resolve(transaction.Owner) {
+			log.Println("Invalid transaction: owner mismatch for asset", transaction.AssetHash)
+			return RejectUnauthorized
 		}
-
-		for _, block := range bc.Blocks {
-			for _, existingTx := range block.Transaction {
-				if existingTx.AssetHash == transaction.AssetHash && existingTx.Owner != transaction.Owner {
-					log.Fatal("Invalid transaction")
-				}
-			}
+		if status := AssetStatus(transaction.AssetHash, bc); status != AssetActive {
+			log.Printf("Asset %s is %s and can't be purchased", transaction.AssetHash, status)
+			return RejectInvalid
+		}
+	case TxTypeDelist:
+		upload := FindUpload(transaction.AssetHash, bc)
+		if upload == nil {
+			log.Println("The asset doesn't exists:", transaction.AssetHash)
+			return RejectUnknownAsset
+		}
+		if keys.Resolve(upload.Owner) != transaction.Owner {
+			log.Println("Only the owner can delist asset:", transaction.AssetHash)
+			return RejectUnauthorized
+		}
+		if status := AssetStatus(transaction.AssetHash, bc); status != AssetActive {
+			log.Printf("Asset %s is already %s", transaction.AssetHash, status)
+			return RejectInvalid
+		}
+	case TxTypeTakedown:
+		// Proposes the takedown, which applies once a quorum of validators vote for it
+		if !validateGovernanceTransaction(transaction, bc) {
+			return RejectUnauthorized
+		}
+		status := AssetStatus(transaction.AssetHash, bc)
+		if status == "" {
+			log.Printf("Asset %s can't be taken down, it doesn't exist", transaction.AssetHash)
+			return RejectUnknownAsset
+		}
+		if status == AssetTakenDown {
+			log.Printf("Asset %s is already taken down", transaction.AssetHash)
+			return RejectInvalid
+		}
+	case TxTypeRotateKey, TxTypeSetRecovery:
+		if !validateKeyTransaction(transaction, keys) {
+			return RejectInvalid
 		}
+	case TxTypeGovPropose, TxTypeGovVote, TxTypeEvidence:
+		if !validateGovernanceTransaction(transaction, bc) {
+			return RejectInvalid
+		}
+	default:
+		log.Println("Unknown transaction type:", transaction.TxType)
+		return RejectInvalid
 	}
-	return true
+	return ""
 }
 
 // Check if a user has a valid, unexpired license
 func HasValidLicense(assetHash string, bc *Blockchain, pubKey string) bool {
+	// Content removed for legal reasons is never served, even to its owner
+	if AssetStatus(assetHash, bc) == AssetTakenDown {
+		return false
+	}
+
 	licenseRegistry.Lock()
 	defer licenseRegistry.Unlock()
 
-	for _, block := range bc.Blocks {
+	// Ownership and licenses follow the identity through key rotations
+	keys := NewKeyResolver(bc)
+	now := time.Now().Unix()
+	for _, block := range bc.chain() {
 		for _, existingTx := range block.Transaction {
-			if existingTx.AssetHash == assetHash && (existingTx.License == pubKey || existingTx.Owner == pubKey) {
-				return true
+			if existingTx.AssetHash != assetHash {
+				continue
+			}
+			switch existingTx.TxType {
+			case TxTypeUpload:
+				if keys.Resolve(existingTx.Owner) == pubKey {
+					return true
+				}
+			case TxTypePurchase:
+				// Licenses bought before a delisting stay valid
+				if keys.Resolve(existingTx.Licensee) == pubKey && (existingTx.Expiry == 0 || existingTx.Expiry > now) {
+					return true
+				}
 			}
 		}
 	}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSignatureCoversEveryField(t *testing.T) {
+	privKey, pubKey := GenerateKeyPair()
+	tx := LicenseTransaction{
+		Owner: pubKey, AssetHash: "asset-signed", License: "view", Metadata: `{"Title":"t"}`, TxType: TxTypeUpload,
+		Timestamp: time.Now().Unix(), Expiry: time.Now().Add(time.Hour).Unix(), Nonce: 3,
+	}
+	tx.TxID = GenerateTransactionID(tx)
+	tx.Signature = SignTransaction(privKey, &tx)
+	if !VerifyTransaction(tx) {
+		t.Fatal("Signed transaction doesn't verify")
+	}
+
+	// Filled in by the proposer, or the signatures themselves
+	unsigned := map[string]bool{"TxID": true, "Signature": true, "CoSignature": true, "ValidatorID": true, "IsValidated": true}
+
+	fields := reflect.TypeOf(tx)
+	for i := range fields.NumField() {
+		name := fields.Field(i).Name
+		if unsigned[name] || name == "Owner" {
+			continue
+		}
+
+		changed := tx
+		field := reflect.ValueOf(&changed).Elem().Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(field.String() + "x")
+		case reflect.Int64:
+			field.SetInt(field.Int() + 1)
+		case reflect.Uint64:
+			field.SetUint(field.Uint() + 1)
+		default:
+			t.Fatalf("No way to change field %s", name)
+		}
+
+		if VerifyTransaction(changed) {
+			t.Errorf("Changing %s keeps the signature valid", name)
+		}
+		// Not even with an ID matching the new contents
+		changed.TxID = GenerateTransactionID(changed)
+		if VerifyTransaction(changed) {
+			t.Errorf("Changing %s and the ID keeps the signature valid", name)
+		}
+	}
+
+	stamped := tx
+	stamped.ValidatorID, stamped.IsValidated = 2, true
+	if !VerifyTransaction(stamped) {
+		t.Error("Transaction stamped by its proposer no longer verifies")
+	}
+}
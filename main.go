@@ -2,11 +2,11 @@ package main
 
 import (
 	"context"
+	"flag"
+	"io"
 	"log"
 	"os"
 	"os/signal"
-	"strconv"
-	"sync"
 	"syscall"
 
 	"github.com/Saumya40-codes/DeSecure/cmd"
@@ -14,14 +14,14 @@ import (
 	storage "github.com/Saumya40-codes/DeSecure/pkg"
 )
 
-const (
-	TopicName            = "drm-consensus"
-	DataDirPath          = "./data"
-	ValidatorDataDirPath = "./validator"
-)
+const DataDirPath = "./data"
 
 func main() {
-	if len(os.Args) > 1 {
+	// Anything but the options of the node is a CLI command
+	flags := flag.NewFlagSet("drm", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	genesisPath := flags.String("genesis", "", "Genesis file of the network, stored in the local chain on first use")
+	if err := flags.Parse(os.Args[1:]); err != nil || flags.NArg() > 0 {
 		cmd.Execute()
 		return
 	}
@@ -43,7 +43,7 @@ func main() {
 	defer db.CloseDB()
 
 	log.Println("Creating P2P node...")
-	node, err := core.NewNode(ctx, TopicName, false)
+	node, err := core.NewNode(ctx, core.ConsensusTopic)
 	if err != nil {
 		log.Fatal("Failed to create node:", err)
 	}
@@ -55,49 +55,25 @@ func main() {
 	blockchain := core.NewBlockchain(db)
 	log.Printf("Blockchain initialized with %d blocks", len(blockchain.Blocks))
 
+	if *genesisPath != "" {
+		genesis, err := core.LoadGenesis(*genesisPath)
+		if err != nil {
+			log.Fatal("Failed to load genesis:", err)
+		}
+		if err := blockchain.InitGenesis(genesis); err != nil {
+			log.Fatal("Failed to initialize genesis:", err)
+		}
+	}
+	if blockchain.Genesis() == nil {
+		log.Println("The chain has no genesis, blocks and receipts can't be checked until the node is started with --genesis")
+	}
+
 	log.Println("Starting transaction listener...")
 	go core.ListenForTransactions(node, blockchain, db)
 
-	numValidators := 5
-
-	wg := &sync.WaitGroup{}
-	log.Printf("Starting %d validators...", numValidators)
-
-	for i := range numValidators {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-
-			privKey, pubKey := core.GenerateKeyPair()
-			log.Printf("Validator %d initialized with public key: %s", id, pubKey[:16]+"...")
-
-			validatorDBPath := ValidatorDataDirPath + "/validator_" + strconv.Itoa(id)
-			os.MkdirAll(validatorDBPath, 0o700)
-			db := storage.OpenDB(validatorDBPath)
-			defer func() {
-				db.CloseDB()
-				log.Printf("Validator %d DB closed", id)
-			}()
-
-			node, err := core.NewNode(ctx, TopicName, true)
-			if err != nil {
-				log.Printf("Validator %d failed to create node: %v", id, err)
-				return
-			}
-
-			validatorBlockchain := core.NewBlockchain(db)
-			mempool := core.NewMempool()
-
-			validator := core.NewValidator(id, node, pubKey, privKey, mempool)
-			validator.StartConsensus(ctx, validatorBlockchain)
-
-			<-ctx.Done()
-			log.Printf("Validator %d received shutdown signal", id)
-		}(i)
-	}
+	// Validators run as their own processes with persistent keys
+	log.Println("Start validators with: drmcli validator start --config <dir>/validator.json")
 
 	<-ctx.Done()
-	log.Println("Main context canceled, waiting for validators to shut down...")
-	wg.Wait()
-	log.Println("All validators shut down cleanly.")
+	log.Println("Node shut down cleanly.")
 }
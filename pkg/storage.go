@@ -41,3 +41,28 @@ func (db *DB) Load(key string) ([]byte, error) {
 	})
 	return value, err
 }
+
+func (db *DB) Delete(key string) error {
+	return db.conn.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(key))
+	})
+}
+
+// LoadPrefix returns every value whose key starts with prefix, by key
+func (db *DB) LoadPrefix(prefix string) (map[string][]byte, error) {
+	values := make(map[string][]byte)
+	err := db.conn.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek([]byte(prefix)); it.ValidForPrefix([]byte(prefix)); it.Next() {
+			value, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			values[string(it.Item().Key())] = value
+		}
+		return nil
+	})
+	return values, err
+}